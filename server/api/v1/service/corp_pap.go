@@ -2,6 +2,7 @@ package service
 
 import (
 	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
 	"eve-corp-manager/models/service/pap"
 	"net/http"
 	"time"
@@ -23,12 +24,7 @@ func GetUserPapList(c *gin.Context) {
 		return
 	}
 
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
 
 	var papRecords []pap.CorpPap
 	var total int64
@@ -48,7 +44,7 @@ func GetUserPapList(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
+		"code":    0,
 		"message": "获取PAP记录成功",
 		"data": gin.H{
 			"total": total,
@@ -87,7 +83,7 @@ func GetUserPapBalance(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
+		"code":    0,
 		"message": "获取PAP余额成功",
 		"data":    balance,
 	})
@@ -101,7 +97,6 @@ func AddUserPap(c *gin.Context) {
 		Source   string `json:"source"`
 		SourceID uint   `json:"sourceId"`
 		Remark   string `json:"remark"`
-		Operator uint   `json:"operator"` // 操作人ID
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -113,6 +108,8 @@ func AddUserPap(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "PAP数量必须大于0"})
 		return
 	}
+	// 操作人固定为当前登录用户，不接受客户端传入
+	operator := middleware.GetUserID(c)
 
 	// 获取当前余额
 	var currentBalance int
@@ -158,7 +155,7 @@ func AddUserPap(c *gin.Context) {
 		Amount:     req.Amount,
 		BeforeVal:  currentBalance,
 		AfterVal:   newBalance,
-		Operator:   req.Operator,
+		Operator:   operator,
 		CreateTime: time.Now(),
 		Remark:     req.Remark,
 	}
@@ -180,7 +177,7 @@ func AddUserPap(c *gin.Context) {
 	global.Logger.Info("用户PAP增加成功, 用户ID:", req.UserID, "数量:", req.Amount, "来源:", req.Source)
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
+		"code":    0,
 		"message": "PAP增加成功",
 		"data":    newBalance,
 	})
@@ -194,7 +191,6 @@ func ConsumeUserPap(c *gin.Context) {
 		Source   string `json:"source"`
 		SourceID uint   `json:"sourceId"`
 		Remark   string `json:"remark"`
-		Operator uint   `json:"operator"` // 操作人ID
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -206,6 +202,8 @@ func ConsumeUserPap(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "PAP数量必须大于0"})
 		return
 	}
+	// 操作人固定为当前登录用户，不接受客户端传入
+	operator := middleware.GetUserID(c)
 
 	// 获取当前余额
 	var currentBalance int
@@ -260,7 +258,7 @@ func ConsumeUserPap(c *gin.Context) {
 		Amount:     req.Amount,
 		BeforeVal:  currentBalance,
 		AfterVal:   newBalance,
-		Operator:   req.Operator,
+		Operator:   operator,
 		CreateTime: time.Now(),
 		Remark:     req.Remark,
 	}
@@ -282,7 +280,7 @@ func ConsumeUserPap(c *gin.Context) {
 	global.Logger.Info("用户PAP消费成功, 用户ID:", req.UserID, "数量:", req.Amount, "来源:", req.Source)
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
+		"code":    0,
 		"message": "PAP消费成功",
 		"data":    newBalance,
 	})
@@ -301,12 +299,7 @@ func GetPapLogs(c *gin.Context) {
 		return
 	}
 
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.Limit <= 0 {
-		req.Limit = 10
-	}
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
 
 	var papLogs []pap.CorpPapLog
 	var total int64
@@ -326,7 +319,7 @@ func GetPapLogs(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
+		"code":    0,
 		"message": "获取PAP操作日志成功",
 		"data": gin.H{
 			"total": total,
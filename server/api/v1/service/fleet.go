@@ -0,0 +1,297 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	fleetCore "eve-corp-manager/core/fleet"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	fleetModel "eve-corp-manager/models/service/fleet"
+	fleetRepo "eve-corp-manager/repository/service/fleet"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nullable 区分未填写和显式null的JSON字段
+type nullable[T any] struct {
+	Set   bool // 请求中包含该字段
+	Value *T   // 为nil表示null
+}
+
+// UnmarshalJSON 解析字段值，null时Value为nil
+func (n *nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set, n.Value = true, nil
+	if string(data) == "null" {
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+// fleetRequest 新增/修改舰队参数
+type fleetRequest struct {
+	FleetName        string              `json:"fleetName" binding:"required,max=64"`
+	FleetType        int                 `json:"fleetType" binding:"min=0"`
+	FleetExtraInfo   string              `json:"fleetExtraInfo" binding:"max=255"`
+	FleetCommanderID nullable[uint]      `json:"fleetCommanderId"` // 新建时为空则使用当前用户的主角色，修改时不填则保留原指挥，null清除指挥
+	SolarSystemID    int                 `json:"solarSystemId"`    // 修改时为空则保留原地点
+	Sig              int                 `json:"sig"`
+	Srp              bool                `json:"srp"`
+	CorpPap          int                 `json:"corpPap" binding:"min=0"`
+	AutoSrp          bool                `json:"autoSrp"` // 开启时必须填写结束时间
+	StartTime        time.Time           `json:"startTime" binding:"required"`
+	EndTime          nullable[time.Time] `json:"endTime"` // 修改时不填则保留原结束时间，null清除结束时间
+}
+
+// apply 将参数写入舰队，未填写的指挥和结束时间保留原值，null时清除
+func (r *fleetRequest) apply(f *fleetModel.Fleet) {
+	f.FleetName = r.FleetName
+	f.FleetType = r.FleetType
+	f.FleetExtraInfo = r.FleetExtraInfo
+	if r.FleetCommanderID.Set {
+		f.FleetCommanderID = 0
+		if r.FleetCommanderID.Value != nil {
+			f.FleetCommanderID = *r.FleetCommanderID.Value
+		}
+	}
+	f.Sig = r.Sig
+	f.Srp = r.Srp
+	f.CorpPap = r.CorpPap
+	f.AutoSrp = r.AutoSrp && r.Srp
+	f.StartTime = r.StartTime
+	if r.EndTime.Set {
+		f.EndTime = r.EndTime.Value
+	}
+}
+
+// CreateFleet 新建舰队
+func CreateFleet(c *gin.Context) {
+	var req fleetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	var f fleetModel.Fleet
+	req.apply(&f)
+	result, err := fleetCore.Save(c.Request.Context(), &f, req.SolarSystemID, middleware.GetUserID(c))
+	if err != nil {
+		writeFleetError(c, "新建舰队失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "新建舰队成功",
+		"data":    result,
+	})
+}
+
+// UpdateFleet 修改舰队
+func UpdateFleet(c *gin.Context) {
+	id, ok := fleetIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req fleetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	f, err := fleetCore.Get(id)
+	if err != nil {
+		writeFleetError(c, "修改舰队失败", err)
+		return
+	}
+	req.apply(f)
+	result, err := fleetCore.Save(c.Request.Context(), f, req.SolarSystemID, middleware.GetUserID(c))
+	if err != nil {
+		writeFleetError(c, "修改舰队失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "修改舰队成功",
+		"data":    result,
+	})
+}
+
+// DeleteFleet 删除舰队及其参与记录
+func DeleteFleet(c *gin.Context) {
+	id, ok := fleetIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := fleetCore.Delete(id); err != nil {
+		writeFleetError(c, "删除舰队失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "删除舰队成功",
+	})
+}
+
+// GetFleetList 按指挥、舰队类型和开始时间查询舰队列表
+func GetFleetList(c *gin.Context) {
+	var req struct {
+		CommanderID uint   `json:"commanderId" form:"commanderId"`
+		FleetType   int    `json:"fleetType" form:"fleetType"`
+		StartTime   string `json:"startTime" form:"startTime"` // RFC3339或2006-01-02
+		EndTime     string `json:"endTime" form:"endTime"`
+		Page        int    `json:"page" form:"page"`
+		Limit       int    `json:"limit" form:"limit"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+
+	startTime, err := parseQueryTime(req.StartTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "开始时间格式错误"})
+		return
+	}
+	endTime, err := parseQueryTime(req.EndTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "结束时间格式错误"})
+		return
+	}
+
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	fleets, total, err := repo.List(fleetRepo.FleetFilter{
+		CommanderID: req.CommanderID,
+		FleetType:   req.FleetType,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Page:        req.Page,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取舰队列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取舰队列表成功",
+		"data": gin.H{
+			"total": total,
+			"items": fleets,
+		},
+	})
+}
+
+// GetFleet 获取舰队详情及参与角色
+func GetFleet(c *gin.Context) {
+	id, ok := fleetIDParam(c)
+	if !ok {
+		return
+	}
+
+	result, err := fleetCore.GetDetail(c.Request.Context(), id)
+	if err != nil {
+		writeFleetError(c, "获取舰队详情失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取舰队详情成功",
+		"data":    result,
+	})
+}
+
+// AddFleetMembers 添加舰队参与角色，已参与的角色忽略
+func AddFleetMembers(c *gin.Context) {
+	id, ok := fleetIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CharacterIDs []uint `json:"characterIds" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	added, err := fleetCore.AddMembers(id, req.CharacterIDs)
+	if err != nil {
+		writeFleetError(c, "添加参与角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "添加参与角色成功",
+		"data": gin.H{
+			"added": added,
+		},
+	})
+}
+
+// RemoveFleetMember 移除舰队参与角色
+func RemoveFleetMember(c *gin.Context) {
+	id, ok := fleetIDParam(c)
+	if !ok {
+		return
+	}
+	characterID, err := strconv.ParseUint(c.Param("characterId"), 10, 64)
+	if err != nil || characterID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	if err := fleetCore.RemoveMember(id, uint(characterID)); err != nil {
+		writeFleetError(c, "移除参与角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "移除参与角色成功",
+	})
+}
+
+// fleetIDParam 解析路径中的舰队ID
+func fleetIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeFleetError 将舰队业务错误转换为响应
+func writeFleetError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, fleetCore.ErrFleetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, fleetCore.ErrInvalidTime), errors.Is(err, fleetCore.ErrAutoSrpEndTime),
+		errors.Is(err, fleetCore.ErrSolarSystemNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, fleetCore.ErrFleetHasSrp):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	default:
+		global.Logger.Error(message+":", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
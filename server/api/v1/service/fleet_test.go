@@ -0,0 +1,44 @@
+package service
+
+import (
+	fleetModel "eve-corp-manager/models/service/fleet"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestFleetRequestApply(t *testing.T) {
+	oldEnd := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC)
+	newEnd := time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		fields        string // 追加到必填参数之后的字段
+		wantCommander uint
+		wantEnd       *time.Time
+	}{
+		{"不填保留原值", ``, 2112000001, &oldEnd},
+		{"null清除", `,"fleetCommanderId":null,"endTime":null`, 0, nil},
+		{"填写时修改", `,"fleetCommanderId":2112000002,"endTime":"2025-01-01T15:00:00Z"`, 2112000002, &newEnd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"fleetName":"test","startTime":"2025-01-01T12:00:00Z"` + tt.fields + `}`
+			var req fleetRequest
+			if err := binding.JSON.BindBody([]byte(body), &req); err != nil {
+				t.Fatalf("bind %s: %v", body, err)
+			}
+
+			end := oldEnd
+			f := &fleetModel.Fleet{FleetCommanderID: 2112000001, EndTime: &end}
+			req.apply(f)
+			if f.FleetCommanderID != tt.wantCommander {
+				t.Errorf("commander = %d, want %d", f.FleetCommanderID, tt.wantCommander)
+			}
+			if (f.EndTime == nil) != (tt.wantEnd == nil) || (f.EndTime != nil && !f.EndTime.Equal(*tt.wantEnd)) {
+				t.Errorf("endTime = %v, want %v", f.EndTime, tt.wantEnd)
+			}
+		})
+	}
+}
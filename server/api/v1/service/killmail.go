@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/killmail"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	"eve-corp-manager/models/service/character"
+	characterRepo "eve-corp-manager/repository/service/character"
+	"eve-corp-manager/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SubmitKillmail 提交zkillboard或ESI击毁邮件链接并保存
+func SubmitKillmail(c *gin.Context) {
+	var req struct {
+		Link string `json:"link" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	killMailID, killMailHash, err := esi.GetKillmailHash(c.Request.Context(), req.Link)
+	if err != nil || killMailID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无法识别的击毁邮件链接"})
+		return
+	}
+	if killMailHash == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "无法获取击毁邮件hash，请提供zkillboard或ESI链接"})
+		return
+	}
+
+	creator := killmail.Creator{ID: middleware.GetUserID(c), Name: middleware.GetUserName(c)}
+	result, err := killmail.Save(c.Request.Context(), killMailID, killMailHash, creator)
+	if err != nil {
+		global.Logger.Error("保存击毁邮件失败:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"code": 502, "message": "获取击毁邮件失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "提交击毁邮件成功",
+		"data":    result,
+	})
+}
+
+// GetKillmail 获取击毁邮件详情，lang参数指定物品、舰船和分组名称的语言(en/zh/de/ru等)，默认中文
+func GetKillmail(c *gin.Context) {
+	result, ok := getStoredKillmail(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取击毁邮件成功",
+		"data":    killmail.Details(result, killmailLang(c)),
+	})
+}
+
+// GetKillmailUIJSON 获取击毁邮件的UI数据，lang参数指定语言，默认中文
+func GetKillmailUIJSON(c *gin.Context) {
+	result, ok := getStoredKillmail(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取击毁邮件成功",
+		"data":    killmail.UIJSON(result, killmailLang(c)),
+	})
+}
+
+// killmailLang 请求指定的语言，未指定时使用默认语言
+func killmailLang(c *gin.Context) string {
+	if lang := c.Query("lang"); lang != "" {
+		return lang
+	}
+	return utils.DefaultLang
+}
+
+// getStoredKillmail 根据路径参数获取已保存的击毁邮件，失败时写入响应
+func getStoredKillmail(c *gin.Context) (*character.KillmailList, bool) {
+	killMailID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || killMailID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return nil, false
+	}
+
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	result, err := killmailRepo.Get(killMailID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "击毁邮件不存在"})
+		return nil, false
+	}
+	if err != nil {
+		global.Logger.Error("获取击毁邮件失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取击毁邮件失败"})
+		return nil, false
+	}
+	return result, true
+}
+
+// GetKillmailList 按角色、用户和时间查询击毁邮件列表
+func GetKillmailList(c *gin.Context) {
+	var req struct {
+		CharacterID int    `json:"characterId" form:"characterId"`
+		UserID      uint   `json:"userId" form:"userId"`
+		StartTime   string `json:"startTime" form:"startTime"` // RFC3339或2006-01-02
+		EndTime     string `json:"endTime" form:"endTime"`
+		Page        int    `json:"page" form:"page"`
+		Limit       int    `json:"limit" form:"limit"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+
+	startTime, err := parseQueryTime(req.StartTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "开始时间格式错误"})
+		return
+	}
+	endTime, err := parseQueryTime(req.EndTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "结束时间格式错误"})
+		return
+	}
+
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	killmails, total, err := killmailRepo.List(characterRepo.KillmailFilter{
+		CharacterID: req.CharacterID,
+		UserID:      req.UserID,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Page:        req.Page,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取击毁邮件列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取击毁邮件列表成功",
+		"data": gin.H{
+			"total": total,
+			"items": killmails,
+		},
+	})
+}
+
+// maxPageLimit 列表接口每页最多返回的数量
+const maxPageLimit = 100
+
+// normalizePage 修正分页参数，page默认为1，limit默认为10且不超过maxPageLimit
+func normalizePage(page, limit int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	return page, min(limit, maxPageLimit)
+}
+
+// parseQueryTime 解析查询参数中的时间，空字符串返回零值
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
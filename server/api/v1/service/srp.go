@@ -0,0 +1,359 @@
+package service
+
+import (
+	"errors"
+	"eve-corp-manager/core/srp"
+	"eve-corp-manager/core/system"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	srpModel "eve-corp-manager/models/service/srp"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SubmitSrp 提交补损申请
+func SubmitSrp(c *gin.Context) {
+	var req struct {
+		Link    string `json:"link" binding:"required"`
+		FleetID uint   `json:"fleetId"`
+		Remark  string `json:"remark" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	result, err := srp.Submit(c.Request.Context(), req.Link, req.FleetID, req.Remark, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "提交补损申请失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "提交补损申请成功",
+		"data":    result,
+	})
+}
+
+// GetSrp 获取补损申请详情及审计日志，非本人申请需要审核权限
+func GetSrp(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	result, err := repo.Get(id)
+	if err != nil {
+		writeSrpError(c, "获取补损申请失败", err)
+		return
+	}
+
+	if result.UserID != middleware.GetUserID(c) {
+		allowed, err := global.Permissions.HasCode(middleware.GetUserID(c), system.PermSrpReview)
+		if err != nil || !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "没有操作权限"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取补损申请成功",
+		"data":    result,
+	})
+}
+
+// GetMySrpList 获取当前用户的补损申请
+func GetMySrpList(c *gin.Context) {
+	listSrp(c, middleware.GetUserID(c))
+}
+
+// GetSrpList 按用户、角色、舰队和状态查询补损申请
+func GetSrpList(c *gin.Context) {
+	listSrp(c, 0)
+}
+
+// listSrp 分页查询补损申请，userID不为0时只查询该用户
+func listSrp(c *gin.Context, userID uint) {
+	var req struct {
+		UserID      uint   `json:"userId" form:"userId"`
+		CharacterID int    `json:"characterId" form:"characterId"`
+		FleetID     uint   `json:"fleetId" form:"fleetId"`
+		Status      int    `json:"status" form:"status"`
+		StartTime   string `json:"startTime" form:"startTime"` // RFC3339或2006-01-02
+		EndTime     string `json:"endTime" form:"endTime"`
+		Page        int    `json:"page" form:"page"`
+		Limit       int    `json:"limit" form:"limit"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	if userID > 0 {
+		req.UserID = userID
+	}
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+
+	startTime, err := parseQueryTime(req.StartTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "开始时间格式错误"})
+		return
+	}
+	endTime, err := parseQueryTime(req.EndTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "结束时间格式错误"})
+		return
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	requests, total, err := repo.List(srpRepo.SrpFilter{
+		UserID:      req.UserID,
+		CharacterID: req.CharacterID,
+		FleetID:     req.FleetID,
+		Status:      req.Status,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Page:        req.Page,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取补损申请列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取补损申请列表成功",
+		"data": gin.H{
+			"total": total,
+			"items": requests,
+		},
+	})
+}
+
+// ApproveSrp 批准补损申请，金额与申请金额不同时需要填写意见
+func ApproveSrp(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount  float64 `json:"amount"`
+		Comment string  `json:"comment" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	result, err := srp.Approve(id, req.Amount, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "批准补损申请失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "批准补损申请成功",
+		"data":    result,
+	})
+}
+
+// RejectSrp 拒绝补损申请
+func RejectSrp(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请填写拒绝原因"})
+		return
+	}
+
+	result, err := srp.Reject(id, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "拒绝补损申请失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "拒绝补损申请成功",
+		"data":    result,
+	})
+}
+
+// PaySrp 发放补损，金额少于批准金额时为部分发放
+func PaySrp(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount  float64 `json:"amount"`
+		Comment string  `json:"comment" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	result, err := srp.Pay(id, req.Amount, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "发放补损失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "发放补损成功",
+		"data":    result,
+	})
+}
+
+// srpIDParam 解析路径中的补损申请ID，失败时写入响应
+func srpIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentOperator 当前登录用户
+func currentOperator(c *gin.Context) srp.Operator {
+	return srp.Operator{ID: middleware.GetUserID(c), Name: middleware.GetUserName(c)}
+}
+
+// writeSrpError 将补损业务错误转换为响应
+func writeSrpError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "补损申请不存在"})
+	case errors.Is(err, srp.ErrInvalidLink),
+		errors.Is(err, srp.ErrFleetNotFound),
+		errors.Is(err, srp.ErrFleetNoSrp),
+		errors.Is(err, srp.ErrInvalidAmount),
+		errors.Is(err, srp.ErrCommentRequired),
+		errors.Is(err, srp.ErrNoApprovedRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, srp.ErrLossNotFound), errors.Is(err, srp.ErrBatchNotFound), errors.Is(err, srp.ErrPayoutItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case errors.Is(err, srp.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	case errors.Is(err, srp.ErrAlreadySubmitted), errors.Is(err, srp.ErrInvalidStatus), errors.Is(err, srp.ErrLossHandled),
+		errors.Is(err, srp.ErrInBatch), errors.Is(err, srp.ErrBatchConflict), errors.Is(err, srp.ErrPayoutItemPaid):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	default:
+		global.Logger.Error(message+":", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+// GetSrpLosses 查询自动补损的匹配记录，默认返回待人工处理的损失
+func GetSrpLosses(c *gin.Context) {
+	var req struct {
+		Status int `json:"status" form:"status"`
+		Page   int `json:"page" form:"page"`
+		Limit  int `json:"limit" form:"limit"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	if req.Status == 0 {
+		req.Status = srpModel.SrpMatchUnmatched
+	}
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	matches, total, err := repo.ListMatches(req.Status, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取损失列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取损失列表成功",
+		"data": gin.H{
+			"total": total,
+			"items": matches,
+		},
+	})
+}
+
+// AssignSrpLoss 将未匹配的损失关联到舰队并代为提交补损申请
+func AssignSrpLoss(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		FleetID uint   `json:"fleetId" binding:"required"`
+		Comment string `json:"comment" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	result, err := srp.AssignLoss(c.Request.Context(), id, req.FleetID, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "提交补损申请失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "提交补损申请成功",
+		"data":    result,
+	})
+}
+
+// IgnoreSrpLoss 确认未匹配的损失不予补损
+func IgnoreSrpLoss(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请填写原因"})
+		return
+	}
+
+	result, err := srp.IgnoreLoss(id, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "处理损失失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "处理损失成功",
+		"data":    result,
+	})
+}
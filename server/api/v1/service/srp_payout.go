@@ -0,0 +1,157 @@
+package service
+
+import (
+	"eve-corp-manager/core/srp"
+	"eve-corp-manager/global"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSrpBatch 将已批准的补损申请按收款主角色汇总为发放批次
+func CreateSrpBatch(c *gin.Context) {
+	var req struct {
+		Name       string `json:"name" binding:"max=64"`
+		RequestIDs []uint `json:"requestIds"` // 为空时使用全部已批准且未加入批次的申请
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	result, err := srp.CreateBatch(req.Name, req.RequestIDs, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "创建发放批次失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "创建发放批次成功",
+		"data":    result,
+	})
+}
+
+// GetSrpBatchList 分页查询发放批次
+func GetSrpBatchList(c *gin.Context) {
+	var req struct {
+		Status int `json:"status" form:"status"`
+		Page   int `json:"page" form:"page"`
+		Limit  int `json:"limit" form:"limit"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	req.Page, req.Limit = normalizePage(req.Page, req.Limit)
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	batches, total, err := repo.ListBatches(req.Status, req.Page, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取发放批次列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取发放批次列表成功",
+		"data": gin.H{
+			"total": total,
+			"items": batches,
+		},
+	})
+}
+
+// GetSrpBatch 获取发放批次及明细，金额不符的明细附带对账说明
+func GetSrpBatch(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	result, err := srp.GetBatch(id)
+	if err != nil {
+		writeSrpError(c, "获取发放批次失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取发放批次成功",
+		"data":    result,
+	})
+}
+
+// ExportSrpBatch 导出批次中尚未发放的明细，每行为 角色名称<TAB>金额<TAB>转账备注
+func ExportSrpBatch(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	batch, err := srp.GetBatch(id)
+	if err != nil {
+		writeSrpError(c, "导出发放批次失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "导出发放批次成功",
+		"data": gin.H{
+			"text": srp.ExportBatch(batch),
+		},
+	})
+}
+
+// ConfirmSrpPayoutItem 人工确认发放明细已转账
+func ConfirmSrpPayoutItem(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请填写备注"})
+		return
+	}
+
+	result, err := srp.ConfirmPayoutItem(id, req.Comment, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "确认发放失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "确认发放成功",
+		"data":    result,
+	})
+}
+
+// ReconcileSrp 立即读取公司钱包流水进行对账
+func ReconcileSrp(c *gin.Context) {
+	result, err := srp.Reconcile(c.Request.Context())
+	if err != nil {
+		global.Logger.Error("补损钱包对账失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "补损钱包对账失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "补损钱包对账成功",
+		"data": gin.H{
+			"entries":  result.Entries,
+			"paid":     result.Paid,
+			"mismatch": result.Mismatch,
+		},
+	})
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"eve-corp-manager/core/srp"
+	"eve-corp-manager/global"
+	srpModel "eve-corp-manager/models/service/srp"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// srpRuleRequest 新增/修改补损规则参数
+type srpRuleRequest struct {
+	Name        string  `json:"name" binding:"required,max=64"`
+	RuleType    int     `json:"ruleType" binding:"required,min=1,max=5"`
+	TargetID    int     `json:"targetId" binding:"required"`
+	FixedAmount float64 `json:"fixedAmount" binding:"min=0"`
+	Percent     float64 `json:"percent" binding:"min=0,max=1000"`
+	MaxAmount   float64 `json:"maxAmount" binding:"min=0"`
+	Priority    int     `json:"priority"`
+	Enabled     *bool   `json:"enabled"`
+	Remark      string  `json:"remark" binding:"max=255"`
+}
+
+func (r *srpRuleRequest) apply(rule *srpModel.SrpRule) {
+	rule.Name = r.Name
+	rule.RuleType = r.RuleType
+	rule.TargetID = r.TargetID
+	rule.FixedAmount = r.FixedAmount
+	rule.Percent = r.Percent
+	rule.MaxAmount = r.MaxAmount
+	rule.Priority = r.Priority
+	rule.Enabled = true
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+	rule.Remark = r.Remark
+}
+
+// DryRunSrp 按当前补损规则试算击毁邮件的补损金额及计算依据
+func DryRunSrp(c *gin.Context) {
+	var req struct {
+		Link      string `json:"link" binding:"required"`
+		FleetID   uint   `json:"fleetId"`
+		FleetType *int   `json:"fleetType"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	km, payout, err := srp.DryRun(c.Request.Context(), req.Link, req.FleetID, req.FleetType, currentOperator(c))
+	if err != nil {
+		writeSrpError(c, "补损试算失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "补损试算成功",
+		"data": gin.H{
+			"killMailId":    km.KillMailID,
+			"characterName": km.CharacterName,
+			"shipTypeId":    km.ShipTypeID,
+			"shipTypeName":  km.ShipTypeName,
+			"payout":        payout,
+		},
+	})
+}
+
+// GetSrpRules 获取全部补损规则
+func GetSrpRules(c *gin.Context) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	rules, err := repo.ListRules(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取补损规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取补损规则成功",
+		"data":    rules,
+	})
+}
+
+// CreateSrpRule 新增补损规则
+func CreateSrpRule(c *gin.Context) {
+	var req srpRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	var rule srpModel.SrpRule
+	req.apply(&rule)
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	if _, err := repo.AddRule(&rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "新增补损规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "新增补损规则成功",
+		"data":    rule,
+	})
+}
+
+// UpdateSrpRule 修改补损规则
+func UpdateSrpRule(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req srpRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	rule, err := repo.GetRule(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "补损规则不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取补损规则失败"})
+		return
+	}
+
+	req.apply(rule)
+	if _, err := repo.UpdateRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "修改补损规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "修改补损规则成功",
+		"data":    rule,
+	})
+}
+
+// DeleteSrpRule 删除补损规则
+func DeleteSrpRule(c *gin.Context) {
+	id, ok := srpIDParam(c)
+	if !ok {
+		return
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	if err := repo.DeleteRule(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除补损规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "删除补损规则成功"})
+}
+
+// GetSrpMultipliers 获取舰队类型补损倍率
+func GetSrpMultipliers(c *gin.Context) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	multipliers, err := repo.ListMultipliers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取补损倍率失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "获取补损倍率成功",
+		"data":    multipliers,
+	})
+}
+
+// SaveSrpMultiplier 设置舰队类型的补损倍率
+func SaveSrpMultiplier(c *gin.Context) {
+	var req struct {
+		FleetType  int     `json:"fleetType"`
+		Multiplier float64 `json:"multiplier" binding:"gt=0,max=100"`
+		Remark     string  `json:"remark" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	err := repo.SaveMultiplier(&srpModel.SrpFleetMultiplier{
+		FleetType:  req.FleetType,
+		Multiplier: req.Multiplier,
+		Remark:     req.Remark,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "设置补损倍率失败"})
+		return
+	}
+	multiplier, err := repo.GetMultiplier(req.FleetType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "设置补损倍率失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "设置补损倍率成功",
+		"data":    multiplier,
+	})
+}
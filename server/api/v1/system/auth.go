@@ -0,0 +1,143 @@
+package system
+
+import (
+	"errors"
+	"eve-corp-manager/core/auth"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	"eve-corp-manager/models/system"
+	systemRepo "eve-corp-manager/repository/system"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// refreshTokenCookie 保存refresh token的cookie名称，与前端约定一致
+const refreshTokenCookie = "jwt"
+
+// Login 使用SSO回调签发的一次性票据登录
+func Login(c *gin.Context) {
+	var req struct {
+		Ticket string `json:"ticket" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	userID, err := auth.TokenManager.ConsumeLoginTicket(c.Request.Context(), req.Ticket)
+	if err != nil {
+		clearRefreshTokenCookie(c)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "登录票据无效或已过期"})
+		return
+	}
+
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	user, err := userRepo.Get(&system.User{UserId: userID})
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "用户不存在"})
+		return
+	}
+	if user.Status == system.UserStatusDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "用户已被禁用"})
+		return
+	}
+
+	accessToken, err := auth.TokenManager.IssueAccessToken(user.UserId, user.Name)
+	if err != nil {
+		global.Logger.Error("签发访问令牌失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "登录失败"})
+		return
+	}
+	refreshToken, err := auth.TokenManager.IssueRefreshToken(c.Request.Context(), user.UserId)
+	if err != nil {
+		global.Logger.Error("签发refresh token失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "登录失败"})
+		return
+	}
+	setRefreshTokenCookie(c, refreshToken)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"userId":      fmt.Sprint(user.UserId),
+			"username":    user.Name,
+			"realName":    user.Name,
+			"accessToken": accessToken,
+		},
+	})
+}
+
+// RefreshToken 使用cookie中的refresh token换取新的访问令牌，refresh token同时轮换
+func RefreshToken(c *gin.Context) {
+	refreshToken, err := c.Cookie(refreshTokenCookie)
+	if err != nil || refreshToken == "" {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "Forbidden Exception"})
+		return
+	}
+	clearRefreshTokenCookie(c)
+
+	userID, newRefreshToken, err := auth.TokenManager.RotateRefreshToken(c.Request.Context(), refreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrTokenReused) {
+			global.Logger.Warn("检测到refresh token重放，已吊销该用户所有登录状态")
+		}
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "Forbidden Exception"})
+		return
+	}
+
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	user, err := userRepo.Get(&system.User{UserId: userID})
+	if err != nil || user.Status == system.UserStatusDisabled {
+		_ = auth.TokenManager.RevokeRefreshToken(c.Request.Context(), newRefreshToken)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "Forbidden Exception"})
+		return
+	}
+
+	accessToken, err := auth.TokenManager.IssueAccessToken(user.UserId, user.Name)
+	if err != nil {
+		global.Logger.Error("签发访问令牌失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "刷新令牌失败"})
+		return
+	}
+	setRefreshTokenCookie(c, newRefreshToken)
+
+	// 前端直接读取响应体作为新的访问令牌
+	c.String(http.StatusOK, accessToken)
+}
+
+// Logout 退出登录
+func Logout(c *gin.Context) {
+	if refreshToken, err := c.Cookie(refreshTokenCookie); err == nil && refreshToken != "" {
+		if err := auth.TokenManager.RevokeRefreshToken(c.Request.Context(), refreshToken); err != nil {
+			global.Logger.Error("吊销refresh token失败:", err)
+		}
+	}
+	clearRefreshTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": ""})
+}
+
+// GetAccessCodes 获取当前用户权限码
+func GetAccessCodes(c *gin.Context) {
+	perm, err := global.Permissions.Get(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取权限码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": perm.Codes})
+}
+
+func setRefreshTokenCookie(c *gin.Context, refreshToken string) {
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(refreshTokenCookie, refreshToken, int(auth.TokenManager.RefreshTTL().Seconds()), "/", "", true, true)
+}
+
+func clearRefreshTokenCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(refreshTokenCookie, "", -1, "/", "", true, true)
+}
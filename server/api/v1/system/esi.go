@@ -0,0 +1,13 @@
+package system
+
+import (
+	"eve-corp-manager/core/esi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEsiErrorLimit 获取ESI错误额度状态，用于监控
+func GetEsiErrorLimit(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": esi.EsiClient.ErrorLimitState()})
+}
@@ -0,0 +1,207 @@
+package system
+
+import (
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	"eve-corp-manager/models/system"
+	systemRepo "eve-corp-manager/repository/system"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// menuRequest 新增/修改菜单参数
+type menuRequest struct {
+	Pid       uint            `json:"pid"`
+	Name      string          `json:"name" binding:"required"`
+	Path      string          `json:"path"`
+	Component string          `json:"component"`
+	Redirect  string          `json:"redirect"`
+	Type      string          `json:"type" binding:"required,oneof=catalog menu button"`
+	AuthCode  string          `json:"authCode"`
+	Status    *int            `json:"status"`
+	Meta      system.MenuMeta `json:"meta"`
+}
+
+func (r *menuRequest) apply(menu *system.Menu) {
+	menu.Pid = r.Pid
+	menu.Name = r.Name
+	menu.Path = r.Path
+	menu.Component = r.Component
+	menu.Redirect = r.Redirect
+	menu.Type = r.Type
+	menu.AuthCode = r.AuthCode
+	menu.Status = system.StatusEnabled
+	if r.Status != nil {
+		menu.Status = *r.Status
+	}
+	menu.Meta = r.Meta
+}
+
+// GetAllMenus 获取当前用户可访问的菜单树，按钮权限通过/auth/codes返回
+func GetAllMenus(c *gin.Context) {
+	perm, err := global.Permissions.Get(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+		return
+	}
+
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	allMenus, err := menuRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+		return
+	}
+
+	menuMap := make(map[uint]system.Menu, len(allMenus))
+	for _, menu := range allMenus {
+		menuMap[menu.ID] = menu
+	}
+
+	// 授权了子菜单时父级目录也需要返回
+	visible := make(map[uint]bool)
+	for _, menuID := range perm.MenuIds {
+		for id := menuID; id != 0 && !visible[id]; {
+			menu, ok := menuMap[id]
+			if !ok {
+				break
+			}
+			visible[id] = true
+			id = menu.Pid
+		}
+	}
+
+	menus := make([]system.Menu, 0, len(visible))
+	for _, menu := range allMenus {
+		if visible[menu.ID] && menu.Type != system.MenuTypeButton && menu.Status == system.StatusEnabled {
+			menus = append(menus, menu)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": systemRepo.BuildMenuTree(menus)})
+}
+
+// GetMenuList 获取完整菜单树(含按钮)
+func GetMenuList(c *gin.Context) {
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	menus, err := menuRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": systemRepo.BuildMenuTree(menus)})
+}
+
+// CreateMenu 新增菜单
+func CreateMenu(c *gin.Context) {
+	var req menuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	if req.Pid != 0 {
+		if _, err := menuRepo.Get(req.Pid); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "父菜单不存在"})
+			return
+		}
+	}
+
+	var menu system.Menu
+	req.apply(&menu)
+	if _, err := menuRepo.Add(&menu); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "新增菜单失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": menu})
+}
+
+// UpdateMenu 修改菜单
+func UpdateMenu(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	var req menuRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	if req.Pid == uri.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "父菜单不能是自己"})
+		return
+	}
+
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	menu, err := menuRepo.Get(uri.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "菜单不存在"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+		return
+	}
+
+	if req.Pid != 0 && req.Pid != menu.Pid {
+		descendant, err := menuRepo.IsDescendant(req.Pid, uri.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "父菜单不存在"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+			return
+		}
+		if descendant {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "父菜单不能是自己的子菜单"})
+			return
+		}
+	}
+
+	req.apply(menu)
+	if _, err := menuRepo.Update(menu); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "修改菜单失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": menu})
+}
+
+// DeleteMenu 删除菜单
+func DeleteMenu(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	count, err := menuRepo.CountChildren(uri.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除菜单失败"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请先删除子菜单"})
+		return
+	}
+
+	if err := menuRepo.Delete(uri.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除菜单失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": nil})
+}
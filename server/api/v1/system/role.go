@@ -0,0 +1,321 @@
+package system
+
+import (
+	"errors"
+	coreSystem "eve-corp-manager/core/system"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	"eve-corp-manager/models/system"
+	systemRepo "eve-corp-manager/repository/system"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// roleRequest 新增/修改角色参数
+type roleRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Code   string `json:"code" binding:"required"`
+	Status *int   `json:"status"`
+	Remark string `json:"remark"`
+}
+
+// GetRoleList 获取角色列表
+func GetRoleList(c *gin.Context) {
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	roles, err := roleRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取角色列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": gin.H{
+		"total": len(roles),
+		"items": roles,
+	}})
+}
+
+// CreateRole 新增角色
+func CreateRole(c *gin.Context) {
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	if _, err := roleRepo.GetByCode(req.Code); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "角色编码已存在"})
+		return
+	}
+
+	role := system.SysRole{
+		Name:   req.Name,
+		Code:   req.Code,
+		Status: system.StatusEnabled,
+		Remark: req.Remark,
+	}
+	if req.Status != nil {
+		role.Status = *req.Status
+	}
+	if _, err := roleRepo.Add(&role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "新增角色失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": role})
+}
+
+// UpdateRole 修改角色
+func UpdateRole(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	var req roleRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	role, ok := getRole(c, &roleRepo, uri.ID)
+	if !ok {
+		return
+	}
+	if !checkSuperRole(c, role) {
+		return
+	}
+	if role.Code == coreSystem.SuperRoleCode && req.Code != role.Code {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "不能修改超级管理员角色编码"})
+		return
+	}
+	if existing, err := roleRepo.GetByCode(req.Code); err == nil && existing.ID != role.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "角色编码已存在"})
+		return
+	}
+
+	role.Name = req.Name
+	role.Code = req.Code
+	role.Remark = req.Remark
+	if req.Status != nil {
+		role.Status = *req.Status
+	}
+	if _, err := roleRepo.Update(role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "修改角色失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": role})
+}
+
+// DeleteRole 删除角色
+func DeleteRole(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	role, ok := getRole(c, &roleRepo, uri.ID)
+	if !ok {
+		return
+	}
+	if role.Code == coreSystem.SuperRoleCode {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "不能删除超级管理员角色"})
+		return
+	}
+
+	if err := roleRepo.Delete(role.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除角色失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": nil})
+}
+
+// GetRoleMenus 获取角色已授权的菜单ID
+func GetRoleMenus(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	menuIds, err := roleRepo.GetMenuIds(uri.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取角色菜单失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": menuIds})
+}
+
+// SetRoleMenus 设置角色的菜单和按钮权限
+func SetRoleMenus(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	var req struct {
+		MenuIds []uint `json:"menuIds"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	role, ok := getRole(c, &roleRepo, uri.ID)
+	if !ok || !checkSuperRole(c, role) {
+		return
+	}
+
+	menuRepo := systemRepo.MenuRepository{DB: global.Db}
+	menus, err := menuRepo.ListByIds(req.MenuIds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取菜单失败"})
+		return
+	}
+
+	if err := roleRepo.SetMenus(uri.ID, menus); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "设置角色菜单失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": nil})
+}
+
+// GetUserRoles 获取用户的角色ID
+func GetUserRoles(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	roleIds, err := roleRepo.GetUserRoleIds(uri.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户角色失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": roleIds})
+}
+
+// SetUserRoles 设置用户的角色
+func SetUserRoles(c *gin.Context) {
+	var uri struct {
+		ID uint `uri:"id" binding:"required"`
+	}
+	var req struct {
+		RoleIds []uint `json:"roleIds"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	if _, err := userRepo.Get(&system.User{UserId: uri.ID}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
+		return
+	}
+
+	// 超级管理员角色只能由超级管理员分配或收回
+	operator, ok := getOperatorPermission(c)
+	if !ok {
+		return
+	}
+	target, err := global.Permissions.Get(uri.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取权限失败"})
+		return
+	}
+	if target.Super && !operator.Super {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "不能修改超级管理员的角色"})
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	for _, roleID := range req.RoleIds {
+		role, ok := getRole(c, &roleRepo, roleID)
+		if !ok {
+			return
+		}
+		if role.Code == coreSystem.SuperRoleCode && !operator.Super {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "只有超级管理员可以分配超级管理员角色"})
+			return
+		}
+	}
+
+	if err := roleRepo.SetUserRoles(uri.ID, req.RoleIds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "设置用户角色失败"})
+		return
+	}
+	global.Permissions.Flush()
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": nil})
+}
+
+// getRole 获取角色，不存在时直接写入响应
+func getRole(c *gin.Context, roleRepo *systemRepo.RoleRepository, roleID uint) (*system.SysRole, bool) {
+	role, err := roleRepo.Get(roleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "角色不存在"})
+		return nil, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取角色失败"})
+		return nil, false
+	}
+	return role, true
+}
+
+// getOperatorPermission 获取当前用户的权限，失败时直接写入响应
+func getOperatorPermission(c *gin.Context) (coreSystem.UserPermission, bool) {
+	operator, err := global.Permissions.Get(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取权限失败"})
+		return operator, false
+	}
+	return operator, true
+}
+
+// checkSuperRole 超级管理员角色只能由超级管理员修改，不允许时直接写入响应
+func checkSuperRole(c *gin.Context, role *system.SysRole) bool {
+	if role.Code != coreSystem.SuperRoleCode {
+		return true
+	}
+	operator, ok := getOperatorPermission(c)
+	if !ok {
+		return false
+	}
+	if !operator.Super {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "只有超级管理员可以修改超级管理员角色"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+package system
+
+import (
+	"encoding/json"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/auth"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/sso"
+	coreSystem "eve-corp-manager/core/system"
+	esiToken "eve-corp-manager/core/token"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	"eve-corp-manager/models/service/character"
+	systemRepo "eve-corp-manager/repository/system"
+	"eve-corp-manager/utils"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateTTL 登录state有效期
+const ssoStateTTL = 10 * time.Minute
+
+// ssoState 登录过程中保存在Redis中的状态
+type ssoState struct {
+	CodeVerifier string `json:"codeVerifier"`
+	UserID       uint   `json:"userId"` // 非0时表示为已登录用户绑定新角色
+}
+
+func ssoStateKey(state string) string {
+	return "sso:state:" + state
+}
+
+// EveLogin 跳转到EVE SSO登录
+func EveLogin(c *gin.Context) {
+	loginURL, err := newLoginURL(c, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "登录失败"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, loginURL)
+}
+
+// EveBind 为当前用户绑定新角色，返回SSO授权地址由前端跳转
+func EveBind(c *gin.Context) {
+	loginURL, err := newLoginURL(c, middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "绑定角色失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "ok", "data": loginURL})
+}
+
+// newLoginURL 生成PKCE和state并返回SSO授权地址
+func newLoginURL(c *gin.Context, userID uint) (string, error) {
+	verifier, challenge, err := sso.NewPKCE()
+	if err != nil {
+		global.Logger.Error("生成PKCE失败:", err)
+		return "", err
+	}
+	state, err := sso.NewState()
+	if err != nil {
+		global.Logger.Error("生成state失败:", err)
+		return "", err
+	}
+
+	stateData, _ := json.Marshal(ssoState{CodeVerifier: verifier, UserID: userID})
+	if err := global.Redis.Set(c.Request.Context(), ssoStateKey(state), stateData, ssoStateTTL).Err(); err != nil {
+		global.Logger.Error("保存登录state失败:", err)
+		return "", err
+	}
+
+	return sso.SsoClient.AuthorizeURL(state, challenge), nil
+}
+
+// EveCallback EVE SSO登录回调
+func EveCallback(c *gin.Context) {
+	var req struct {
+		Code  string `form:"code" binding:"required"`
+		State string `form:"state" binding:"required"`
+	}
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	// state只能使用一次
+	stateData, err := global.Redis.GetDel(c.Request.Context(), ssoStateKey(req.State)).Bytes()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "登录已过期，请重新登录"})
+		return
+	}
+	var state ssoState
+	if err := json.Unmarshal(stateData, &state); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "登录已过期，请重新登录"})
+		return
+	}
+
+	token, err := sso.SsoClient.ExchangeCode(c.Request.Context(), req.Code, state.CodeVerifier)
+	if err != nil {
+		global.Logger.Error("SSO换取令牌失败:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"code": 502, "message": "EVE SSO验证失败"})
+		return
+	}
+
+	claims, err := sso.SsoClient.VerifyAccessToken(token.AccessToken)
+	if err != nil {
+		global.Logger.Error("SSO令牌校验失败:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "EVE SSO验证失败"})
+		return
+	}
+
+	characterID, err := claims.CharacterID()
+	if err != nil {
+		global.Logger.Error("解析角色ID失败:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "EVE SSO验证失败"})
+		return
+	}
+
+	userCharacter := &character.UserCharacter{
+		CharacterID:   characterID,
+		CharacterName: claims.Name,
+		OwnerHash:     claims.Owner,
+		RefreshToken:  token.RefreshToken,
+		Scopes:        strings.Join(claims.Scopes, " "),
+		Status:        character.CharacterStatusValid,
+	}
+
+	// 公司和联盟信息获取失败不影响登录
+	if info, err := esi.GetCharacterPublicInfo(c.Request.Context(), characterID); err != nil {
+		global.Logger.Warnf("获取角色公开信息失败, characterID: %v, error: %v", characterID, err)
+	} else {
+		userCharacter.CorpID = info.CorporationID
+		userCharacter.AllianceID = info.AllianceID
+	}
+
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	user, err := userRepo.BindCharacter(state.UserID, userCharacter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "保存角色失败"})
+		return
+	}
+
+	global.Logger.Info("EVE SSO登录成功, 用户ID:", user.UserId, "角色:", claims.Name)
+
+	if err := esiToken.ESITokens.Store(c.Request.Context(), characterID, token.AccessToken, token.ExpiresIn); err != nil {
+		global.Logger.Warnf("缓存ESI访问令牌失败, characterID: %v, error: %v", characterID, err)
+	}
+
+	grantSuperAdmin(user.UserId, characterID)
+
+	ticket, err := auth.TokenManager.IssueLoginTicket(c.Request.Context(), user.UserId)
+	if err != nil {
+		global.Logger.Error("签发登录票据失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "登录失败"})
+		return
+	}
+
+	// 配置了前端地址时携带票据跳转，由前端调用/auth/login换取令牌
+	if redirectURL := config.AppConfig.Auth.LoginRedirectUrl; redirectURL != "" {
+		target, err := url.Parse(redirectURL)
+		if err == nil {
+			query := target.Query()
+			query.Set("ticket", ticket)
+			target.RawQuery = query.Encode()
+			c.Redirect(http.StatusFound, target.String())
+			return
+		}
+		global.Logger.Error("Auth.LoginRedirectUrl格式错误:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "登录成功",
+		"data": gin.H{
+			"ticket":    ticket,
+			"user":      user,
+			"character": userCharacter,
+		},
+	})
+}
+
+// grantSuperAdmin 配置在Auth.SuperAdminCharacterIds中的角色登录后自动授予超级管理员
+func grantSuperAdmin(userID, characterID uint) {
+	adminIds, err := utils.StringToIntList(config.AppConfig.Auth.SuperAdminCharacterIds)
+	if err != nil {
+		global.Logger.Error("Auth.SuperAdminCharacterIds格式错误:", err)
+		return
+	}
+	if !slices.Contains(adminIds, characterID) {
+		return
+	}
+
+	roleRepo := systemRepo.RoleRepository{DB: global.Db}
+	role, err := roleRepo.GetByCode(coreSystem.SuperRoleCode)
+	if err != nil {
+		global.Logger.Error("获取超级管理员角色失败:", err)
+		return
+	}
+	if err := roleRepo.AddUserRole(userID, role.ID); err != nil {
+		return
+	}
+	global.Permissions.Flush()
+}
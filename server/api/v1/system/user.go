@@ -0,0 +1,63 @@
+package system
+
+import (
+	"eve-corp-manager/config"
+	"eve-corp-manager/global"
+	"eve-corp-manager/middleware"
+	systemRepo "eve-corp-manager/repository/system"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultImageURL 官方图片服务地址
+const defaultImageURL = "https://images.evetech.net"
+
+// characterPortraitURL 角色头像地址
+func characterPortraitURL(characterID int) string {
+	imageURL := strings.TrimRight(config.AppConfig.Esi.ImageUrl, "/")
+	if imageURL == "" {
+		imageURL = defaultImageURL
+	}
+	return fmt.Sprintf("%s/characters/%d/portrait?size=128", imageURL, characterID)
+}
+
+// GetUserInfo 获取当前用户信息
+func GetUserInfo(c *gin.Context) {
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	user, err := userRepo.GetCharacterList(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在"})
+		return
+	}
+
+	perm, err := global.Permissions.Get(user.UserId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户角色失败"})
+		return
+	}
+
+	avatar := ""
+	if user.MainCharacterId != 0 {
+		avatar = characterPortraitURL(user.MainCharacterId)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "ok",
+		"data": gin.H{
+			"userId":          fmt.Sprint(user.UserId),
+			"username":        user.Name,
+			"realName":        user.Name,
+			"avatar":          avatar,
+			"desc":            "",
+			"homePath":        "",
+			"roles":           perm.Roles,
+			"mainCharacterId": user.MainCharacterId,
+			"qq":              user.Qq,
+			"characters":      user.Characters,
+		},
+	})
+}
@@ -0,0 +1,50 @@
+// 使用当前密钥重新加密所有ESI refresh token
+// 轮换密钥步骤：在Encryption.Keys中追加新密钥并将ActiveKey改为新密钥ID，执行本命令，确认完成后再移除旧密钥
+package main
+
+import (
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/secret"
+	"eve-corp-manager/global"
+	"eve-corp-manager/initialize/database"
+	"eve-corp-manager/initialize/run_log"
+	initSecret "eve-corp-manager/initialize/secret"
+	"eve-corp-manager/repository/service/character"
+	"log"
+)
+
+func main() {
+	config.InitConfig()
+
+	logger, err := run_log.InitLog(config.AppConfig.App.Env, "/reencrypt_tokens.log")
+	if err != nil {
+		log.Fatalln("Log initialization error", err)
+	}
+	global.Logger = logger
+
+	if err := initSecret.InitTokenKeyring(); err != nil {
+		log.Fatalln("Encryption key initialization error", err)
+	}
+	if secret.TokenKeyring.ActiveKeyID() == "" {
+		log.Fatalln("未配置Encryption.ActiveKey，无法重新加密")
+	}
+
+	db, err := database.DbInit(&database.MySQLConfig{
+		Dsn:          config.AppConfig.Database.Dsn,
+		MaxIdleConns: config.AppConfig.Database.MaxIdleConns,
+		MaxOpenConns: config.AppConfig.Database.MaxOpenConns,
+		WaitTimeout:  config.AppConfig.Database.WaitTimeOut,
+	})
+	if err != nil {
+		log.Fatalln("Database initialization error", err)
+	}
+	global.Db = db
+
+	repo := character.UserCharacterRepository{DB: db}
+	updated, err := repo.ReencryptRefreshTokens(secret.TokenKeyring, 500)
+	if err != nil {
+		log.Fatalf("重新加密未完成，已更新%d条，请勿移除旧密钥: %v", updated, err)
+	}
+
+	global.Logger.Infof("refresh token重新加密完成，使用密钥%s，共更新%d条", secret.TokenKeyring.ActiveKeyID(), updated)
+}
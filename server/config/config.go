@@ -36,6 +36,52 @@ type Config struct {
 	QQ struct {
 		OnebotUrl string
 	}
+	Sso struct {
+		ClientId     string
+		ClientSecret string
+		CallbackUrl  string
+		Scopes       string
+		AuthorizeUrl string
+		TokenUrl     string
+		JwksUrl      string
+		Issuer       string
+	}
+	Auth struct {
+		JwtSecret              string
+		AccessTokenTTL         int // 访问令牌有效期(分钟)
+		RefreshTokenTTL        int // refresh token有效期(小时)
+		LoginRedirectUrl       string
+		SuperAdminCharacterIds string // 登录后自动授予超级管理员的EVE角色ID，逗号分隔
+	}
+	Esi struct {
+		BaseUrl    string // 留空使用官方地址
+		Datasource string // tranquility(欧服) 或 serenity(国服)
+		ImageUrl   string // 图片服务地址，留空使用官方地址
+	}
+	Janice struct {
+		BaseUrl string // 留空使用官方地址
+	}
+	Zkillboard struct {
+		BaseUrl      string // 留空使用官方地址
+		RedisqUrl    string // RedisQ地址，留空使用官方地址
+		QueueId      string // RedisQ队列ID，留空时自动生成并保存在Redis
+		WebsocketUrl string // websocket地址，留空使用官方地址
+		Feed         string // 推送来源 websocket(默认，只接收关注的公司/联盟，重连后通过ESI补齐) 或 redisq(全部击毁邮件)
+		Listen       bool   // 是否监听推送自动保存本公司相关的击毁邮件
+	}
+	Killmail struct {
+		SyncInterval int // 通过ESI同步公司和角色击毁邮件的间隔(分钟)，0表示不同步
+	}
+	Srp struct {
+		AutoInterval      int // 自动将损失匹配到舰队并创建补损申请的间隔(分钟)，0表示不启用
+		AutoDays          int // 自动匹配最近多少天的损失，默认7天
+		MaxFleetHours     int // 未填写结束时间的舰队按开始后多少小时结束匹配，默认24小时
+		ReconcileInterval int // 读取公司钱包流水确认补损发放的间隔(分钟)，0表示不启用
+	}
+	Encryption struct {
+		ActiveKey string // 当前用于加密的密钥ID
+		Keys      string // 格式 id1:base64key,id2:base64key，密钥为32字节
+	}
 }
 
 var AppConfig *Config
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	refreshKeyPrefix     = "auth:refresh:"      // 有效的refresh token -> 用户ID
+	usedRefreshKeyPrefix = "auth:refresh_used:" // 已轮换的refresh token -> 用户ID，用于检测重放
+	userRefreshKeyPrefix = "auth:user_refresh:" // 用户ID -> 该用户所有refresh token
+	ticketKeyPrefix      = "auth:ticket:"       // SSO登录后的一次性票据 -> 用户ID
+
+	ticketTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidToken = errors.New("令牌无效或已过期")
+	ErrTokenReused  = errors.New("refresh token被重复使用")
+)
+
+// Options 令牌配置
+type Options struct {
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// Claims 访问令牌载荷
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID uint   `json:"uid"`
+	Name   string `json:"name"`
+}
+
+// Manager 负责签发和校验访问令牌以及轮换refresh token
+type Manager struct {
+	redis *redis.Client
+	opts  Options
+}
+
+// TokenManager 全局令牌管理器
+var TokenManager *Manager
+
+// NewManager 创建令牌管理器
+func NewManager(redisClient *redis.Client, opts Options) *Manager {
+	return &Manager{
+		redis: redisClient,
+		opts:  opts,
+	}
+}
+
+// RefreshTTL refresh token有效期
+func (m *Manager) RefreshTTL() time.Duration {
+	return m.opts.RefreshTTL
+}
+
+// IssueAccessToken 签发访问令牌
+func (m *Manager) IssueAccessToken(userID uint, name string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.opts.AccessTTL)),
+		},
+		UserID: userID,
+		Name:   name,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.opts.Secret)
+}
+
+// ParseAccessToken 校验访问令牌
+func (m *Manager) ParseAccessToken(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.opts.Secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil || claims.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// IssueRefreshToken 签发refresh token
+func (m *Manager) IssueRefreshToken(ctx context.Context, userID uint) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	userKey := userRefreshKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	pipe := m.redis.TxPipeline()
+	pipe.Set(ctx, refreshKeyPrefix+token, userID, m.opts.RefreshTTL)
+	pipe.SAdd(ctx, userKey, token)
+	pipe.Expire(ctx, userKey, m.opts.RefreshTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken 使用旧的refresh token换取新的refresh token，旧令牌立即失效
+// 已轮换的旧令牌再次出现时视为泄露，吊销该用户所有refresh token
+func (m *Manager) RotateRefreshToken(ctx context.Context, token string) (uint, string, error) {
+	userID, err := m.redis.GetDel(ctx, refreshKeyPrefix+token).Uint64()
+	if errors.Is(err, redis.Nil) {
+		if usedUserID, err := m.redis.Get(ctx, usedRefreshKeyPrefix+token).Uint64(); err == nil {
+			_ = m.RevokeAll(ctx, uint(usedUserID))
+			return 0, "", ErrTokenReused
+		}
+		return 0, "", ErrInvalidToken
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	userKey := userRefreshKeyPrefix + strconv.FormatUint(userID, 10)
+	pipe := m.redis.TxPipeline()
+	pipe.Set(ctx, usedRefreshKeyPrefix+token, userID, m.opts.RefreshTTL)
+	pipe.SRem(ctx, userKey, token)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, "", err
+	}
+
+	newToken, err := m.IssueRefreshToken(ctx, uint(userID))
+	if err != nil {
+		return 0, "", err
+	}
+	return uint(userID), newToken, nil
+}
+
+// RevokeRefreshToken 吊销单个refresh token
+func (m *Manager) RevokeRefreshToken(ctx context.Context, token string) error {
+	userID, err := m.redis.GetDel(ctx, refreshKeyPrefix+token).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return m.redis.SRem(ctx, userRefreshKeyPrefix+strconv.FormatUint(userID, 10), token).Err()
+}
+
+// RevokeAll 吊销用户所有refresh token
+func (m *Manager) RevokeAll(ctx context.Context, userID uint) error {
+	userKey := userRefreshKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	tokens, err := m.redis.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		keys = append(keys, refreshKeyPrefix+token)
+	}
+	keys = append(keys, userKey)
+	return m.redis.Del(ctx, keys...).Err()
+}
+
+// IssueLoginTicket 签发SSO登录后用于换取令牌的一次性票据
+func (m *Manager) IssueLoginTicket(ctx context.Context, userID uint) (string, error) {
+	ticket, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := m.redis.Set(ctx, ticketKeyPrefix+ticket, userID, ticketTTL).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// ConsumeLoginTicket 消费一次性票据并返回用户ID
+func (m *Manager) ConsumeLoginTicket(ctx context.Context, ticket string) (uint, error) {
+	userID, err := m.redis.GetDel(ctx, ticketKeyPrefix+ticket).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint(userID), nil
+}
+
+// randomToken 生成随机令牌
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机令牌失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisKeyCache 每个缓存项单独保存为一个Redis键，由Redis按过期时间自动删除
+// 适合键数量不确定、大多只读取一次的缓存，避免过期项一直留在同一个hash中
+type RedisKeyCache[T any] struct {
+	Redis             *redis.Client
+	Ctx               context.Context
+	Prefix            string
+	DefaultExpiration time.Duration
+}
+
+// NewRedisKeyCache 创建按键存储的Redis缓存，键名为 prefix:k
+func NewRedisKeyCache[T any](redisClient *redis.Client, prefix string, defaultExpiration time.Duration) *RedisKeyCache[T] {
+	return &RedisKeyCache[T]{
+		Redis:             redisClient,
+		Ctx:               context.Background(),
+		Prefix:            prefix,
+		DefaultExpiration: defaultExpiration,
+	}
+}
+
+func (r *RedisKeyCache[T]) key(k string) string {
+	return r.Prefix + ":" + k
+}
+
+// Set 设置缓存，d不大于0时不过期
+func (r *RedisKeyCache[T]) Set(k string, v T, d time.Duration) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if d < 0 {
+		d = 0
+	}
+	r.Redis.Set(r.Ctx, r.key(k), jsonData, d)
+}
+
+// Get 获取缓存
+func (r *RedisKeyCache[T]) Get(k string) (T, bool) {
+	var value T
+	jsonData, err := r.Redis.Get(r.Ctx, r.key(k)).Bytes()
+	if err != nil {
+		return value, false
+	}
+	if err := json.Unmarshal(jsonData, &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+// SetDefault 使用默认过期时间设置缓存
+func (r *RedisKeyCache[T]) SetDefault(k string, v T) {
+	r.Set(k, v, r.DefaultExpiration)
+}
+
+// Delete 删除缓存项
+func (r *RedisKeyCache[T]) Delete(k string) {
+	r.Redis.Del(r.Ctx, r.key(k))
+}
+
+// SetKeepExpiration 设置值但不重置过期时间，键不存在时使用默认过期时间
+func (r *RedisKeyCache[T]) SetKeepExpiration(k string, v T) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if ok, err := r.Redis.SetXX(r.Ctx, r.key(k), jsonData, redis.KeepTTL).Result(); err == nil && ok {
+		return
+	}
+	r.SetDefault(k, v)
+}
+
+// ItemCount 获取缓存项目数量
+func (r *RedisKeyCache[T]) ItemCount() (int64, error) {
+	var count int64
+	iter := r.Redis.Scan(r.Ctx, 0, r.key("*"), 1000).Iterator()
+	for iter.Next(r.Ctx) {
+		count++
+	}
+	return count, iter.Err()
+}
+
+// Flush 清空缓存
+func (r *RedisKeyCache[T]) Flush() {
+	iter := r.Redis.Scan(r.Ctx, 0, r.key("*"), 1000).Iterator()
+	keys := make([]string, 0, 1000)
+	for iter.Next(r.Ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == cap(keys) {
+			r.Redis.Del(r.Ctx, keys...)
+			keys = keys[:0]
+		}
+	}
+	if len(keys) > 0 {
+		r.Redis.Del(r.Ctx, keys...)
+	}
+}
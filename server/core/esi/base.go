@@ -2,13 +2,14 @@ package esi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"eve-corp-manager/core/cache"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
-	"sync"
+	"strings"
 	"time"
 )
 
@@ -18,13 +19,22 @@ const (
 
 	typeEsi    = "esi"
 	typeJanice = "janice"
+
+	// DatasourceTranquility 欧服
+	DatasourceTranquility = "tranquility"
+	// DatasourceSerenity 国服
+	DatasourceSerenity = "serenity"
 )
 
 // Client HTTP客户端
 type Client struct {
-	client    *http.Client
-	userAgent string
-	baseURL   string
+	client     *http.Client
+	userAgent  string
+	baseURL    string
+	cache      cache.Cache[CachedResponse]
+	limiter    *errorLimiter
+	timeout    time.Duration // 单次调用超时，包括重试和错误额度暂停
+	datasource string
 }
 
 // EsiClient 全局客户端实例
@@ -33,8 +43,8 @@ var (
 	JaniceClient *Client
 )
 
-// NewClient 创建一个新的ESI客户端
-func NewClient(proxyHost, proxyPort, userAgent, apiType string) *Client {
+// NewClient 创建一个新的ESI客户端，baseURL留空时根据apiType使用官方地址
+func NewClient(proxyHost, proxyPort, userAgent, apiType, baseURL string) *Client {
 	transport := &http.Transport{
 		MaxIdleConns:    2000,
 		IdleConnTimeout: 90 * time.Second,
@@ -52,44 +62,50 @@ func NewClient(proxyHost, proxyPort, userAgent, apiType string) *Client {
 		Timeout:   time.Second * 30,
 	}
 
-	var baseURL string
-	switch apiType {
-	case typeEsi:
-		baseURL = baseESIURL
-	case typeJanice:
-		baseURL = baseJaniceURL
+	if baseURL == "" {
+		switch apiType {
+		case typeEsi:
+			baseURL = baseESIURL
+		case typeJanice:
+			baseURL = baseJaniceURL
+		}
 	}
 
 	return &Client{
-		client:    client,
-		userAgent: userAgent,
-		baseURL:   baseURL,
+		client:     client,
+		userAgent:  userAgent,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		limiter:    newErrorLimiter(),
+		timeout:    defaultCallTimeout,
+		datasource: DatasourceTranquility,
 	}
 }
 
-// Get 发送GET请求到ESI API
-func (c *Client) Get(path string, query url.Values) (*http.Response, error) {
-	reqURL := c.baseURL + path
-	if query != nil {
-		reqURL += "?" + query.Encode()
-	}
-
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, err
+// SetDatasource 设置ESI数据源(tranquility/serenity)
+func (c *Client) SetDatasource(datasource string) {
+	if datasource != "" {
+		c.datasource = datasource
 	}
+}
 
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "application/json")
+// Datasource 当前ESI数据源
+func (c *Client) Datasource() string {
+	return c.datasource
+}
 
-	return c.client.Do(req)
+// Get 发送GET请求到ESI API
+func (c *Client) Get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	return c.get(ctx, path, query, nil)
 }
 
 // Post 发送POST请求到ESI API
-func (c *Client) Post(path string, contentType string, body []byte) (*http.Response, error) {
+func (c *Client) Post(ctx context.Context, path string, contentType string, body []byte) (*http.Response, error) {
+	ctx, cancel := c.callContext(ctx)
+
 	reqURL := c.baseURL + path
-	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, bytes.NewBuffer(body))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
@@ -97,162 +113,99 @@ func (c *Client) Post(path string, contentType string, body []byte) (*http.Respo
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
 
-	return c.client.Do(req)
+	resp, err := c.do(req)
+	return releaseOnClose(resp, err, cancel)
 }
 
 // GetJSON 发送GET请求并将结果解析为JSON
-func (c *Client) GetJSON(path string, query url.Values, result interface{}) error {
-	resp, err := c.Get(path, query)
+func (c *Client) GetJSON(ctx context.Context, path string, query url.Values, result interface{}) error {
+	resp, err := c.Get(ctx, path, query)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ESI API错误 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return json.NewDecoder(resp.Body).Decode(result)
+	return decodeResponse(resp, result)
 }
 
-// AuthorizedGet 发送带授权的GET请求
-func (c *Client) AuthorizedGet(path string, query url.Values, token string) (*http.Response, error) {
-	reqURL := c.baseURL + path
-	if query != nil {
-		reqURL += "?" + query.Encode()
-	}
-
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	return c.client.Do(req)
+// AuthorizedGet 发送带授权的GET请求，令牌被拒绝(401)时刷新令牌并重试一次
+func (c *Client) AuthorizedGet(ctx context.Context, path string, query url.Values, tokenSource TokenSource) (*http.Response, error) {
+	return c.get(ctx, path, query, tokenSource)
 }
 
 // AuthorizedGetJSON 发送带授权的GET请求并解析JSON
-func (c *Client) AuthorizedGetJSON(path string, query url.Values, token string, result interface{}) error {
-	resp, err := c.AuthorizedGet(path, query, token)
+func (c *Client) AuthorizedGetJSON(ctx context.Context, path string, query url.Values, tokenSource TokenSource, result interface{}) error {
+	resp, err := c.AuthorizedGet(ctx, path, query, tokenSource)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ESI API错误 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return json.NewDecoder(resp.Body).Decode(result)
+	return decodeResponse(resp, result)
 }
 
-// GetAllPages 并发获取所有分页数据
-func (c *Client) GetAllPages(path string, query url.Values, resultContainer interface{}) error {
-	// 首先获取第一页来确定总页数
-	resp, err := c.Get(path, query)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// get 发送GET请求，整个调用(包括重试和刷新令牌)受单次调用超时限制
+func (c *Client) get(ctx context.Context, path string, query url.Values, tokenSource TokenSource) (*http.Response, error) {
+	ctx, cancel := c.callContext(ctx)
+	resp, err := c.getOnce(ctx, path, query, tokenSource)
+	return releaseOnClose(resp, err, cancel)
+}
 
-	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ESI API错误 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
+// getOnce 发送GET请求，tokenSource不为空时带授权，令牌被拒绝(401)时刷新令牌并重试一次
+func (c *Client) getOnce(ctx context.Context, path string, query url.Values, tokenSource TokenSource) (*http.Response, error) {
+	if tokenSource == nil {
+		req, err := c.newGetRequest(ctx, path, query)
+		if err != nil {
+			return nil, err
+		}
+		return c.doCached(req, "")
 	}
 
-	// 获取总页数
-	totalPagesStr := resp.Header.Get("X-Pages")
-	totalPages, err := strconv.Atoi(totalPagesStr)
+	token, err := tokenSource.Token(ctx)
 	if err != nil {
-		return fmt.Errorf("解析X-Pages失败: %w", err)
+		return nil, err
 	}
 
-	// 如果只有一页，直接解析并返回
-	if totalPages <= 1 {
-		return json.NewDecoder(resp.Body).Decode(resultContainer)
+	resp, err := c.authorizedGet(ctx, path, query, token, tokenIdentity(tokenSource, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
 	}
+	resp.Body.Close()
 
-	// 解析第一页数据
-	var firstPageData []interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&firstPageData); err != nil {
-		return err
+	tokenSource.Invalidate(ctx)
+	token, err = tokenSource.Token(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return c.authorizedGet(ctx, path, query, token, tokenIdentity(tokenSource, token))
+}
 
-	// 创建一个等待组和结果通道
-	var wg sync.WaitGroup
-	resultChan := make(chan []interface{}, totalPages)
-	errorChan := make(chan error, totalPages)
-
-	// 已经获取了第一页，将其放入结果通道
-	resultChan <- firstPageData
-
-	// 限制最大并发数
-	semaphore := make(chan struct{}, 100)
-
-	// 并发请求剩余页面
-	for page := 2; page <= totalPages; page++ {
-		wg.Add(1)
-		go func(pageNum int) {
-			defer wg.Done()
-
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// 复制查询参数并添加页码
-			pageQuery := url.Values{}
-			for k, v := range query {
-				pageQuery[k] = v
-			}
-			pageQuery.Set("page", strconv.Itoa(pageNum))
-
-			// 获取当前页数据
-			var pageData []interface{}
-			err := c.GetJSON(path, pageQuery, &pageData)
-			if err != nil {
-				errorChan <- fmt.Errorf("获取第%d页失败: %w", pageNum, err)
-				return
-			}
-
-			resultChan <- pageData
-		}(page)
+// authorizedGet 使用指定令牌发送GET请求
+func (c *Client) authorizedGet(ctx context.Context, path string, query url.Values, token, identity string) (*http.Response, error) {
+	req, err := c.newGetRequest(ctx, path, query)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	// 等待所有请求完成
-	wg.Wait()
-	close(resultChan)
-	close(errorChan)
-
-	// 检查是否有错误
-	if len(errorChan) > 0 {
-		return <-errorChan
-	}
+	return c.doCached(req, identity)
+}
 
-	// 合并所有结果
-	var allResults []interface{}
-	for result := range resultChan {
-		allResults = append(allResults, result...)
+// newGetRequest 构造GET请求
+func (c *Client) newGetRequest(ctx context.Context, path string, query url.Values) (*http.Request, error) {
+	reqURL := c.baseURL + path
+	if query != nil {
+		reqURL += "?" + query.Encode()
 	}
 
-	// 将合并的结果转换为预期类型
-	resultBytes, err := json.Marshal(allResults)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return json.Unmarshal(resultBytes, resultContainer)
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+	return req, nil
 }
 
-// AuthorizedGetAllPages 带授权的并发获取所有分页数据
-func (c *Client) AuthorizedGetAllPages(path string, query url.Values, token string, resultContainer interface{}) error {
-	// 首先获取第一页来确定总页数
-	resp, err := c.AuthorizedGet(path, query, token)
-	if err != nil {
-		return err
-	}
+// decodeResponse 检查状态码并将响应解析为JSON
+func decodeResponse(resp *http.Response, result interface{}) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
@@ -260,83 +213,5 @@ func (c *Client) AuthorizedGetAllPages(path string, query url.Values, token stri
 		return fmt.Errorf("ESI API错误 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// 获取总页数
-	totalPagesStr := resp.Header.Get("X-Pages")
-	totalPages, err := strconv.Atoi(totalPagesStr)
-	if err != nil {
-		return fmt.Errorf("解析X-Pages失败: %w", err)
-	}
-
-	// 如果只有一页，直接解析并返回
-	if totalPages <= 1 {
-		return json.NewDecoder(resp.Body).Decode(resultContainer)
-	}
-
-	// 解析第一页数据
-	var firstPageData []interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&firstPageData); err != nil {
-		return err
-	}
-
-	// 创建一个等待组和结果通道
-	var wg sync.WaitGroup
-	resultChan := make(chan []interface{}, totalPages)
-	errorChan := make(chan error, totalPages)
-
-	// 已经获取了第一页，将其放入结果通道
-	resultChan <- firstPageData
-
-	// 限制最大并发数
-	semaphore := make(chan struct{}, 100)
-
-	// 并发请求剩余页面
-	for page := 2; page <= totalPages; page++ {
-		wg.Add(1)
-		go func(pageNum int) {
-			defer wg.Done()
-
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// 复制查询参数并添加页码
-			pageQuery := url.Values{}
-			for k, v := range query {
-				pageQuery[k] = v
-			}
-			pageQuery.Set("page", strconv.Itoa(pageNum))
-
-			// 获取当前页数据
-			var pageData []interface{}
-			err := c.AuthorizedGetJSON(path, pageQuery, token, &pageData)
-			if err != nil {
-				errorChan <- fmt.Errorf("获取第%d页失败: %w", pageNum, err)
-				return
-			}
-
-			resultChan <- pageData
-		}(page)
-	}
-
-	// 等待所有请求完成
-	wg.Wait()
-	close(resultChan)
-	close(errorChan)
-
-	// 检查是否有错误
-	if len(errorChan) > 0 {
-		return <-errorChan
-	}
-
-	// 合并所有结果
-	var allResults []interface{}
-	for result := range resultChan {
-		allResults = append(allResults, result...)
-	}
-
-	// 将合并的结果转换为预期类型
-	resultBytes, err := json.Marshal(allResults)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(resultBytes, resultContainer)
+	return json.NewDecoder(resp.Body).Decode(result)
 }
@@ -0,0 +1,159 @@
+package esi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"eve-corp-manager/core/cache"
+	"io"
+	"net/http"
+	"time"
+)
+
+// revalidateWindow 响应过期后继续保留的时间，用于携带ETag重新验证
+const revalidateWindow = 24 * time.Hour
+
+// CachedResponse 缓存的ESI响应
+type CachedResponse struct {
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"lastModified"`
+	Expires      time.Time   `json:"expires"`
+}
+
+// CacheKeyer 令牌源可实现该接口，使同一角色的授权响应在令牌刷新后仍能命中缓存
+type CacheKeyer interface {
+	CacheKey() string
+}
+
+// SetCache 设置HTTP响应缓存，传nil关闭缓存
+func (c *Client) SetCache(responseCache cache.Cache[CachedResponse]) {
+	c.cache = responseCache
+}
+
+// doCached 发送GET请求，未过期时直接返回缓存，过期后携带ETag/Last-Modified重新验证
+// identity用于区分不同授权身份的缓存，公开接口传空字符串，ctx由WithoutCache标记时直接请求
+func (c *Client) doCached(req *http.Request, identity string) (*http.Response, error) {
+	if c.cache == nil || req.Method != http.MethodGet || cacheDisabled(req.Context()) {
+		return c.do(req)
+	}
+
+	key := responseCacheKey(req, identity)
+	cached, found := c.cache.Get(key)
+	if found && time.Now().Before(cached.Expires) {
+		return cached.response(req, "HIT"), nil
+	}
+
+	if found {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未修改，沿用缓存内容并更新过期时间
+	if found && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		cached.Expires = responseExpires(resp.Header)
+		for _, h := range []string{"Expires", "Date", "Last-Modified", "ETag", "X-Pages"} {
+			if v := resp.Header.Get(h); v != "" {
+				cached.Header.Set(h, v)
+			}
+		}
+		c.storeResponse(key, cached)
+		return cached.response(req, "REVALIDATED"), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	expires := responseExpires(resp.Header)
+	if etag == "" && lastModified == "" && !time.Now().Before(expires) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	c.storeResponse(key, CachedResponse{
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ETag:         etag,
+		LastModified: lastModified,
+		Expires:      expires,
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.Header.Set("X-Cache", "MISS")
+	return resp, nil
+}
+
+func (c *Client) storeResponse(key string, cached CachedResponse) {
+	ttl := time.Until(cached.Expires)
+	if ttl < 0 {
+		ttl = 0
+	}
+	c.cache.Set(key, cached, ttl+revalidateWindow)
+}
+
+// response 根据缓存构造响应
+func (r CachedResponse) response(req *http.Request, status string) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("X-Cache", status)
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// responseExpires 根据Expires和Date计算本地过期时间，避免本地时钟偏差
+func responseExpires(header http.Header) time.Time {
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return time.Time{}
+	}
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		return time.Now().Add(expires.Sub(date))
+	}
+	return expires
+}
+
+// responseCacheKey 以请求地址和授权身份生成缓存键
+func responseCacheKey(req *http.Request, identity string) string {
+	sum := sha256.Sum256([]byte(identity + "|" + req.URL.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenIdentity 获取令牌源的缓存身份
+func tokenIdentity(tokenSource TokenSource, token string) string {
+	if keyer, ok := tokenSource.(CacheKeyer); ok {
+		return keyer.CacheKey()
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,182 @@
+package esi_test
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// setupCache 启动模拟服务并为全局ESI客户端设置内存缓存
+func setupCache(t *testing.T) (*esitest.Server, *esitest.MemoryCache[esi.CachedResponse]) {
+	t.Helper()
+	srv := esitest.NewServer()
+	restore := srv.Install()
+	responseCache := esitest.NewMemoryCache[esi.CachedResponse](0)
+	esi.EsiClient.SetCache(responseCache)
+	t.Cleanup(func() {
+		restore()
+		srv.Close()
+	})
+	return srv, responseCache
+}
+
+// httpTime 返回距现在d的HTTP时间
+func httpTime(d time.Duration) string {
+	return time.Now().Add(d).UTC().Format(http.TimeFormat)
+}
+
+// getCached 请求path并返回X-Cache和响应内容
+func getCached(t *testing.T, ctx context.Context, path string) (string, string) {
+	t.Helper()
+	resp, err := esi.EsiClient.Get(ctx, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d %s", path, resp.StatusCode, body)
+	}
+	return resp.Header.Get("X-Cache"), string(body)
+}
+
+func TestCache(t *testing.T) {
+	const path = "/markets/prices/"
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		header map[string]string // 响应头
+		// update 在第二次请求前修改服务端数据
+		update    func(srv *esitest.Server)
+		wantCache []string // 两次请求的X-Cache
+		wantBody  string   // 第二次请求的响应内容
+		wantHits  int
+	}{
+		{
+			name:      "未过期时命中缓存",
+			header:    map[string]string{"Expires": httpTime(time.Hour)},
+			update:    func(srv *esitest.Server) { srv.SetJSON(path, []int{2}) },
+			wantCache: []string{"MISS", "HIT"},
+			wantBody:  "[1]",
+			wantHits:  1,
+		},
+		{
+			name:      "过期后ETag未变返回304并沿用缓存",
+			header:    map[string]string{"Expires": httpTime(-time.Hour), "ETag": `"v1"`},
+			wantCache: []string{"MISS", "REVALIDATED"},
+			wantBody:  "[1]",
+			wantHits:  2,
+		},
+		{
+			name:   "过期后ETag变化返回新内容",
+			header: map[string]string{"Expires": httpTime(-time.Hour), "ETag": `"v1"`},
+			update: func(srv *esitest.Server) {
+				srv.SetJSON(path, []int{2})
+				srv.SetHeader(path, "ETag", `"v2"`)
+			},
+			wantCache: []string{"MISS", "MISS"},
+			wantBody:  "[2]",
+			wantHits:  2,
+		},
+		{
+			name:      "没有Expires和ETag时不缓存",
+			update:    func(srv *esitest.Server) { srv.SetJSON(path, []int{2}) },
+			wantCache: []string{"", ""},
+			wantBody:  "[2]",
+			wantHits:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := setupCache(t)
+			srv.SetJSON(path, []int{1})
+			for key, value := range tt.header {
+				srv.SetHeader(path, key, value)
+			}
+
+			var got []string
+			cacheStatus, body := getCached(t, ctx, path)
+			got = append(got, cacheStatus)
+			if body != "[1]" {
+				t.Errorf("first body = %s, want [1]", body)
+			}
+			if tt.update != nil {
+				tt.update(srv)
+			}
+			cacheStatus, body = getCached(t, ctx, path)
+			got = append(got, cacheStatus)
+
+			if got[0] != tt.wantCache[0] || got[1] != tt.wantCache[1] {
+				t.Errorf("X-Cache = %q, want %q", got, tt.wantCache)
+			}
+			if body != tt.wantBody {
+				t.Errorf("second body = %s, want %s", body, tt.wantBody)
+			}
+			if hits := srv.Hits(path); hits != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCacheRevalidateRefreshesExpires(t *testing.T) {
+	const path = "/markets/prices/"
+	ctx := context.Background()
+
+	srv, _ := setupCache(t)
+	srv.SetJSON(path, []int{1})
+	srv.SetHeader(path, "ETag", `"v1"`)
+	srv.SetHeader(path, "Expires", httpTime(-time.Hour))
+
+	getCached(t, ctx, path)
+	// 304响应带新的Expires，之后直接命中缓存
+	srv.SetHeader(path, "Expires", httpTime(time.Hour))
+	if cacheStatus, _ := getCached(t, ctx, path); cacheStatus != "REVALIDATED" {
+		t.Fatalf("X-Cache = %q, want REVALIDATED", cacheStatus)
+	}
+	if cacheStatus, body := getCached(t, ctx, path); cacheStatus != "HIT" || body != "[1]" {
+		t.Errorf("after revalidate = %q %s, want HIT [1]", cacheStatus, body)
+	}
+	if hits := srv.Hits(path); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	const path = "/markets/prices/"
+
+	srv, responseCache := setupCache(t)
+	srv.SetJSON(path, []int{1})
+	srv.SetHeader(path, "Expires", httpTime(time.Hour))
+
+	// WithoutCache既不读取也不写入缓存
+	for range 2 {
+		if cacheStatus, _ := getCached(t, esi.WithoutCache(context.Background()), path); cacheStatus != "" {
+			t.Errorf("X-Cache = %q, want empty", cacheStatus)
+		}
+	}
+	if n, _ := responseCache.ItemCount(); n != 0 {
+		t.Errorf("cached %d responses, want 0", n)
+	}
+
+	// 错误响应不缓存
+	srv.FailNext(path, http.StatusNotFound, 1)
+	if resp, err := esi.EsiClient.Get(context.Background(), path, nil); err == nil {
+		resp.Body.Close()
+	}
+	if n, _ := responseCache.ItemCount(); n != 0 {
+		t.Errorf("cached %d responses after error, want 0", n)
+	}
+	if hits := srv.Hits(path); hits != 3 {
+		t.Errorf("hits = %d, want 3", hits)
+	}
+}
@@ -0,0 +1,31 @@
+package esi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// CharacterPublicInfo 角色公开信息
+type CharacterPublicInfo struct {
+	Name           string  `json:"name"`
+	CorporationID  uint    `json:"corporation_id"`
+	AllianceID     uint    `json:"alliance_id"`
+	Birthday       string  `json:"birthday"`
+	SecurityStatus float64 `json:"security_status"`
+}
+
+// GetCharacterPublicInfo 获取角色公开信息
+func GetCharacterPublicInfo(ctx context.Context, characterID uint) (*CharacterPublicInfo, error) {
+	var result CharacterPublicInfo
+
+	query := url.Values{}
+	query.Set("datasource", EsiClient.Datasource())
+
+	path := fmt.Sprintf("/characters/%d/", characterID)
+	if err := EsiClient.GetJSON(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -1,17 +1,18 @@
 package esi
 
 import (
+	"context"
 	"net/url"
 )
 
 // getServerStatus 获取服务器状态
-func getServerStatus() (map[string]interface{}, error) {
+func getServerStatus(ctx context.Context) (map[string]interface{}, error) {
 	var result map[string]interface{}
 
 	query := url.Values{}
-	query.Set("datasource", "tranquility")
+	query.Set("datasource", EsiClient.Datasource())
 
-	err := EsiClient.GetJSON("/status/", query, &result)
+	err := EsiClient.GetJSON(ctx, "/status/", query, &result)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,60 @@
+package esi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultCallTimeout 单次调用默认超时，需覆盖重试退避和错误额度暂停
+const defaultCallTimeout = 2 * time.Minute
+
+// SetCallTimeout 设置单次调用超时，0表示只使用调用方ctx的截止时间
+func (c *Client) SetCallTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// callContext 为单次调用附加超时，ctx已有更早的截止时间时以ctx为准
+func (c *Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// noCacheKey 标记不使用HTTP响应缓存的ctx
+type noCacheKey struct{}
+
+// WithoutCache 返回不读写HTTP响应缓存的ctx，用于大量只请求一次的地址，如RedisQ推送的全部击毁邮件
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheDisabled ctx是否不使用HTTP响应缓存
+func cacheDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCacheKey{}).(bool)
+	return disabled
+}
+
+// cancelOnClose 关闭响应体时释放调用的ctx
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// releaseOnClose 请求失败时立即释放ctx，成功时推迟到响应体关闭
+func releaseOnClose(resp *http.Response, err error, cancel context.CancelFunc) (*http.Response, error) {
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
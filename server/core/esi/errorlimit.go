@@ -0,0 +1,171 @@
+package esi
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultErrorLimitThreshold 剩余错误额度低于该值时暂停所有请求直到额度重置
+	defaultErrorLimitThreshold = 20
+
+	maxRetries     = 3
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// ErrorLimitState ESI错误额度状态
+type ErrorLimitState struct {
+	Remain      int       `json:"remain"`      // 剩余错误额度，-1表示尚未收到响应头
+	ResetAt     time.Time `json:"resetAt"`     // 额度重置时间
+	PausedUntil time.Time `json:"pausedUntil"` // 暂停请求直到该时间
+	Threshold   int       `json:"threshold"`   // 暂停阈值
+	UpdatedAt   time.Time `json:"updatedAt"`   // 最后更新时间
+}
+
+// errorLimiter 跟踪X-ESI-Error-Limit-*响应头，额度不足时暂停所有请求
+type errorLimiter struct {
+	mu    sync.Mutex
+	state ErrorLimitState
+}
+
+func newErrorLimiter() *errorLimiter {
+	return &errorLimiter{
+		state: ErrorLimitState{
+			Remain:    -1,
+			Threshold: defaultErrorLimitThreshold,
+		},
+	}
+}
+
+// wait 处于暂停期时阻塞，请求被取消时返回错误
+func (l *errorLimiter) wait(req *http.Request) error {
+	l.mu.Lock()
+	pausedUntil := l.state.PausedUntil
+	l.mu.Unlock()
+
+	delay := time.Until(pausedUntil)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// update 根据响应头更新错误额度
+func (l *errorLimiter) update(resp *http.Response) {
+	remainStr := resp.Header.Get("X-ESI-Error-Limit-Remain")
+	resetStr := resp.Header.Get("X-ESI-Error-Limit-Reset")
+	if remainStr == "" && resp.StatusCode != 420 {
+		return
+	}
+
+	remain, remainErr := strconv.Atoi(remainStr)
+	reset, resetErr := strconv.Atoi(resetStr)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.state.UpdatedAt = now
+	if remainErr == nil {
+		l.state.Remain = remain
+	}
+	if resetErr == nil {
+		l.state.ResetAt = now.Add(time.Duration(reset) * time.Second)
+	}
+
+	// 420表示已触发错误限制
+	if resp.StatusCode == 420 || (remainErr == nil && remain < l.state.Threshold) {
+		pausedUntil := l.state.ResetAt
+		if !pausedUntil.After(now) {
+			pausedUntil = now.Add(time.Minute)
+		}
+		if pausedUntil.After(l.state.PausedUntil) {
+			l.state.PausedUntil = pausedUntil
+		}
+	}
+}
+
+// snapshot 返回当前状态
+func (l *errorLimiter) snapshot() ErrorLimitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state
+}
+
+// ErrorLimitState 获取当前ESI错误额度状态
+func (c *Client) ErrorLimitState() ErrorLimitState {
+	return c.limiter.snapshot()
+}
+
+// SetErrorLimitThreshold 设置暂停请求的剩余额度阈值
+func (c *Client) SetErrorLimitThreshold(threshold int) {
+	c.limiter.mu.Lock()
+	c.limiter.state.Threshold = threshold
+	c.limiter.mu.Unlock()
+}
+
+// do 发送请求，遵守错误额度暂停，并对502/503/504进行带抖动的指数退避重试
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.limiter.update(resp)
+
+		if !isRetryableStatus(resp.StatusCode) || attempt >= maxRetries {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		timer := time.NewTimer(retryDelay(attempt))
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// retryDelay 指数退避加全抖动
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay))) + retryBaseDelay/2
+}
@@ -0,0 +1,146 @@
+package esi_test
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newLimitClient 启动模拟服务并创建独立的客户端，错误额度状态不与其他测试共享
+func newLimitClient(t *testing.T) (*esitest.Server, *esi.Client) {
+	t.Helper()
+	srv := esitest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv, esi.NewClient("", "", "esitest", "esi", srv.ESIURL())
+}
+
+// getStatus 请求/status/并返回状态码
+func getStatus(ctx context.Context, c *esi.Client) (int, error) {
+	resp, err := c.Get(ctx, "/status/", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestErrorLimitPause(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(srv *esitest.Server)
+		status  int
+		minWait time.Duration // 暂停时间至少为
+	}{
+		{
+			name:    "剩余额度低于阈值",
+			setup:   func(srv *esitest.Server) { srv.SetErrorLimit(5, 30, true) },
+			status:  http.StatusOK,
+			minWait: 25 * time.Second,
+		},
+		{
+			name:    "返回420",
+			setup:   func(srv *esitest.Server) { srv.FailNext("/status/", 420, 1) },
+			status:  420,
+			minWait: 55 * time.Second, // 默认重置时间60秒
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, c := newLimitClient(t)
+			tt.setup(srv)
+
+			status, err := getStatus(context.Background(), c)
+			if err != nil || status != tt.status {
+				t.Fatalf("first request = %d, %v, want %d", status, err, tt.status)
+			}
+			state := c.ErrorLimitState()
+			if wait := time.Until(state.PausedUntil); wait < tt.minWait {
+				t.Errorf("paused for %v, want at least %v", wait, tt.minWait)
+			}
+
+			// 暂停期间的请求不发送，直到ctx取消
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err := getStatus(ctx, c); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("paused request error = %v, want deadline exceeded", err)
+			}
+			if hits := srv.Hits("/status/"); hits != 1 {
+				t.Errorf("hits = %d, want 1", hits)
+			}
+		})
+	}
+}
+
+func TestErrorLimitNoPauseAboveThreshold(t *testing.T) {
+	srv, c := newLimitClient(t)
+	srv.SetErrorLimit(21, 30, true)
+
+	for range 2 {
+		if status, err := getStatus(context.Background(), c); err != nil || status != http.StatusOK {
+			t.Fatalf("request = %d, %v", status, err)
+		}
+	}
+	state := c.ErrorLimitState()
+	if state.Remain != 21 || !state.PausedUntil.IsZero() {
+		t.Errorf("state = %+v, want remain 21 without pause", state)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		times      int
+		wantStatus int
+		wantHits   int
+	}{
+		{"502重试", http.StatusBadGateway, 1, http.StatusOK, 2},
+		{"503重试", http.StatusServiceUnavailable, 2, http.StatusOK, 3},
+		{"504重试", http.StatusGatewayTimeout, 1, http.StatusOK, 2},
+		{"超过重试次数", http.StatusServiceUnavailable, 10, http.StatusServiceUnavailable, 4},
+		{"404不重试", http.StatusNotFound, 1, http.StatusNotFound, 1},
+		{"420不重试", 420, 1, 420, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv, c := newLimitClient(t)
+			srv.SetErrorLimit(100, 60, false)
+			srv.FailNext("/status/", tt.status, tt.times)
+
+			status, err := getStatus(context.Background(), c)
+			if err != nil || status != tt.wantStatus {
+				t.Errorf("status = %d, %v, want %d", status, err, tt.wantStatus)
+			}
+			if hits := srv.Hits("/status/"); hits != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryPostBody(t *testing.T) {
+	srv, c := newLimitClient(t)
+	srv.AddNames(esitest.Name{ID: 2112000001, Name: "Esitest Hunter", Category: "character"})
+	srv.FailNext("/universe/names/", http.StatusBadGateway, 1)
+
+	// 重试时重新发送请求体
+	resp, err := c.Post(context.Background(), "/universe/names/", "application/json", []byte(`[2112000001]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if hits := srv.Hits("/universe/names/"); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
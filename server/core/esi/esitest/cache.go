@@ -0,0 +1,107 @@
+package esitest
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryItem 内存缓存项，expires为零值时不过期
+type memoryItem[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// MemoryCache 进程内缓存，代替测试中的Redis缓存，过期项在读取时删除
+type MemoryCache[T any] struct {
+	DefaultExpiration time.Duration
+
+	mu    sync.Mutex
+	items map[string]memoryItem[T]
+}
+
+// NewMemoryCache 创建进程内缓存，可用于esi.Cache和名称缓存
+func NewMemoryCache[T any](defaultExpiration time.Duration) *MemoryCache[T] {
+	return &MemoryCache[T]{
+		DefaultExpiration: defaultExpiration,
+		items:             make(map[string]memoryItem[T]),
+	}
+}
+
+// Set 设置缓存，d不大于0时不过期
+func (m *MemoryCache[T]) Set(k string, v T, d time.Duration) {
+	item := memoryItem[T]{value: v}
+	if d > 0 {
+		item.expires = time.Now().Add(d)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[k] = item
+}
+
+// Get 获取缓存
+func (m *MemoryCache[T]) Get(k string) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[k]
+	if ok && !item.expires.IsZero() && time.Now().After(item.expires) {
+		delete(m.items, k)
+		ok = false
+	}
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return item.value, true
+}
+
+// SetDefault 使用默认过期时间设置缓存
+func (m *MemoryCache[T]) SetDefault(k string, v T) {
+	m.Set(k, v, m.DefaultExpiration)
+}
+
+// Delete 删除缓存
+func (m *MemoryCache[T]) Delete(k string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, k)
+}
+
+// SetKeepExpiration 设置值并保留原过期时间，不存在时使用默认过期时间
+func (m *MemoryCache[T]) SetKeepExpiration(k string, v T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[k]
+	if !ok && m.DefaultExpiration > 0 {
+		item.expires = time.Now().Add(m.DefaultExpiration)
+	}
+	item.value = v
+	m.items[k] = item
+}
+
+// ItemCount 缓存项数量，包括尚未清理的过期项
+func (m *MemoryCache[T]) ItemCount() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.items)), nil
+}
+
+// Flush 清空缓存
+func (m *MemoryCache[T]) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = make(map[string]memoryItem[T])
+}
+
+// Values 返回未过期的缓存值，用于检查写入了哪些缓存
+func (m *MemoryCache[T]) Values() []T {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([]T, 0, len(m.items))
+	now := time.Now()
+	for _, item := range m.items {
+		if item.expires.IsZero() || now.Before(item.expires) {
+			values = append(values, item.value)
+		}
+	}
+	return values
+}
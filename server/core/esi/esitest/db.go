@@ -0,0 +1,61 @@
+package esitest
+
+import (
+	"eve-corp-manager/global"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dbSeq 内存数据库序号，保证每个测试使用独立的数据库
+var dbSeq atomic.Int64
+
+// OpenDB 打开内存sqlite数据库并迁移models，测试结束时关闭
+func OpenDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:esitest_%d?mode=memory&cache=shared", dbSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// UseDB 使用内存数据库作为global.Db并关闭日志输出，测试结束时恢复
+func UseDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db := OpenDB(t, models...)
+	oldDb, oldLogger := global.Db, global.Logger
+	global.Db, global.Logger = db, zap.NewNop().Sugar()
+	t.Cleanup(func() {
+		global.Db, global.Logger = oldDb, oldLogger
+	})
+	return db
+}
+
+// UseSdeDB 使用内存数据库作为global.SdeDb，测试结束时恢复
+func UseSdeDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db := OpenDB(t, models...)
+	oldSdeDb := global.SdeDb
+	global.SdeDb = db
+	t.Cleanup(func() {
+		global.SdeDb = oldSdeDb
+	})
+	return db
+}
@@ -0,0 +1,108 @@
+package esitest
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Fixtures 内置的测试数据
+//
+//	killmails/<killmail_id>_<hash>.json  ESI击毁邮件原始JSON
+//	names.json                           /universe/names/ 可解析的名称
+//	prices.json                          Janice估价使用的物品单价
+//
+//go:embed fixtures
+var Fixtures embed.FS
+
+// FixtureKillmailID 内置击毁邮件的ID和hash
+const (
+	FixtureKillmailID   = 123456789
+	FixtureKillmailHash = "0a1b2c3d4e5f60718293a4b5c6d7e8f901234567"
+)
+
+// NewServerWithFixtures 启动模拟服务并加载内置测试数据
+func NewServerWithFixtures() *Server {
+	s := NewServer()
+	if err := s.LoadFixtures(Fixtures, "fixtures"); err != nil {
+		s.Close()
+		panic(fmt.Sprintf("esitest: 加载内置测试数据失败: %v", err))
+	}
+	return s
+}
+
+// LoadFixtures 从fsys的dir目录加载测试数据，目录结构与Fixtures相同，缺少的文件会被忽略
+func (s *Server) LoadFixtures(fsys fs.FS, dir string) error {
+	killmails, err := fs.Glob(fsys, path.Join(dir, "killmails", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range killmails {
+		killmailID, hash, err := parseKillmailFileName(path.Base(file))
+		if err != nil {
+			return err
+		}
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(body) {
+			return fmt.Errorf("无效的JSON: %s", file)
+		}
+		s.AddKillmail(killmailID, hash, body)
+	}
+
+	var names []Name
+	if err := readJSON(fsys, path.Join(dir, "names.json"), &names); err != nil {
+		return err
+	}
+	s.AddNames(names...)
+
+	var prices map[string]float64
+	if err := readJSON(fsys, path.Join(dir, "prices.json"), &prices); err != nil {
+		return err
+	}
+	for name, price := range prices {
+		s.SetPrice(name, price)
+	}
+
+	return nil
+}
+
+// ReadFixture 读取内置测试数据的原始内容，name相对于fixtures目录
+func ReadFixture(name string) ([]byte, error) {
+	return Fixtures.ReadFile(path.Join("fixtures", name))
+}
+
+// parseKillmailFileName 解析 <killmail_id>_<hash>.json
+func parseKillmailFileName(name string) (int, string, error) {
+	idStr, hash, ok := strings.Cut(strings.TrimSuffix(name, ".json"), "_")
+	if !ok || hash == "" {
+		return 0, "", fmt.Errorf("击毁邮件文件名应为<killmail_id>_<hash>.json: %s", name)
+	}
+	killmailID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return 0, "", fmt.Errorf("击毁邮件文件名应为<killmail_id>_<hash>.json: %s", name)
+	}
+	return killmailID, hash, nil
+}
+
+// readJSON 读取并解析JSON文件，文件不存在时忽略
+func readJSON(fsys fs.FS, file string, v interface{}) error {
+	body, err := fs.ReadFile(fsys, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("解析%s失败: %w", file, err)
+	}
+	return nil
+}
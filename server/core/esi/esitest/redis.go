@@ -0,0 +1,335 @@
+package esitest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ScriptFunc 代替Lua脚本的Go实现，执行期间持有锁，可直接读写data
+type ScriptFunc func(data map[string]string, keys, args []string) interface{}
+
+// Redis 模拟Redis服务，只支持令牌缓存和分布式锁用到的GET/SET/DEL和脚本命令
+// 脚本无法执行Lua，需要通过HandleScript注册对应的Go实现
+type Redis struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	data    map[string]string
+	expires map[string]time.Time
+	scripts map[string]ScriptFunc // 脚本SHA1 -> 实现
+	conns   map[net.Conn]bool
+}
+
+// NewRedis 启动监听本地随机端口的模拟Redis服务
+func NewRedis() *Redis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("esitest: 启动Redis失败: %v", err))
+	}
+	r := &Redis{
+		listener: listener,
+		data:     make(map[string]string),
+		expires:  make(map[string]time.Time),
+		scripts:  make(map[string]ScriptFunc),
+		conns:    make(map[net.Conn]bool),
+	}
+	r.wg.Add(1)
+	go r.serve()
+	return r
+}
+
+// Addr 返回监听地址
+func (r *Redis) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Client 创建连接到模拟服务的客户端
+func (r *Redis) Client() *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: r.Addr(), Protocol: 2})
+}
+
+// Close 关闭监听和所有连接
+func (r *Redis) Close() {
+	r.listener.Close()
+	r.mu.Lock()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// HandleScript 注册脚本的Go实现，hash为redis.Script.Hash()
+func (r *Redis) HandleScript(hash string, fn ScriptFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[hash] = fn
+}
+
+// Get 读取键值
+func (r *Redis) Get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+	value, ok := r.data[key]
+	return value, ok
+}
+
+// Set 写入键值，ttl不大于0时不过期
+func (r *Redis) Set(key, value string, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(key, value, ttl)
+}
+
+// TTL 返回键的剩余过期时间，不存在或不过期时返回0
+func (r *Redis) TTL(key string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+	if expires, ok := r.expires[key]; ok {
+		return time.Until(expires)
+	}
+	return 0
+}
+
+func (r *Redis) set(key, value string, ttl time.Duration) {
+	r.data[key] = value
+	if ttl > 0 {
+		r.expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(r.expires, key)
+	}
+}
+
+// expire 删除已过期和已被脚本删除的键，调用时须持有锁
+func (r *Redis) expire() {
+	now := time.Now()
+	for key, expires := range r.expires {
+		if _, ok := r.data[key]; !ok || now.After(expires) {
+			delete(r.data, key)
+			delete(r.expires, key)
+		}
+	}
+}
+
+func (r *Redis) serve() {
+	defer r.wg.Done()
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.conns[conn] = true
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.handle(conn)
+			r.mu.Lock()
+			delete(r.conns, conn)
+			r.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// handle 按RESP2协议逐条读取命令并回复，直到连接关闭
+func (r *Redis) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		writeReply(writer, r.exec(args))
+		// 管道中的命令一起回复
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// redisError 错误回复
+type redisError string
+
+// redisStatus 状态回复，如OK
+type redisStatus string
+
+func (r *Redis) exec(args []string) interface{} {
+	if len(args) == 0 {
+		return redisError("ERR empty command")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return redisStatus("PONG")
+	case "CLIENT", "SELECT":
+		return redisStatus("OK")
+	case "GET":
+		if len(args) != 2 {
+			return redisError("ERR wrong number of arguments for 'get' command")
+		}
+		if value, ok := r.data[args[1]]; ok {
+			return value
+		}
+		return nil
+	case "SET":
+		return r.execSet(args[1:])
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := r.data[key]; ok {
+				delete(r.data, key)
+				delete(r.expires, key)
+				deleted++
+			}
+		}
+		return deleted
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 {
+			return redisError("ERR wrong number of arguments for 'eval' command")
+		}
+		hash := args[1]
+		if strings.ToUpper(args[0]) == "EVAL" {
+			sum := sha1.Sum([]byte(args[1]))
+			hash = hex.EncodeToString(sum[:])
+		}
+		fn, ok := r.scripts[hash]
+		if !ok {
+			return redisError("NOSCRIPT No matching script")
+		}
+		numKeys, err := strconv.Atoi(args[2])
+		if err != nil || numKeys < 0 || numKeys > len(args)-3 {
+			return redisError("ERR invalid number of keys")
+		}
+		return fn(r.data, args[3:3+numKeys], args[3+numKeys:])
+	default:
+		return redisError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+// execSet 处理 SET key value [EX seconds|PX milliseconds] [NX|XX]
+func (r *Redis) execSet(args []string) interface{} {
+	if len(args) < 2 {
+		return redisError("ERR wrong number of arguments for 'set' command")
+	}
+	key, value := args[0], args[1]
+	var (
+		ttl    time.Duration
+		nx, xx bool
+	)
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return redisError("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return redisError("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return redisError("ERR syntax error")
+		}
+	}
+
+	_, exists := r.data[key]
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+	r.set(key, value, ttl)
+	return redisStatus("OK")
+}
+
+// readCommand 读取一条以多行批量字符串数组发送的命令
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("esitest: 不支持内联命令")
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for range n {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("esitest: 无效的批量字符串 %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func writeReply(writer *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		writer.WriteString("$-1\r\n")
+	case redisStatus:
+		fmt.Fprintf(writer, "+%s\r\n", v)
+	case redisError:
+		fmt.Fprintf(writer, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(writer, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(v), v)
+	default:
+		fmt.Fprintf(writer, "-ERR unsupported reply %T\r\n", v)
+	}
+}
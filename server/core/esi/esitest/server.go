@@ -0,0 +1,529 @@
+// Package esitest 提供进程内的模拟ESI服务，用于离线测试击毁邮件解析、分页和补损逻辑
+package esitest
+
+import (
+	"encoding/json"
+	"eve-corp-manager/core/esi"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	esiPrefix    = "/latest"
+	janicePrefix = "/janice"
+	zkbPrefix    = "/zkb"
+	RedisQPath   = "/redisq/listen.php" // RedisQ监听路径，可用于FailNext和Hits
+
+	redisqEmptyWait = 100 * time.Millisecond // 队列为空时的等待时间，代替RedisQ的ttw
+	killmailExpires = 30 * 24 * time.Hour    // 击毁邮件响应的缓存时间
+
+	defaultErrorLimitRemain = 100
+	defaultErrorLimitReset  = 60
+)
+
+// Name /universe/names/ 返回的名称
+type Name = esi.Name
+
+// failure 注入的失败响应
+type failure struct {
+	statusCode int
+	times      int
+}
+
+// killmailKey 击毁邮件索引
+type killmailKey struct {
+	id   int
+	hash string
+}
+
+// Server 模拟ESI服务，同时提供Janice、zKillboard和SSO令牌接口
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	routes     map[string][][]byte // GET路径 -> 分页数据，只有一页时不返回X-Pages
+	paged      map[string]bool
+	headers    map[string]http.Header // GET路径 -> 额外的响应头
+	killmails  map[killmailKey][]byte
+	names      map[int]Name
+	prices     map[string]float64
+	failures   map[string]*failure
+	hits       map[string]int
+	errRemain  int
+	errReset   int
+	errCounted bool
+	redisq     []esi.RedisQPackage
+	queues     map[string]int // RedisQ队列ID -> 已读取的消息数
+
+	sso *ssoServer
+	ws  *websocketServer
+}
+
+// NewServer 启动模拟服务，测试结束后调用Close
+func NewServer() *Server {
+	s := &Server{
+		routes:     make(map[string][][]byte),
+		paged:      make(map[string]bool),
+		headers:    make(map[string]http.Header),
+		killmails:  make(map[killmailKey][]byte),
+		names:      make(map[int]Name),
+		prices:     make(map[string]float64),
+		failures:   make(map[string]*failure),
+		hits:       make(map[string]int),
+		queues:     make(map[string]int),
+		errRemain:  defaultErrorLimitRemain,
+		errReset:   defaultErrorLimitReset,
+		errCounted: true,
+	}
+	s.sso = newSSOServer()
+	s.ws = newWebsocketServer()
+	s.SetJSON("/status/", map[string]interface{}{
+		"players":        20000,
+		"server_version": "esitest",
+		"start_time":     "2025-01-01T11:00:00Z",
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(esiPrefix+"/", s.handleESI)
+	mux.HandleFunc(janicePrefix+"/appraisal", s.handleAppraisal)
+	mux.HandleFunc(zkbPrefix+"/api/killID/", s.handleZkb)
+	mux.HandleFunc(RedisQPath, s.handleRedisQ)
+	s.sso.register(mux)
+	s.ws.register(mux)
+
+	s.Server = httptest.NewServer(mux)
+	s.sso.issuer = s.URL
+	return s
+}
+
+// ESIURL ESI接口地址，对应配置Esi.BaseUrl
+func (s *Server) ESIURL() string {
+	return s.URL + esiPrefix
+}
+
+// JaniceURL Janice接口地址，对应配置Janice.BaseUrl
+func (s *Server) JaniceURL() string {
+	return s.URL + janicePrefix
+}
+
+// ZkillboardURL zKillboard接口地址，对应配置Zkillboard.BaseUrl
+func (s *Server) ZkillboardURL() string {
+	return s.URL + zkbPrefix
+}
+
+// RedisQURL RedisQ监听地址，对应配置Zkillboard.RedisqUrl
+func (s *Server) RedisQURL() string {
+	return s.URL + RedisQPath
+}
+
+// Install 将全局ESI和Janice客户端及zKillboard地址指向模拟服务，返回恢复函数
+func (s *Server) Install() (restore func()) {
+	oldEsi, oldJanice, oldNames := esi.EsiClient, esi.JaniceClient, esi.Names
+
+	esi.EsiClient = esi.NewClient("", "", "esitest", "esi", s.ESIURL())
+	esi.JaniceClient = esi.NewClient("", "", "esitest", "janice", s.JaniceURL())
+	esi.Names = esi.NewNameResolver(nil, nil, nil)
+	esi.SetZkillboardURL(s.ZkillboardURL())
+	esi.SetRedisQURL(s.RedisQURL())
+	esi.SetZkbWebsocketURL(s.ZkbWebsocketURL())
+
+	return func() {
+		esi.EsiClient, esi.JaniceClient, esi.Names = oldEsi, oldJanice, oldNames
+		esi.SetZkillboardURL("")
+		esi.SetRedisQURL("")
+		esi.SetZkbWebsocketURL("")
+	}
+}
+
+// SetJSON 设置GET接口的返回数据，path不含/latest前缀，如 /characters/123/
+func (s *Server) SetJSON(path string, v interface{}) {
+	body := mustMarshal(v)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = [][]byte{body}
+	delete(s.paged, path)
+}
+
+// SetPages 设置分页接口的数据，每个参数为一页，响应携带X-Pages
+func (s *Server) SetPages(path string, pages ...interface{}) {
+	data := make([][]byte, 0, len(pages))
+	for _, page := range pages {
+		data = append(data, mustMarshal(page))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = data
+	s.paged[path] = true
+}
+
+// SetHeader 设置GET接口的响应头，覆盖自动生成的同名响应头，如用无效的X-Pages测试客户端
+// 设置ETag后请求携带相同的If-None-Match时返回304
+func (s *Server) SetHeader(path, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headers[path] == nil {
+		s.headers[path] = make(http.Header)
+	}
+	s.headers[path].Set(key, value)
+}
+
+// AddKillmail 添加击毁邮件，body为ESI返回的原始JSON
+func (s *Server) AddKillmail(killmailID int, hash string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.killmails[killmailKey{id: killmailID, hash: hash}] = body
+}
+
+// PushRedisQ 向RedisQ推送击毁邮件，withKillmail为true时与旧版RedisQ一样携带完整击毁邮件
+// 每个queueID独立记录读取位置，新的queueID从第一条消息开始读取
+func (s *Server) PushRedisQ(killmailID int, hash string, withKillmail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pkg := esi.RedisQPackage{KillID: killmailID}
+	pkg.Zkb.Hash = hash
+	pkg.Zkb.Href = fmt.Sprintf("%s%s/killmails/%d/%s/", s.URL, esiPrefix, killmailID, hash)
+	if withKillmail {
+		if body, ok := s.killmails[killmailKey{id: killmailID, hash: hash}]; ok {
+			var km esi.Killmail
+			if err := json.Unmarshal(body, &km); err == nil {
+				pkg.Killmail = &km
+			}
+		}
+	}
+	s.redisq = append(s.redisq, pkg)
+}
+
+// AddNames 添加/universe/names/和/universe/ids/可解析的名称
+func (s *Server) AddNames(names ...Name) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		s.names[name.ID] = name
+	}
+}
+
+// SetPrice 设置Janice估价中物品的单价
+func (s *Server) SetPrice(itemName string, price float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[itemName] = price
+}
+
+// SetErrorLimit 设置返回的X-ESI-Error-Limit-Remain/Reset，counted为true时错误响应会扣减剩余额度
+func (s *Server) SetErrorLimit(remain, reset int, counted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errRemain, s.errReset, s.errCounted = remain, reset, counted
+}
+
+// FailNext 接下来times次请求path时返回statusCode，path不含/latest前缀
+func (s *Server) FailNext(path string, statusCode, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = &failure{statusCode: statusCode, times: times}
+}
+
+// Hits 返回path被请求的次数，path不含/latest前缀
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// handleESI 处理ESI请求
+func (s *Server) handleESI(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, esiPrefix)
+
+	s.mu.Lock()
+	s.hits[path]++
+	if f, ok := s.failures[path]; ok && f.times > 0 {
+		f.times--
+		s.mu.Unlock()
+		s.writeError(w, f.statusCode, "esitest injected failure")
+		return
+	}
+	s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && path == "/universe/names/":
+		s.handleNames(w, r)
+	case r.Method == http.MethodPost && path == "/universe/ids/":
+		s.handleIds(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/killmails/"):
+		s.handleKillmail(w, path)
+	case r.Method == http.MethodGet:
+		s.handleRoute(w, r, path)
+	default:
+		s.writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleRoute 返回SetJSON/SetPages设置的数据
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request, path string) {
+	s.mu.Lock()
+	pages, ok := s.routes[path]
+	paged := s.paged[path]
+	headers := s.headers[path].Clone()
+	s.mu.Unlock()
+
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			s.writeError(w, http.StatusBadRequest, "Invalid page")
+			return
+		}
+	}
+	if page > len(pages) {
+		s.writeError(w, http.StatusNotFound, "Requested page does not exist!")
+		return
+	}
+
+	if paged {
+		w.Header().Set("X-Pages", strconv.Itoa(len(pages)))
+	}
+	for key, values := range headers {
+		w.Header()[key] = values
+	}
+	// 与ESI一样，If-None-Match与SetHeader设置的ETag相同时返回304
+	if etag := headers.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeBody(w, http.StatusOK, pages[page-1])
+}
+
+// handleKillmail 返回击毁邮件，路径格式 /killmails/{id}/{hash}/
+func (s *Server) handleKillmail(w http.ResponseWriter, path string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 {
+		s.writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		s.writeError(w, http.StatusUnprocessableEntity, "Invalid killmail_id and/or killmail_hash")
+		return
+	}
+
+	s.mu.Lock()
+	body, ok := s.killmails[killmailKey{id: id, hash: parts[2]}]
+	s.mu.Unlock()
+
+	if !ok {
+		s.writeError(w, http.StatusUnprocessableEntity, "Invalid killmail_id and/or killmail_hash")
+		return
+	}
+	// 击毁邮件不会变化，与ESI一样返回较长的缓存时间
+	w.Header().Set("Expires", time.Now().Add(killmailExpires).UTC().Format(http.TimeFormat))
+	s.writeBody(w, http.StatusOK, body)
+}
+
+// handleNames 与ESI一致，任意ID无效时整个请求返回404
+func (s *Server) handleNames(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil || len(ids) == 0 || len(ids) > 1000 {
+		s.writeError(w, http.StatusBadRequest, "Invalid ids")
+		return
+	}
+
+	s.mu.Lock()
+	result := make([]Name, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		name, ok := s.names[id]
+		if !ok {
+			s.mu.Unlock()
+			s.writeError(w, http.StatusNotFound, "Ensure all IDs are valid before resolving.")
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, name)
+		}
+	}
+	s.mu.Unlock()
+
+	s.writeBody(w, http.StatusOK, mustMarshal(result))
+}
+
+// handleIds 名称反查ID，按类别分组返回，未找到的名称忽略
+func (s *Server) handleIds(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil || len(names) == 0 || len(names) > 500 {
+		s.writeError(w, http.StatusBadRequest, "Invalid names")
+		return
+	}
+
+	type idName struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	result := make(map[string][]idName)
+
+	s.mu.Lock()
+	for _, name := range names {
+		for _, item := range s.names {
+			if strings.EqualFold(item.Name, name) {
+				category := item.Category + "s"
+				if item.Category == "inventory_type" {
+					category = "inventory_types"
+				}
+				result[category] = append(result[category], idName{ID: item.ID, Name: item.Name})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	s.writeBody(w, http.StatusOK, mustMarshal(result))
+}
+
+// handleAppraisal 模拟Janice估价，按SetPrice设置的单价乘以数量求和
+func (s *Server) handleAppraisal(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PriceData struct {
+			RawTextarea string `json:"raw_textarea"`
+		} `json:"pricedata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total := 0.0
+	s.mu.Lock()
+	for _, line := range strings.Split(req.PriceData.RawTextarea, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, quantity := line, 1.0
+		if i := strings.LastIndex(line, "\t"); i >= 0 {
+			name = line[:i]
+			if q, err := strconv.ParseFloat(line[i+1:], 64); err == nil {
+				quantity = q
+			}
+		}
+		total += s.prices[name] * quantity
+	}
+	s.mu.Unlock()
+
+	body := mustMarshal(map[string]interface{}{
+		"appraisal": map[string]interface{}{
+			"prices": map[string]interface{}{
+				"sell": map[string]interface{}{"min": total},
+			},
+		},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// handleZkb 模拟zKillboard的 /api/killID/{id}/ 接口
+func (s *Server) handleZkb(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, zkbPrefix+"/api/killID/"), "/")
+	id, _ := strconv.Atoi(idStr)
+
+	type zkbEntry struct {
+		KillmailID int `json:"killmail_id"`
+		Zkb        struct {
+			Hash string `json:"hash"`
+		} `json:"zkb"`
+	}
+	result := []zkbEntry{}
+
+	s.mu.Lock()
+	for key := range s.killmails {
+		if key.id == id {
+			entry := zkbEntry{KillmailID: id}
+			entry.Zkb.Hash = key.hash
+			result = append(result, entry)
+		}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(mustMarshal(result))
+}
+
+// handleRedisQ 模拟RedisQ，队列为空时短暂等待后返回{"package":null}，FailNext使用RedisQPath注入失败
+func (s *Server) handleRedisQ(w http.ResponseWriter, r *http.Request) {
+	queueID := r.URL.Query().Get("queueID")
+	if queueID == "" {
+		http.Error(w, "queueID is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.hits[RedisQPath]++
+	if f, ok := s.failures[RedisQPath]; ok && f.times > 0 {
+		f.times--
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "esitest injected failure", f.statusCode)
+		return
+	}
+	var pkg *esi.RedisQPackage
+	if next := s.queues[queueID]; next < len(s.redisq) {
+		pkg = &s.redisq[next]
+		s.queues[queueID] = next + 1
+	}
+	s.mu.Unlock()
+
+	if pkg == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(redisqEmptyWait):
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(mustMarshal(map[string]interface{}{"package": pkg}))
+}
+
+// writeBody 写入响应并附带错误额度头
+func (s *Server) writeBody(w http.ResponseWriter, statusCode int, body []byte) {
+	s.mu.Lock()
+	if statusCode >= 400 && s.errCounted && s.errRemain > 0 {
+		s.errRemain--
+	}
+	remain, reset := s.errRemain, s.errReset
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-ESI-Error-Limit-Remain", strconv.Itoa(remain))
+	w.Header().Set("X-ESI-Error-Limit-Reset", strconv.Itoa(reset))
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// writeError 写入ESI格式的错误
+func (s *Server) writeError(w http.ResponseWriter, statusCode int, message string) {
+	s.writeBody(w, statusCode, mustMarshal(map[string]string{"error": message}))
+}
+
+func mustMarshal(v interface{}) []byte {
+	if raw, ok := v.([]byte); ok {
+		return raw
+	}
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("esitest: 序列化失败: %v", err))
+	}
+	return body
+}
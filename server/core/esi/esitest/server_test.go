@@ -0,0 +1,160 @@
+package esitest
+
+import (
+	"context"
+	"encoding/json"
+	"eve-corp-manager/core/esi"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// get 请求模拟服务并返回状态码、剩余错误额度和响应内容
+func get(t *testing.T, url string) (int, int, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remain, _ := strconv.Atoi(resp.Header.Get("X-ESI-Error-Limit-Remain"))
+	return resp.StatusCode, remain, body
+}
+
+func TestFixtures(t *testing.T) {
+	srv := NewServerWithFixtures()
+	defer srv.Close()
+
+	killmailURL := fmt.Sprintf("%s/killmails/%d/%s/", srv.ESIURL(), FixtureKillmailID, FixtureKillmailHash)
+	status, _, body := get(t, killmailURL)
+	if status != http.StatusOK || !strings.Contains(string(body), fmt.Sprintf(`"killmail_id": %d`, FixtureKillmailID)) {
+		t.Fatalf("killmail = %d %s", status, body)
+	}
+	if status, _, _ := get(t, srv.ESIURL()+"/killmails/123456789/ffff/"); status != http.StatusUnprocessableEntity {
+		t.Errorf("wrong hash status = %d, want 422", status)
+	}
+
+	resp, err := http.Post(srv.ESIURL()+"/universe/names/", "application/json", strings.NewReader(`[2112000001,30002187]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []Name
+	json.NewDecoder(resp.Body).Decode(&names)
+	resp.Body.Close()
+	if len(names) != 2 || names[0].Name != "Esitest Hunter" || names[1].Category != "solar_system" {
+		t.Errorf("names = %+v", names)
+	}
+
+	appraisal := strings.NewReader(`{"pricedata":{"raw_textarea":"Rifter\t2\nTritanium\t1000"}}`)
+	resp, err = http.Post(srv.JaniceURL()+"/appraisal", "application/json", appraisal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Appraisal struct {
+			Prices struct {
+				Sell struct {
+					Min float64 `json:"min"`
+				} `json:"sell"`
+			} `json:"prices"`
+		} `json:"appraisal"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if got := result.Appraisal.Prices.Sell.Min; got != 2*450000+1000*4.5 {
+		t.Errorf("appraisal = %v", got)
+	}
+}
+
+func TestFailNextAndErrorLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		counted    bool
+		wantRemain []int // 依次三次请求后的剩余额度
+	}{
+		{"错误扣减额度", true, []int{9, 8, 8}},
+		{"错误不扣减额度", false, []int{10, 10, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer()
+			defer srv.Close()
+			srv.SetErrorLimit(10, 30, tt.counted)
+			srv.FailNext("/status/", http.StatusBadGateway, 2)
+
+			wantStatus := []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}
+			for i := range wantStatus {
+				status, remain, _ := get(t, srv.ESIURL()+"/status/")
+				if status != wantStatus[i] || remain != tt.wantRemain[i] {
+					t.Errorf("request %d = %d remain %d, want %d remain %d", i, status, remain, wantStatus[i], tt.wantRemain[i])
+				}
+			}
+			if hits := srv.Hits("/status/"); hits != 3 {
+				t.Errorf("hits = %d, want 3", hits)
+			}
+		})
+	}
+}
+
+func TestSetPages(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetPages("/markets/prices/", []int{1}, []int{2})
+
+	for _, tt := range []struct {
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"", http.StatusOK, "[1]"},
+		{"?page=2", http.StatusOK, "[2]"},
+		{"?page=3", http.StatusNotFound, ""},
+		{"?page=x", http.StatusBadRequest, ""},
+	} {
+		status, _, body := get(t, srv.ESIURL()+"/markets/prices/"+tt.query)
+		if status != tt.wantStatus || (tt.wantBody != "" && string(body) != tt.wantBody) {
+			t.Errorf("page %q = %d %s", tt.query, status, body)
+		}
+	}
+}
+
+func TestRedisQ(t *testing.T) {
+	srv := NewServerWithFixtures()
+	defer srv.Close()
+	defer srv.Install()()
+	ctx := context.Background()
+
+	srv.PushRedisQ(FixtureKillmailID, FixtureKillmailHash, false)
+	srv.PushRedisQ(FixtureKillmailID, FixtureKillmailHash, true)
+
+	// 每个队列独立读取，读完后返回空包
+	for _, queueID := range []string{"a", "b"} {
+		first, err := esi.ListenRedisQ(ctx, queueID, 1)
+		if err != nil || first == nil || first.Killmail != nil || first.Zkb.Hash != FixtureKillmailHash {
+			t.Fatalf("queue %s first = %+v, %v", queueID, first, err)
+		}
+		second, err := esi.ListenRedisQ(ctx, queueID, 1)
+		if err != nil || second == nil || second.Killmail == nil || second.Killmail.KillmailID != FixtureKillmailID {
+			t.Fatalf("queue %s second = %+v, %v", queueID, second, err)
+		}
+		if empty, err := esi.ListenRedisQ(ctx, queueID, 1); err != nil || empty != nil {
+			t.Errorf("queue %s empty = %+v, %v", queueID, empty, err)
+		}
+	}
+
+	srv.FailNext(RedisQPath, http.StatusTooManyRequests, 1)
+	if _, err := esi.ListenRedisQ(ctx, "a", 1); err == nil {
+		t.Error("injected failure should return an error")
+	}
+	if hits := srv.Hits(RedisQPath); hits != 7 {
+		t.Errorf("hits = %d, want 7", hits)
+	}
+}
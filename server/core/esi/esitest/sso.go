@@ -0,0 +1,234 @@
+package esitest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"eve-corp-manager/core/sso"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ssoTokenPath = "/v2/oauth/token"
+	ssoJwksPath  = "/oauth/jwks"
+	ssoKeyID     = "esitest"
+
+	accessTokenTTL = 20 * time.Minute
+)
+
+// Character SSO授权的角色
+type Character struct {
+	CharacterID uint
+	Name        string
+	Owner       string
+	Scopes      []string
+}
+
+// ssoServer 模拟EVE SSO的令牌和JWKS接口
+type ssoServer struct {
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	issuer  string
+	codes   map[string]Character // 授权码 -> 角色，一次性
+	refresh map[string]Character // refresh token -> 角色
+	rotate  bool
+
+	refreshes int // refresh_token授权请求次数
+}
+
+func newSSOServer() *ssoServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("esitest: 生成RSA密钥失败: %v", err))
+	}
+	return &ssoServer{
+		key:     key,
+		codes:   make(map[string]Character),
+		refresh: make(map[string]Character),
+	}
+}
+
+func (s *ssoServer) register(mux *http.ServeMux) {
+	mux.HandleFunc(ssoTokenPath, s.handleToken)
+	mux.HandleFunc(ssoJwksPath, s.handleJwks)
+}
+
+// SSOOptions 指向模拟服务的SSO客户端配置
+func (s *Server) SSOOptions(clientID string) sso.Options {
+	return sso.Options{
+		ClientID:     clientID,
+		CallbackURL:  s.URL + "/callback",
+		AuthorizeURL: s.URL + "/v2/oauth/authorize",
+		TokenURL:     s.URL + ssoTokenPath,
+		JwksURL:      s.URL + ssoJwksPath,
+		Issuer:       s.URL,
+	}
+}
+
+// AddAuthCode 注册授权码，换取令牌后失效
+func (s *Server) AddAuthCode(code string, character Character) {
+	s.sso.mu.Lock()
+	defer s.sso.mu.Unlock()
+	s.sso.codes[code] = character
+}
+
+// AddRefreshToken 注册可用于刷新的refresh token
+func (s *Server) AddRefreshToken(refreshToken string, character Character) {
+	s.sso.mu.Lock()
+	defer s.sso.mu.Unlock()
+	s.sso.refresh[refreshToken] = character
+}
+
+// RevokeRefreshToken 吊销refresh token，之后刷新返回invalid_grant
+func (s *Server) RevokeRefreshToken(refreshToken string) {
+	s.sso.mu.Lock()
+	defer s.sso.mu.Unlock()
+	delete(s.sso.refresh, refreshToken)
+}
+
+// SetRotateRefreshToken 开启后每次刷新都签发新的refresh token并使旧令牌失效
+func (s *Server) SetRotateRefreshToken(rotate bool) {
+	s.sso.mu.Lock()
+	defer s.sso.mu.Unlock()
+	s.sso.rotate = rotate
+}
+
+// Refreshes 返回refresh_token授权的请求次数，包括失败的请求
+func (s *Server) Refreshes() int {
+	s.sso.mu.Lock()
+	defer s.sso.mu.Unlock()
+	return s.sso.refreshes
+}
+
+// AccessToken 为角色签发访问令牌，aud包含clientID
+func (s *Server) AccessToken(clientID string, character Character) (string, error) {
+	return s.sso.sign(clientID, character)
+}
+
+// handleToken 处理authorization_code和refresh_token两种授权
+func (s *ssoServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeSSOError(w, "invalid_request", err.Error())
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID = user
+	}
+
+	s.mu.Lock()
+	var (
+		character    Character
+		found        bool
+		refreshToken string
+	)
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		character, found = s.codes[code]
+		delete(s.codes, code)
+		if found {
+			refreshToken = randomString()
+			s.refresh[refreshToken] = character
+		}
+	case "refresh_token":
+		s.refreshes++
+		refreshToken = r.PostForm.Get("refresh_token")
+		character, found = s.refresh[refreshToken]
+		if found && s.rotate {
+			delete(s.refresh, refreshToken)
+			refreshToken = randomString()
+			s.refresh[refreshToken] = character
+		}
+	default:
+		s.mu.Unlock()
+		writeSSOError(w, "unsupported_grant_type", "")
+		return
+	}
+	s.mu.Unlock()
+
+	if !found {
+		writeSSOError(w, "invalid_grant", "Invalid refresh token or authorization code")
+		return
+	}
+
+	accessToken, err := s.sign(clientID, character)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sso.Token{
+		AccessToken:  accessToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		TokenType:    "Bearer",
+		RefreshToken: refreshToken,
+	})
+}
+
+// handleJwks 返回签名公钥
+func (s *ssoServer) handleJwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": ssoKeyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// sign 签发与EVE SSO格式一致的访问令牌
+func (s *ssoServer) sign(clientID string, character Character) (string, error) {
+	now := time.Now()
+	claims := sso.CharacterClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   fmt.Sprintf("CHARACTER:EVE:%d", character.CharacterID),
+			Audience:  jwt.ClaimStrings{clientID, "EVE Online"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		},
+		Name:   character.Name,
+		Owner:  character.Owner,
+		Scopes: character.Scopes,
+		Azp:    clientID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = ssoKeyID
+	return token.SignedString(s.key)
+}
+
+func writeSSOError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("esitest: 生成随机数失败: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
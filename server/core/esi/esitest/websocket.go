@@ -0,0 +1,168 @@
+package esitest
+
+import (
+	"encoding/json"
+	"eve-corp-manager/core/esi"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const zkbWebsocketPath = zkbPrefix + "/websocket/"
+
+// websocketServer 模拟zKillboard websocket，按连接记录订阅的频道
+type websocketServer struct {
+	mu       sync.Mutex
+	upgrader websocket.Upgrader
+	conns    map[*websocketConn]bool
+}
+
+// websocketConn 一个客户端连接
+type websocketConn struct {
+	conn     *websocket.Conn
+	mu       sync.Mutex // 串行写入
+	channels map[string]bool
+}
+
+func newWebsocketServer() *websocketServer {
+	return &websocketServer{conns: make(map[*websocketConn]bool)}
+}
+
+func (s *websocketServer) register(mux *http.ServeMux) {
+	mux.HandleFunc(zkbWebsocketPath, s.handle)
+}
+
+// handle 处理 {"action":"sub"|"unsub","channel":"..."} 订阅消息直到连接关闭
+func (s *websocketServer) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &websocketConn{conn: conn, channels: make(map[string]bool)}
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var msg struct {
+			Action  string `json:"action"`
+			Channel string `json:"channel"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		s.mu.Lock()
+		switch msg.Action {
+		case "sub":
+			c.channels[msg.Channel] = true
+		case "unsub":
+			delete(c.channels, msg.Channel)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// subscribed 是否有连接订阅了channel
+func (s *websocketServer) subscribed(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if c.channels[channel] {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcast 向订阅了任一频道的连接发送消息，返回发送的连接数
+func (s *websocketServer) broadcast(message []byte, channels []string) int {
+	s.mu.Lock()
+	var targets []*websocketConn
+	for c := range s.conns {
+		for _, channel := range channels {
+			if c.channels[channel] {
+				targets = append(targets, c)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	sent := 0
+	for _, c := range targets {
+		c.mu.Lock()
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		err := c.conn.WriteMessage(websocket.TextMessage, message)
+		c.mu.Unlock()
+		if err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// ZkbWebsocketURL zKillboard websocket地址，对应配置Zkillboard.WebsocketUrl
+func (s *Server) ZkbWebsocketURL() string {
+	return "ws" + s.URL[len("http"):] + zkbWebsocketPath
+}
+
+// DropWebsockets 断开所有websocket连接，返回断开的连接数，用于测试重连
+func (s *Server) DropWebsockets() int {
+	s.ws.mu.Lock()
+	defer s.ws.mu.Unlock()
+	for c := range s.ws.conns {
+		c.conn.Close()
+	}
+	return len(s.ws.conns)
+}
+
+// Subscribed 是否有websocket连接订阅了channel，如 esi.ZkbCorporationChannel(98000001)
+func (s *Server) Subscribed(channel string) bool {
+	return s.ws.subscribed(channel)
+}
+
+// PushWebsocket 向订阅了击毁邮件相关公司/联盟频道的websocket连接推送已添加的击毁邮件，返回推送的连接数
+// 与zKillboard一样推送完整击毁邮件并附带zkb字段
+func (s *Server) PushWebsocket(killmailID int, hash string) int {
+	s.mu.Lock()
+	body, ok := s.killmails[killmailKey{id: killmailID, hash: hash}]
+	s.mu.Unlock()
+	if !ok {
+		panic(fmt.Sprintf("esitest: 击毁邮件不存在: %d", killmailID))
+	}
+
+	var km esi.Killmail
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(body, &km); err != nil {
+		panic(fmt.Sprintf("esitest: 解析击毁邮件失败: %v", err))
+	}
+	if err := json.Unmarshal(body, &message); err != nil {
+		panic(fmt.Sprintf("esitest: 解析击毁邮件失败: %v", err))
+	}
+	message["zkb"] = mustMarshal(map[string]interface{}{
+		"locationID": km.SolarSystemID,
+		"hash":       hash,
+		"href":       fmt.Sprintf("%s%s/killmails/%d/%s/", s.URL, esiPrefix, killmailID, hash),
+	})
+
+	channels := []string{
+		esi.ZkbCorporationChannel(km.Victim.CorporationID),
+		esi.ZkbAllianceChannel(km.Victim.AllianceID),
+	}
+	for _, attacker := range km.Attackers {
+		channels = append(channels,
+			esi.ZkbCorporationChannel(attacker.CorporationID),
+			esi.ZkbAllianceChannel(attacker.AllianceID))
+	}
+	return s.ws.broadcast(mustMarshal(message), channels)
+}
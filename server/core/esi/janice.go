@@ -1,6 +1,7 @@
 package esi
 
 import (
+	"context"
 	"encoding/json"
 	"eve-corp-manager/global"
 	"fmt"
@@ -8,7 +9,7 @@ import (
 )
 
 // GetAppraisal 从Janice获取物品估价
-func GetAppraisal(queryStr string) (float64, error) {
+func GetAppraisal(ctx context.Context, queryStr string) (float64, error) {
 	if queryStr == "" {
 		return 0, nil
 	}
@@ -32,7 +33,7 @@ func GetAppraisal(queryStr string) (float64, error) {
 	}
 
 	// 发送POST请求
-	resp, err := JaniceClient.Post("/appraisal", "application/json", reqJSON)
+	resp, err := JaniceClient.Post(ctx, "/appraisal", "application/json", reqJSON)
 	if err != nil {
 		return 0, err
 	}
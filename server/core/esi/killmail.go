@@ -1,94 +1,141 @@
 package esi
 
 import (
+	"context"
 	"encoding/json"
-	"eve-corp-manager/global"
 	"fmt"
-	"io"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// GetKillmail 获取击毁邮件详情
-func GetKillmail(killmailID int, killmailHash string) (map[string]interface{}, error) {
-	var result map[string]interface{}
+// defaultZkillboardURL zKillboard官方地址
+const defaultZkillboardURL = "https://zkillboard.com"
 
-	query := url.Values{}
-	query.Set("datasource", "tranquility")
+// zkillboardURL zKillboard接口地址
+var zkillboardURL = defaultZkillboardURL
 
-	path := fmt.Sprintf("/killmails/%d/%s/", killmailID, killmailHash)
-	err := EsiClient.GetJSON(path, query, &result)
-	if err != nil {
-		return nil, err
+// SetZkillboardURL 设置zKillboard接口地址，留空使用官方地址
+func SetZkillboardURL(baseURL string) {
+	if baseURL == "" {
+		baseURL = defaultZkillboardURL
 	}
+	zkillboardURL = strings.TrimRight(baseURL, "/")
+}
 
-	return result, nil
+// Killmail ESI击毁邮件
+type Killmail struct {
+	KillmailID    int                `json:"killmail_id"`
+	KillmailTime  time.Time          `json:"killmail_time"`
+	SolarSystemID int                `json:"solar_system_id"`
+	MoonID        int                `json:"moon_id,omitempty"`
+	WarID         int                `json:"war_id,omitempty"`
+	Victim        KillmailVictim     `json:"victim"`
+	Attackers     []KillmailAttacker `json:"attackers"`
 }
 
-// PostIdsToNames 批量获取ID对应的名称
-func PostIdsToNames(ids []int) (map[string]string, error) {
-	result := make(map[string]string)
-	if len(ids) == 0 {
-		return result, nil
-	}
+// KillmailVictim 受害者信息
+type KillmailVictim struct {
+	AllianceID    int               `json:"alliance_id,omitempty"`
+	CharacterID   int               `json:"character_id,omitempty"`
+	CorporationID int               `json:"corporation_id,omitempty"`
+	FactionID     int               `json:"faction_id,omitempty"`
+	DamageTaken   int               `json:"damage_taken"`
+	ShipTypeID    int               `json:"ship_type_id"`
+	Items         []KillmailItem    `json:"items,omitempty"`
+	Position      *KillmailPosition `json:"position,omitempty"`
+}
 
-	// 过滤掉0值
-	filteredIDs := make([]int, 0)
-	for _, id := range ids {
-		if id != 0 {
-			filteredIDs = append(filteredIDs, id)
+// KillmailAttacker 攻击者信息，NPC没有角色ID
+type KillmailAttacker struct {
+	AllianceID     int     `json:"alliance_id,omitempty"`
+	CharacterID    int     `json:"character_id,omitempty"`
+	CorporationID  int     `json:"corporation_id,omitempty"`
+	FactionID      int     `json:"faction_id,omitempty"`
+	DamageDone     int     `json:"damage_done"`
+	FinalBlow      bool    `json:"final_blow"`
+	SecurityStatus float64 `json:"security_status"`
+	ShipTypeID     int     `json:"ship_type_id,omitempty"`
+	WeaponTypeID   int     `json:"weapon_type_id,omitempty"`
+}
+
+// KillmailItem 受害者物品，容器内的物品在Items中
+type KillmailItem struct {
+	Flag              int            `json:"flag"`
+	ItemTypeID        int            `json:"item_type_id"`
+	QuantityDestroyed int64          `json:"quantity_destroyed,omitempty"`
+	QuantityDropped   int64          `json:"quantity_dropped,omitempty"`
+	Singleton         int            `json:"singleton"`
+	Items             []KillmailItem `json:"items,omitempty"`
+}
+
+// KillmailPosition 击毁位置坐标
+type KillmailPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// FinalBlow 获取最后一击的攻击者，没有时返回nil
+func (k *Killmail) FinalBlow() *KillmailAttacker {
+	for i := range k.Attackers {
+		if k.Attackers[i].FinalBlow {
+			return &k.Attackers[i]
 		}
 	}
+	return nil
+}
 
-	if len(filteredIDs) == 0 {
-		return result, nil
-	}
+// Dropped 物品是否掉落
+func (i *KillmailItem) Dropped() bool {
+	return i.QuantityDropped > 0
+}
 
-	// 将整数ID转换为字符串
-	idsJSON, err := json.Marshal(filteredIDs)
-	if err != nil {
-		return nil, err
-	}
+// Quantity 物品数量，包括掉落和摧毁
+func (i *KillmailItem) Quantity() int64 {
+	return i.QuantityDropped + i.QuantityDestroyed
+}
+
+// GetKillmail 获取击毁邮件详情
+func GetKillmail(ctx context.Context, killmailID int, killmailHash string) (*Killmail, error) {
+	var result Killmail
 
-	// 发送POST请求
-	resp, err := EsiClient.Post("/universe/names/", "application/json", idsJSON)
+	query := url.Values{}
+	query.Set("datasource", EsiClient.Datasource())
+
+	path := fmt.Sprintf("/killmails/%d/%s/", killmailID, killmailHash)
+	err := EsiClient.GetJSON(ctx, path, query, &result)
 	if err != nil {
 		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			global.Logger.Errorf("Failed to close response body: %v", err)
-		}
-	}(resp.Body)
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("ESI API错误 (状态码: %d)", resp.StatusCode)
+	if result.KillmailID != killmailID || result.KillmailTime.IsZero() || result.Victim.ShipTypeID == 0 {
+		return nil, fmt.Errorf("无效的击毁邮件数据: %d", killmailID)
 	}
 
-	// 解析响应
-	var namesData []struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-	}
+	return &result, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&namesData); err != nil {
+// PostIdsToNames 批量获取ID对应的名称，返回以字符串ID为键的名称
+func PostIdsToNames(ctx context.Context, ids []int) (map[string]string, error) {
+	names, err := ResolveNames(ctx, ids)
+	if err != nil {
 		return nil, err
 	}
 
-	// 构建ID到名称的映射
-	for _, item := range namesData {
-		result[strconv.Itoa(item.ID)] = item.Name
+	result := make(map[string]string, len(names))
+	for id, name := range names {
+		result[strconv.Itoa(id)] = name.Name
 	}
-
 	return result, nil
 }
 
 // ExtractKillID 从zkillboard URL中提取killmail ID
 func ExtractKillID(url string) (int, error) {
-	pattern := regexp.MustCompile(`https://zkillboard\.com/kill/(\d+)/`)
+	pattern := regexp.MustCompile(`^https?://.+/kill/(\d+)/?`)
 	match := pattern.FindStringSubmatch(url)
 	if match != nil && len(match) > 1 {
 		return strconv.Atoi(match[1])
@@ -96,9 +143,9 @@ func ExtractKillID(url string) (int, error) {
 	return 0, fmt.Errorf("无法从URL提取killmail ID")
 }
 
-// ExtractKillmailIDAndHash 从ESI URL中提取killmail ID和hash
+// ExtractKillmailIDAndHash 从ESI URL中提取killmail ID和hash，兼容欧服和国服地址
 func ExtractKillmailIDAndHash(url string) (int, string, error) {
-	pattern := regexp.MustCompile(`https://esi\.evetech\.net/\w+/killmails/(\d+)/([a-f0-9]+).*`)
+	pattern := regexp.MustCompile(`^https?://[^/]+/(?:\w+/)?killmails/(\d+)/([a-f0-9]+)`)
 	match := pattern.FindStringSubmatch(url)
 	if match != nil && len(match) > 2 {
 		killmailID, err := strconv.Atoi(match[1])
@@ -112,7 +159,7 @@ func ExtractKillmailIDAndHash(url string) (int, string, error) {
 }
 
 // GetKillmailHash 从各种输入中获取killmail ID和hash
-func GetKillmailHash(killmailURL string) (int, string, error) {
+func GetKillmailHash(ctx context.Context, killmailURL string) (int, string, error) {
 	killmailID := 0
 	killmailHash := ""
 	var err error
@@ -125,7 +172,7 @@ func GetKillmailHash(killmailURL string) (int, string, error) {
 
 	// 处理URL
 	killmailURL = strings.TrimSpace(killmailURL)
-	if strings.HasPrefix(killmailURL, "https://zkillboard.com/kill/") {
+	if isZkillboardURL(killmailURL) {
 		// 从zkillboard URL提取killmail ID
 		killmailID, err = ExtractKillID(killmailURL)
 		if err != nil || killmailID == 0 {
@@ -133,8 +180,13 @@ func GetKillmailHash(killmailURL string) (int, string, error) {
 		}
 
 		// 从zkillboard API获取hash
-		_url := fmt.Sprintf("https://zkillboard.com/api/killID/%d/", killmailID)
-		resp, err := EsiClient.client.Get(_url)
+		_url := fmt.Sprintf("%s/api/killID/%d/", zkillboardURL, killmailID)
+		req, err := http.NewRequestWithContext(ctx, "GET", _url, nil)
+		if err != nil {
+			return 0, "", err
+		}
+		req.Header.Set("User-Agent", EsiClient.userAgent)
+		resp, err := EsiClient.client.Do(req)
 		if err != nil {
 			return 0, "", err
 		}
@@ -151,7 +203,7 @@ func GetKillmailHash(killmailURL string) (int, string, error) {
 		}
 
 		killmailHash = data[0].Zkb.Hash
-	} else if strings.HasPrefix(killmailURL, "https://esi.evetech.net") {
+	} else if strings.Contains(killmailURL, "/killmails/") {
 		// 从ESI URL提取killmail ID和hash
 		killmailID, killmailHash, err = ExtractKillmailIDAndHash(killmailURL)
 		if err != nil {
@@ -161,3 +213,9 @@ func GetKillmailHash(killmailURL string) (int, string, error) {
 
 	return killmailID, killmailHash, nil
 }
+
+// isZkillboardURL 判断是否为zkillboard击毁链接，包括配置的zkillboard地址
+func isZkillboardURL(killmailURL string) bool {
+	return strings.HasPrefix(killmailURL, defaultZkillboardURL+"/kill/") ||
+		strings.HasPrefix(killmailURL, zkillboardURL+"/kill/")
+}
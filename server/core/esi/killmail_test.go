@@ -0,0 +1,96 @@
+package esi_test
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"testing"
+)
+
+// 内置击毁邮件的最后一击角色和受害者
+const (
+	hunterID = 2112000001
+	victimID = 2112000010
+)
+
+func TestGetKillmail(t *testing.T) {
+	srv := esitest.NewServerWithFixtures()
+	defer srv.Close()
+	defer srv.Install()()
+
+	km, err := esi.GetKillmail(context.Background(), esitest.FixtureKillmailID, esitest.FixtureKillmailHash)
+	if err != nil {
+		t.Fatalf("GetKillmail() error = %v", err)
+	}
+
+	if km.Victim.CharacterID != victimID || km.Victim.ShipTypeID != 587 || km.Victim.DamageTaken != 1500 {
+		t.Errorf("victim = %+v", km.Victim)
+	}
+	if km.Victim.Position == nil || km.Victim.Position.X != 1250000000.5 {
+		t.Errorf("position = %+v", km.Victim.Position)
+	}
+	if len(km.Attackers) != 2 {
+		t.Fatalf("attackers = %d, want 2", len(km.Attackers))
+	}
+	if fb := km.FinalBlow(); fb == nil || fb.CharacterID != hunterID || fb.DamageDone != 1200 {
+		t.Errorf("FinalBlow() = %+v", fb)
+	}
+	if km.Attackers[1].AllianceID != 0 {
+		t.Errorf("attacker without alliance = %+v", km.Attackers[1])
+	}
+
+	// 最后一项为装有矿物的容器
+	items := km.Victim.Items
+	if len(items) != 8 {
+		t.Fatalf("items = %d, want 8", len(items))
+	}
+	container := items[7]
+	if container.ItemTypeID != 3293 || len(container.Items) != 2 {
+		t.Fatalf("container = %+v", container)
+	}
+	tests := []struct {
+		item         esi.KillmailItem
+		wantType     int
+		wantDropped  bool
+		wantQuantity int64
+	}{
+		{items[0], 2873, false, 1},
+		{items[2], 2873, true, 1},
+		{container, 3293, true, 1},
+		{container.Items[0], 34, true, 1000},
+		{container.Items[1], 35, false, 500},
+	}
+	for _, tt := range tests {
+		if tt.item.ItemTypeID != tt.wantType || tt.item.Dropped() != tt.wantDropped || tt.item.Quantity() != tt.wantQuantity {
+			t.Errorf("item = %+v, want type %d dropped %v quantity %d", tt.item, tt.wantType, tt.wantDropped, tt.wantQuantity)
+		}
+	}
+}
+
+func TestGetKillmailInvalid(t *testing.T) {
+	const killmailID, hash = 200000001, "ffff"
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"字段类型错误", `{"killmail_id":200000001,"killmail_time":12345,"solar_system_id":30002187,"victim":{"ship_type_id":587}}`},
+		{"缺少时间", `{"killmail_id":200000001,"solar_system_id":30002187,"victim":{"ship_type_id":587}}`},
+		{"缺少受害舰船", `{"killmail_id":200000001,"killmail_time":"2025-05-01T12:34:56Z","victim":{}}`},
+		{"ID不一致", `{"killmail_id":200000002,"killmail_time":"2025-05-01T12:34:56Z","victim":{"ship_type_id":587}}`},
+		{"不是对象", `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := esitest.NewServer()
+			defer srv.Close()
+			defer srv.Install()()
+			srv.AddKillmail(killmailID, hash, []byte(tt.body))
+
+			if km, err := esi.GetKillmail(context.Background(), killmailID, hash); err == nil {
+				t.Errorf("GetKillmail() = %+v, want error", km)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package esi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"eve-corp-manager/core/cache"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	namesBatchSize = 1000 // /universe/names/ 单次最多1000个ID
+	idsBatchSize   = 500  // /universe/ids/ 单次最多500个名称
+
+	nameCacheTTL    = 30 * 24 * time.Hour // 名称极少变化，长期缓存
+	invalidCacheTTL = time.Hour           // 无效ID短期缓存，避免反复二分
+	idsCacheTTL     = 7 * 24 * time.Hour
+)
+
+// Name /universe/names/ 返回的名称，Category为空表示ID无效
+type Name struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// idsCategories /universe/ids/ 返回的分组与/universe/names/类别的对应关系
+var idsCategories = map[string]string{
+	"agents":          "character",
+	"alliances":       "alliance",
+	"characters":      "character",
+	"constellations":  "constellation",
+	"corporations":    "corporation",
+	"factions":        "faction",
+	"inventory_types": "inventory_type",
+	"regions":         "region",
+	"stations":        "station",
+	"systems":         "solar_system",
+}
+
+// errInvalidIDs /universe/names/ 请求中存在无效ID
+var errInvalidIDs = errors.New("ESI API错误: 存在无效ID")
+
+// Names 全局名称解析服务
+var Names *NameResolver
+
+// NameResolver 名称解析服务，去重、分批、二分定位无效ID并缓存结果
+type NameResolver struct {
+	client *Client
+	names  cache.Cache[Name]
+	ids    cache.Cache[[]Name]
+}
+
+// NewNameResolver 创建名称解析服务，client为nil时使用EsiClient，缓存为nil时不缓存
+func NewNameResolver(client *Client, nameCache cache.Cache[Name], idsCache cache.Cache[[]Name]) *NameResolver {
+	return &NameResolver{client: client, names: nameCache, ids: idsCache}
+}
+
+// Resolve 批量获取ID对应的名称，忽略0和无效ID
+func (r *NameResolver) Resolve(ctx context.Context, ids []int) (map[int]Name, error) {
+	result := make(map[int]Name, len(ids))
+
+	missing := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if r.names != nil {
+			if name, ok := r.names.Get(nameCacheKey(id)); ok {
+				if name.Category != "" {
+					result[id] = name
+				}
+				continue
+			}
+		}
+		missing = append(missing, id)
+	}
+
+	for start := 0; start < len(missing); start += namesBatchSize {
+		end := min(start+namesBatchSize, len(missing))
+		if err := r.resolveBatch(ctx, missing[start:end], result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// resolveBatch 解析一批ID，整批因无效ID失败时二分重试以定位无效ID
+func (r *NameResolver) resolveBatch(ctx context.Context, ids []int, result map[int]Name) error {
+	names, err := r.postNames(ctx, ids)
+	if errors.Is(err, errInvalidIDs) {
+		if len(ids) == 1 {
+			r.store(Name{ID: ids[0]}, invalidCacheTTL)
+			return nil
+		}
+		mid := len(ids) / 2
+		if err := r.resolveBatch(ctx, ids[:mid], result); err != nil {
+			return err
+		}
+		return r.resolveBatch(ctx, ids[mid:], result)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		result[name.ID] = name
+		r.store(name, nameCacheTTL)
+	}
+	return nil
+}
+
+// postNames 调用/universe/names/
+func (r *NameResolver) postNames(ctx context.Context, ids []int) ([]Name, error) {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []Name
+	if err := r.post(ctx, "/universe/names/", body, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Lookup 根据名称反查ID，名称不区分大小写，同名的不同类别都会返回，未找到的名称不在结果中
+func (r *NameResolver) Lookup(ctx context.Context, names []string) (map[string][]Name, error) {
+	result := make(map[string][]Name, len(names))
+
+	missing := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if r.ids != nil {
+			if matches, ok := r.ids.Get(key); ok {
+				if len(matches) > 0 {
+					result[key] = matches
+				}
+				continue
+			}
+		}
+		missing = append(missing, key)
+	}
+
+	for start := 0; start < len(missing); start += idsBatchSize {
+		end := min(start+idsBatchSize, len(missing))
+		if err := r.lookupBatch(ctx, missing[start:end], result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// lookupBatch 调用/universe/ids/并按小写名称归类结果
+func (r *NameResolver) lookupBatch(ctx context.Context, names []string, result map[string][]Name) error {
+	body, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	var groups map[string][]struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := r.post(ctx, "/universe/ids/", body, &groups); err != nil {
+		return err
+	}
+
+	found := make(map[string][]Name, len(names))
+	for group, items := range groups {
+		category, ok := idsCategories[group]
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			name := Name{ID: item.ID, Name: item.Name, Category: category}
+			key := strings.ToLower(item.Name)
+			found[key] = append(found[key], name)
+			r.store(name, nameCacheTTL)
+		}
+	}
+
+	for _, key := range names {
+		if len(found[key]) > 0 {
+			result[key] = found[key]
+		}
+		if r.ids != nil {
+			r.ids.Set(key, found[key], idsCacheTTL)
+		}
+	}
+	return nil
+}
+
+// post 发送POST请求并解析JSON，404表示请求中存在无效ID
+func (r *NameResolver) post(ctx context.Context, path string, body []byte, result interface{}) error {
+	client := r.client
+	if client == nil {
+		client = EsiClient
+	}
+
+	resp, err := client.Post(ctx, path+"?datasource="+client.Datasource(), "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body)
+		return errInvalidIDs
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("ESI API错误 (状态码: %d)", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// store 缓存ID对应的名称
+func (r *NameResolver) store(name Name, ttl time.Duration) {
+	if r.names != nil {
+		r.names.Set(nameCacheKey(name.ID), name, ttl)
+	}
+}
+
+func nameCacheKey(id int) string {
+	return strconv.Itoa(id)
+}
+
+// ResolveNames 使用全局名称解析服务批量获取ID对应的名称，未初始化时不使用缓存
+func ResolveNames(ctx context.Context, ids []int) (map[int]Name, error) {
+	resolver := Names
+	if resolver == nil {
+		resolver = NewNameResolver(nil, nil, nil)
+	}
+	return resolver.Resolve(ctx, ids)
+}
+
+// LookupIDs 使用全局名称解析服务根据名称反查ID
+func LookupIDs(ctx context.Context, names []string) (map[string][]Name, error) {
+	resolver := Names
+	if resolver == nil {
+		resolver = NewNameResolver(nil, nil, nil)
+	}
+	return resolver.Lookup(ctx, names)
+}
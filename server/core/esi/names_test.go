@@ -0,0 +1,145 @@
+package esi_test
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+const namesPath = "/universe/names/"
+
+// 无效ID，模拟服务中不存在
+const (
+	badID1 = 90000001
+	badID2 = 90000002
+)
+
+// 内置测试数据中的有效ID
+const (
+	corpID   = 98000001
+	systemID = 30002187
+)
+
+func TestResolveNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []int
+		want     []int
+		wantHits int
+	}{
+		{
+			name:     "全部有效",
+			ids:      []int{hunterID, victimID, corpID},
+			want:     []int{hunterID, victimID, corpID},
+			wantHits: 1,
+		},
+		{
+			name:     "去重并忽略0",
+			ids:      []int{hunterID, hunterID, 0, -1, corpID},
+			want:     []int{hunterID, corpID},
+			wantHits: 1,
+		},
+		{
+			// [bad a b c] -> [bad a] -> [bad] [a]; [b c]
+			name:     "二分定位一个无效ID",
+			ids:      []int{badID1, hunterID, victimID, corpID},
+			want:     []int{hunterID, victimID, corpID},
+			wantHits: 5,
+		},
+		{
+			// [a bad1 b bad2] -> [a bad1] -> [a] [bad1]; [b bad2] -> [b] [bad2]
+			name:     "二分定位多个无效ID",
+			ids:      []int{hunterID, badID1, victimID, badID2},
+			want:     []int{hunterID, victimID},
+			wantHits: 7,
+		},
+		{
+			name:     "全部无效",
+			ids:      []int{badID1, badID2},
+			want:     nil,
+			wantHits: 3,
+		},
+		{
+			name:     "空列表不请求",
+			ids:      []int{0},
+			want:     nil,
+			wantHits: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := esitest.NewServerWithFixtures()
+			defer srv.Close()
+			defer srv.Install()()
+
+			resolver := esi.NewNameResolver(nil, nil, nil)
+			names, err := resolver.Resolve(context.Background(), tt.ids)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			got := slices.Sorted(maps.Keys(names))
+			if want := slices.Sorted(slices.Values(tt.want)); !slices.Equal(got, want) {
+				t.Errorf("Resolve() ids = %v, want %v", got, want)
+			}
+			for id, name := range names {
+				if name.ID != id || name.Name == "" || name.Category == "" {
+					t.Errorf("Resolve()[%d] = %+v", id, name)
+				}
+			}
+			if hits := srv.Hits(namesPath); hits != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestResolveNamesChunks(t *testing.T) {
+	srv := esitest.NewServer()
+	defer srv.Close()
+	defer srv.Install()()
+
+	ids := make([]int, 0, 1500)
+	for id := 1000001; id <= 1001500; id++ {
+		ids = append(ids, id)
+		srv.AddNames(esitest.Name{ID: id, Name: "Esitest Pilot", Category: "character"})
+	}
+
+	names, err := esi.NewNameResolver(nil, nil, nil).Resolve(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(names) != len(ids) {
+		t.Errorf("Resolve() = %d names, want %d", len(names), len(ids))
+	}
+	// 每批最多1000个ID
+	if hits := srv.Hits(namesPath); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
+
+func TestResolveNamesCache(t *testing.T) {
+	srv := esitest.NewServerWithFixtures()
+	defer srv.Close()
+	defer srv.Install()()
+
+	resolver := esi.NewNameResolver(nil, esitest.NewMemoryCache[esi.Name](time.Hour), nil)
+	ids := []int{badID1, hunterID, systemID}
+	for range 2 {
+		names, err := resolver.Resolve(context.Background(), ids)
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if len(names) != 2 || names[systemID].Name != "Amarr" {
+			t.Errorf("Resolve() = %v", names)
+		}
+	}
+	// [bad a b] -> [bad]; [a b]，第二次全部命中缓存，无效ID也不再二分
+	if hits := srv.Hits(namesPath); hits != 3 {
+		t.Errorf("hits = %d, want 3", hits)
+	}
+}
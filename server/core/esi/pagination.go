@@ -0,0 +1,150 @@
+package esi
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// pageConcurrency 同时请求的最大页数，流式读取时也是预读的页数上限
+const pageConcurrency = 100
+
+// maxPages X-Pages允许的最大值，ESI最大的分页接口也远小于此值
+const maxPages = 10000
+
+// pageResult 单页请求结果
+type pageResult[T any] struct {
+	items []T
+	err   error
+}
+
+// GetAllPages 并发获取所有分页数据，按页码顺序合并
+func GetAllPages[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	return collectPages(Pages[T](ctx, c, path, query, nil))
+}
+
+// AuthorizedGetAllPages 带授权的并发获取所有分页数据，按页码顺序合并
+func AuthorizedGetAllPages[T any](ctx context.Context, c *Client, path string, query url.Values, tokenSource TokenSource) ([]T, error) {
+	return collectPages(Pages[T](ctx, c, path, query, tokenSource))
+}
+
+// Pages 按页码顺序逐页返回分页数据，适用于数据量很大的接口
+// tokenSource为nil时不带授权；任意一页失败时取消其余请求并返回错误
+func Pages[T any](ctx context.Context, c *Client, path string, query url.Values, tokenSource TokenSource) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// 首先获取第一页来确定总页数
+		firstPage, totalPages, err := fetchPage[T](ctx, c, path, query, 1, tokenSource)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// 每页一个通道，保证按页码顺序返回
+		pending := make([]chan pageResult[T], totalPages+1)
+		start := func(page int) {
+			ch := make(chan pageResult[T], 1)
+			pending[page] = ch
+			go func() {
+				items, _, err := fetchPage[T](ctx, c, path, query, page, tokenSource)
+				ch <- pageResult[T]{items: items, err: err}
+			}()
+		}
+
+		next := 2
+		for ; next <= totalPages && next < 2+pageConcurrency; next++ {
+			start(next)
+		}
+
+		if !yield(firstPage, nil) {
+			return
+		}
+
+		for page := 2; page <= totalPages; page++ {
+			var result pageResult[T]
+			select {
+			case result = <-pending[page]:
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			}
+			pending[page] = nil
+
+			if result.err != nil {
+				yield(nil, fmt.Errorf("获取第%d页失败: %w", page, result.err))
+				return
+			}
+
+			if next <= totalPages {
+				start(next)
+				next++
+			}
+
+			if !yield(result.items, nil) {
+				return
+			}
+		}
+	}
+}
+
+// collectPages 合并所有分页数据
+func collectPages[T any](pages iter.Seq2[[]T, error]) ([]T, error) {
+	var all []T
+	for items, err := range pages {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// fetchPage 获取单页数据并返回总页数，没有X-Pages头时视为只有一页
+func fetchPage[T any](ctx context.Context, c *Client, path string, query url.Values, page int, tokenSource TokenSource) ([]T, int, error) {
+	// 复制查询参数并添加页码
+	pageQuery := url.Values{}
+	for k, v := range query {
+		pageQuery[k] = v
+	}
+	if page > 1 {
+		pageQuery.Set("page", strconv.Itoa(page))
+	}
+
+	resp, err := c.get(ctx, path, pageQuery, tokenSource)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalPages, err := parsePages(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+
+	var items []T
+	if err := decodeResponse(resp, &items); err != nil {
+		return nil, 0, err
+	}
+	return items, totalPages, nil
+}
+
+// parsePages 解析X-Pages头
+func parsePages(resp *http.Response) (int, error) {
+	totalPagesStr := resp.Header.Get("X-Pages")
+	if totalPagesStr == "" {
+		return 1, nil
+	}
+	totalPages, err := strconv.Atoi(totalPagesStr)
+	if err != nil {
+		return 0, fmt.Errorf("解析X-Pages失败: %w", err)
+	}
+	if totalPages < 1 || totalPages > maxPages {
+		return 0, fmt.Errorf("无效的X-Pages: %d", totalPages)
+	}
+	return totalPages, nil
+}
@@ -0,0 +1,154 @@
+package esi_test
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"slices"
+	"testing"
+)
+
+// asset 分页测试使用的数据
+type asset struct {
+	ItemID int `json:"item_id"`
+}
+
+func assets(ids ...int) []asset {
+	result := make([]asset, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, asset{ItemID: id})
+	}
+	return result
+}
+
+func TestGetAllPages(t *testing.T) {
+	const path = "/corporations/98000001/assets/"
+
+	tests := []struct {
+		name     string
+		setup    func(srv *esitest.Server)
+		want     []int
+		wantHits int
+		wantErr  bool
+	}{
+		{
+			name:     "没有X-Pages时只有一页",
+			setup:    func(srv *esitest.Server) { srv.SetJSON(path, assets(1, 2)) },
+			want:     []int{1, 2},
+			wantHits: 1,
+		},
+		{
+			name: "按页码顺序合并",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1, 2), assets(3), assets(4, 5))
+			},
+			want:     []int{1, 2, 3, 4, 5},
+			wantHits: 3,
+		},
+		{
+			name: "包含空页",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1), assets(), assets(2))
+			},
+			want:     []int{1, 2},
+			wantHits: 3,
+		},
+		{
+			name: "第一页失败",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1), assets(2))
+				srv.FailNext(path, 403, 1)
+			},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		{
+			name: "X-Pages为负数",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1))
+				srv.SetHeader(path, "X-Pages", "-1")
+			},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		{
+			name: "X-Pages过大",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1))
+				srv.SetHeader(path, "X-Pages", "100000000")
+			},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		{
+			name: "X-Pages不是数字",
+			setup: func(srv *esitest.Server) {
+				srv.SetPages(path, assets(1))
+				srv.SetHeader(path, "X-Pages", "x")
+			},
+			wantHits: 1,
+			wantErr:  true,
+		},
+		{
+			name:     "接口不存在",
+			setup:    func(srv *esitest.Server) {},
+			wantHits: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := esitest.NewServer()
+			defer srv.Close()
+			defer srv.Install()()
+			tt.setup(srv)
+
+			items, err := esi.GetAllPages[asset](context.Background(), esi.EsiClient, path, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetAllPages() = %v, want error", items)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("GetAllPages() error = %v", err)
+				}
+				var got []int
+				for _, item := range items {
+					got = append(got, item.ItemID)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("GetAllPages() = %v, want %v", got, tt.want)
+				}
+			}
+			if hits := srv.Hits(path); hits != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestPagesStopEarly(t *testing.T) {
+	const path = "/corporations/98000001/assets/"
+
+	srv := esitest.NewServer()
+	defer srv.Close()
+	defer srv.Install()()
+	srv.SetPages(path, assets(1), assets(2), assets(3))
+
+	var got []int
+	for items, err := range esi.Pages[asset](context.Background(), esi.EsiClient, path, nil, nil) {
+		if err != nil {
+			t.Fatalf("Pages() error = %v", err)
+		}
+		for _, item := range items {
+			got = append(got, item.ItemID)
+		}
+		if len(got) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Pages() = %v, want [1 2]", got)
+	}
+}
@@ -0,0 +1,95 @@
+package esi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRedisQURL zKillboard RedisQ官方地址
+const defaultRedisQURL = "https://zkillredisq.stream/listen.php"
+
+// redisqURL RedisQ接口地址
+var redisqURL = defaultRedisQURL
+
+// SetRedisQURL 设置RedisQ接口地址，留空使用官方地址
+func SetRedisQURL(listenURL string) {
+	if listenURL == "" {
+		listenURL = defaultRedisQURL
+	}
+	redisqURL = listenURL
+}
+
+// RedisQPackage RedisQ推送的击毁邮件，新版RedisQ不再携带Killmail，需要通过ESI获取
+type RedisQPackage struct {
+	KillID   int       `json:"killID"`
+	Killmail *Killmail `json:"killmail,omitempty"`
+	Zkb      struct {
+		LocationID  int     `json:"locationID"`
+		Hash        string  `json:"hash"`
+		TotalValue  float64 `json:"totalValue"`
+		NPC         bool    `json:"npc"`
+		Solo        bool    `json:"solo"`
+		Awox        bool    `json:"awox"`
+		Href        string  `json:"href"`
+		FittedValue float64 `json:"fittedValue"`
+	} `json:"zkb"`
+}
+
+// RedisQError RedisQ返回的错误状态，RetryAfter为服务端要求的等待时间
+type RedisQError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *RedisQError) Error() string {
+	return fmt.Sprintf("RedisQ错误 (状态码: %d)", e.StatusCode)
+}
+
+// ListenRedisQ 从RedisQ获取下一条击毁邮件，队列为空时等待ttw秒后返回nil
+// 同一queueID的未读消息由RedisQ保留约3小时，重启后使用相同queueID即可继续读取
+func ListenRedisQ(ctx context.Context, queueID string, ttw int) (*RedisQPackage, error) {
+	query := url.Values{}
+	query.Set("queueID", queueID)
+	if ttw > 0 {
+		query.Set("ttw", strconv.Itoa(ttw))
+	}
+
+	separator := "?"
+	if strings.Contains(redisqURL, "?") {
+		separator = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", redisqURL+separator+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", EsiClient.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := EsiClient.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return nil, &RedisQError{StatusCode: resp.StatusCode, RetryAfter: time.Duration(retryAfter) * time.Second}
+	}
+
+	var data struct {
+		Package *RedisQPackage `json:"package"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Package != nil && (data.Package.KillID == 0 || data.Package.Zkb.Hash == "") {
+		return nil, fmt.Errorf("无效的RedisQ数据: %d", data.Package.KillID)
+	}
+	return data.Package, nil
+}
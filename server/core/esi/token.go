@@ -0,0 +1,22 @@
+package esi
+
+import "context"
+
+// TokenSource 提供授权请求使用的访问令牌
+type TokenSource interface {
+	// Token 返回有效的访问令牌，必要时自动刷新
+	Token(ctx context.Context) (string, error)
+	// Invalidate 丢弃缓存的访问令牌，下次调用Token时强制刷新
+	Invalidate(ctx context.Context)
+}
+
+// StaticToken 固定的访问令牌，不支持刷新
+type StaticToken string
+
+// Token 返回固定令牌
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Invalidate 固定令牌无法刷新
+func (t StaticToken) Invalidate(ctx context.Context) {}
@@ -0,0 +1,158 @@
+package esi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultZkbWebsocketURL zKillboard websocket官方地址
+	defaultZkbWebsocketURL = "wss://zkillboard.com/websocket/"
+
+	zkbWebsocketPingInterval = 30 * time.Second
+	zkbWebsocketReadTimeout  = 3 * zkbWebsocketPingInterval
+	zkbWebsocketWriteTimeout = 10 * time.Second
+)
+
+// zkbWebsocketURL zKillboard websocket地址
+var zkbWebsocketURL = defaultZkbWebsocketURL
+
+// SetZkbWebsocketURL 设置zKillboard websocket地址，留空使用官方地址
+func SetZkbWebsocketURL(websocketURL string) {
+	if websocketURL == "" {
+		websocketURL = defaultZkbWebsocketURL
+	}
+	zkbWebsocketURL = websocketURL
+}
+
+// ZkbCorporationChannel 公司相关击毁邮件的订阅频道
+func ZkbCorporationChannel(corporationID int) string {
+	return fmt.Sprintf("corporation:%d", corporationID)
+}
+
+// ZkbAllianceChannel 联盟相关击毁邮件的订阅频道
+func ZkbAllianceChannel(allianceID int) string {
+	return fmt.Sprintf("alliance:%d", allianceID)
+}
+
+// ZkbWebsocket zKillboard websocket连接，只推送已订阅频道的击毁邮件
+// 连接断开期间的击毁邮件不会补发
+type ZkbWebsocket struct {
+	conn   *websocket.Conn
+	mu     sync.Mutex // 串行写入
+	closed chan struct{}
+	once   sync.Once
+}
+
+// DialZkbWebsocket 连接zKillboard websocket，ctx取消时关闭连接
+func DialZkbWebsocket(ctx context.Context) (*ZkbWebsocket, error) {
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+	}
+	header := http.Header{}
+	if EsiClient != nil {
+		if transport, ok := EsiClient.client.Transport.(*http.Transport); ok && transport.Proxy != nil {
+			dialer.Proxy = transport.Proxy
+		}
+		header.Set("User-Agent", EsiClient.userAgent)
+	}
+
+	conn, _, err := dialer.DialContext(ctx, zkbWebsocketURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &ZkbWebsocket{conn: conn, closed: make(chan struct{})}
+	conn.SetReadDeadline(time.Now().Add(zkbWebsocketReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(zkbWebsocketReadTimeout))
+	})
+	go w.keepalive(ctx)
+	return w, nil
+}
+
+// Subscribe 订阅频道
+func (w *ZkbWebsocket) Subscribe(channel string) error {
+	return w.send("sub", channel)
+}
+
+// Unsubscribe 取消订阅频道
+func (w *ZkbWebsocket) Unsubscribe(channel string) error {
+	return w.send("unsub", channel)
+}
+
+// Next 读取下一条击毁邮件，连接断开或超时未收到心跳时返回错误
+// 推送内容为完整的击毁邮件，转换为RedisQPackage与RedisQ统一处理
+func (w *ZkbWebsocket) Next() (*RedisQPackage, error) {
+	for {
+		_, message, err := w.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		w.conn.SetReadDeadline(time.Now().Add(zkbWebsocketReadTimeout))
+
+		var km Killmail
+		if err := json.Unmarshal(message, &km); err != nil || km.KillmailID == 0 {
+			// 订阅确认等非击毁邮件消息
+			continue
+		}
+		pkg := &RedisQPackage{KillID: km.KillmailID, Killmail: &km}
+		var data struct {
+			Zkb json.RawMessage `json:"zkb"`
+		}
+		if err := json.Unmarshal(message, &data); err == nil && len(data.Zkb) > 0 {
+			json.Unmarshal(data.Zkb, &pkg.Zkb)
+		}
+		if pkg.Zkb.Hash == "" {
+			return nil, fmt.Errorf("无效的websocket数据: %d", km.KillmailID)
+		}
+		return pkg, nil
+	}
+}
+
+// Close 关闭连接
+func (w *ZkbWebsocket) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.closed)
+		err = w.conn.Close()
+	})
+	return err
+}
+
+func (w *ZkbWebsocket) send(action, channel string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(zkbWebsocketWriteTimeout))
+	return w.conn.WriteJSON(map[string]string{"action": action, "channel": channel})
+}
+
+// keepalive 定期发送ping，ctx取消或连接关闭时退出
+func (w *ZkbWebsocket) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(zkbWebsocketPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.Close()
+			return
+		case <-w.closed:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			err := w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(zkbWebsocketWriteTimeout))
+			w.mu.Unlock()
+			if err != nil {
+				w.Close()
+				return
+			}
+		}
+	}
+}
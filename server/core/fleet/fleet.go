@@ -0,0 +1,256 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"eve-corp-manager/models/service/fleet"
+	systemModel "eve-corp-manager/models/system"
+	characterRepo "eve-corp-manager/repository/service/character"
+	fleetRepo "eve-corp-manager/repository/service/fleet"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	systemRepo "eve-corp-manager/repository/system"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFleetNotFound       = errors.New("舰队不存在")
+	ErrInvalidTime         = errors.New("结束时间不能早于开始时间")
+	ErrAutoSrpEndTime      = errors.New("开启自动补损的舰队必须填写结束时间")
+	ErrSolarSystemNotFound = errors.New("星系不存在")
+	ErrFleetHasSrp         = errors.New("舰队已有补损申请，不能删除")
+)
+
+// Participant 舰队参与角色及其所属用户的主角色，未绑定的角色只有名称
+type Participant struct {
+	CharacterID       uint      `json:"characterId"`
+	CharacterName     string    `json:"characterName"`
+	UserID            uint      `json:"userId"`
+	MainCharacterID   int       `json:"mainCharacterId"`
+	MainCharacterName string    `json:"mainCharacterName"`
+	JoinTime          time.Time `json:"joinTime"`
+}
+
+// Detail 舰队详情
+type Detail struct {
+	*fleet.Fleet
+	Participants []Participant `json:"participants"`
+}
+
+// Save 校验并保存舰队，solarSystemID不为0时从SDE解析舰队地点
+// 新建舰队未指定指挥时使用创建用户的主角色，指挥角色名称优先使用已绑定角色的记录
+// 开启自动补损的舰队必须有结束时间，避免之后的损失都被匹配到该舰队
+func Save(ctx context.Context, f *fleet.Fleet, solarSystemID int, userID uint) (*fleet.Fleet, error) {
+	if f.EndTime != nil && f.EndTime.Before(f.StartTime) {
+		return nil, ErrInvalidTime
+	}
+	if f.AutoSrp && f.EndTime == nil {
+		return nil, ErrAutoSrpEndTime
+	}
+
+	if f.ID == 0 && f.FleetCommanderID == 0 {
+		userRepo := systemRepo.UserRepository{DB: global.Db}
+		if user, err := userRepo.Get(&systemModel.User{UserId: userID}); err == nil && user.MainCharacterId > 0 {
+			f.FleetCommanderID = uint(user.MainCharacterId)
+		}
+	}
+
+	if solarSystemID > 0 {
+		location, err := ResolveLocation(solarSystemID)
+		if err != nil {
+			return nil, err
+		}
+		f.FleetLocation = location
+	}
+	if f.FleetLocation == nil {
+		f.FleetLocation = map[string]interface{}{}
+	}
+
+	f.FleetCommanderName = ""
+	if f.FleetCommanderID > 0 {
+		names := characterNames(ctx, []uint{f.FleetCommanderID})
+		f.FleetCommanderName = names[f.FleetCommanderID]
+	}
+
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	if f.ID == 0 {
+		return repo.Add(f)
+	}
+	return repo.Update(f)
+}
+
+// ResolveLocation 从SDE获取星系及所属星域，作为舰队地点保存
+func ResolveLocation(solarSystemID int) (map[string]interface{}, error) {
+	system, err := sde.GetSolarSystemByID(solarSystemID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSolarSystemNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"solarSystemId":   system.SolarSystemID,
+		"solarSystemName": system.SolarSystemName,
+		"constellationId": system.ConstellationID,
+		"regionId":        system.RegionID,
+		"regionName":      system.RegionName,
+		"security":        system.Security,
+	}, nil
+}
+
+// Get 获取舰队
+func Get(id uint) (*fleet.Fleet, error) {
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	f, err := repo.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFleetNotFound
+	}
+	return f, err
+}
+
+// Delete 删除舰队，已有补损申请关联的舰队不能删除
+func Delete(id uint) error {
+	if _, err := Get(id); err != nil {
+		return err
+	}
+
+	srpRepository := srpRepo.SrpRepository{DB: global.Db}
+	_, total, err := srpRepository.List(srpRepo.SrpFilter{FleetID: id, Page: 1, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		return ErrFleetHasSrp
+	}
+
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	return repo.Delete(id)
+}
+
+// GetDetail 获取舰队及参与角色，角色名称优先使用已绑定角色的记录，其余通过ESI解析
+func GetDetail(ctx context.Context, id uint) (*Detail, error) {
+	f, err := Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	members, err := repo.Members(id)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := resolveParticipants(ctx, members)
+	if err != nil {
+		return nil, err
+	}
+	return &Detail{Fleet: f, Participants: participants}, nil
+}
+
+// AddMembers 添加舰队参与角色，返回新增数量
+func AddMembers(id uint, characterIDs []uint) (int, error) {
+	if _, err := Get(id); err != nil {
+		return 0, err
+	}
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	return repo.AddMembers(id, characterIDs)
+}
+
+// RemoveMember 移除舰队参与角色
+func RemoveMember(id, characterID uint) error {
+	if _, err := Get(id); err != nil {
+		return err
+	}
+	repo := fleetRepo.FleetRepository{DB: global.Db}
+	return repo.RemoveMember(id, characterID)
+}
+
+// resolveParticipants 将参与记录解析为角色、所属用户和主角色
+func resolveParticipants(ctx context.Context, members []fleet.CharacterFleetAssociation) ([]Participant, error) {
+	participants := make([]Participant, 0, len(members))
+	if len(members) == 0 {
+		return participants, nil
+	}
+
+	characterIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		characterIDs = append(characterIDs, member.CharacterID)
+	}
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	bound, err := userCharacterRepo.ListByIDs(characterIDs)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, 0, len(bound))
+	owners := make(map[uint]uint, len(bound))
+	for _, char := range bound {
+		owners[char.CharacterID] = char.UserID
+		userIDs = append(userIDs, char.UserID)
+	}
+
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	users, err := userRepo.ListByIds(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	mains := make(map[uint]uint, len(users))
+	for _, user := range users {
+		if user.MainCharacterId > 0 {
+			mains[user.UserId] = uint(user.MainCharacterId)
+			characterIDs = append(characterIDs, uint(user.MainCharacterId))
+		}
+	}
+
+	names := characterNames(ctx, characterIDs)
+	for _, member := range members {
+		participant := Participant{
+			CharacterID:   member.CharacterID,
+			CharacterName: names[member.CharacterID],
+			UserID:        owners[member.CharacterID],
+			JoinTime:      member.CreatedAt,
+		}
+		if mainID, ok := mains[participant.UserID]; ok {
+			participant.MainCharacterID = int(mainID)
+			participant.MainCharacterName = names[mainID]
+		}
+		participants = append(participants, participant)
+	}
+	return participants, nil
+}
+
+// characterNames 获取角色名称，已绑定的角色使用本地记录，其余通过ESI解析，解析失败的角色名称为空
+func characterNames(ctx context.Context, characterIDs []uint) map[uint]string {
+	names := make(map[uint]string, len(characterIDs))
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	if bound, err := userCharacterRepo.ListByIDs(characterIDs); err == nil {
+		for _, char := range bound {
+			if char.CharacterName != "" {
+				names[char.CharacterID] = char.CharacterName
+			}
+		}
+	}
+
+	var missing []int
+	for _, id := range characterIDs {
+		if _, ok := names[id]; !ok && id > 0 && !slices.Contains(missing, int(id)) {
+			missing = append(missing, int(id))
+		}
+	}
+	if len(missing) == 0 {
+		return names
+	}
+
+	resolved, err := esi.ResolveNames(ctx, missing)
+	if err != nil {
+		global.Logger.Warnf("解析角色名称失败: %v", err)
+		return names
+	}
+	for id, name := range resolved {
+		names[uint(id)] = name.Name
+	}
+	return names
+}
@@ -0,0 +1,338 @@
+package killmail
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/global"
+	"eve-corp-manager/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// FeedWebsocket 通过zKillboard websocket只接收关注的公司/联盟的击毁邮件，websocket不补发断线期间的推送，重连后通过ESI同步补齐
+	FeedWebsocket = "websocket"
+	// FeedRedisQ 通过zKillboard RedisQ接收全部击毁邮件，RedisQ按队列ID保留约3小时的未读消息
+	FeedRedisQ = "redisq"
+
+	feedRetryKey         = "zkb:feed:retry"          // 保存失败等待重试的击毁邮件，score为下次重试时间
+	feedRetryAttemptsKey = "zkb:feed:retry_attempts" // 击毁邮件 -> 已重试次数
+
+	feedRetryInterval    = 30 * time.Second // 检查待重试击毁邮件的间隔
+	feedRetryDelay       = time.Minute      // 第一次重试的等待时间，之后逐次翻倍
+	feedRetryMaxDelay    = time.Hour
+	feedRetryMaxAttempts = 24 // 超过后放弃，由ESI同步补齐
+	feedRetryBatch       = 20
+
+	feedMinBackoff       = time.Second
+	feedMaxBackoff       = 5 * time.Minute
+	allowedIDsRefreshTTL = 5 * time.Minute
+)
+
+// Ingester 监听zKillboard推送，保存本公司/联盟相关的击杀和损失
+// 保存失败(如ESI或Janice不可用)的击毁邮件加入重试集合，按退避时间重试
+type Ingester struct {
+	// Feed 推送来源，FeedWebsocket(默认)或FeedRedisQ
+	Feed string
+	// QueueID RedisQ队列ID，留空时从Redis读取或生成并保存，RedisQ按队列ID保留未读消息
+	QueueID string
+	// Redis 保存RedisQ队列ID和重试集合，为nil时重试集合只保存在内存中
+	Redis *redis.Client
+	// AllowedIDs 返回关注的公司/联盟ID，默认读取系统设置allowed_corp_list
+	AllowedIDs func() ([]int, error)
+	// RetryInterval 检查待重试击毁邮件的间隔，RetryDelay 第一次重试的等待时间
+	RetryInterval time.Duration
+	RetryDelay    time.Duration
+	// CatchUp websocket重连后在后台执行，补齐断线期间的击毁邮件，默认SyncAll
+	CatchUp func(ctx context.Context) error
+
+	mu        sync.Mutex
+	allowed   map[int]bool
+	refreshAt time.Time
+	retries   map[string]retryEntry // Redis为nil时使用
+
+	catchingUp atomic.Bool // 正在执行CatchUp
+}
+
+// retryEntry 内存中的重试记录
+type retryEntry struct {
+	next     time.Time
+	attempts int
+}
+
+// NewIngester 创建击毁邮件推送监听器
+func NewIngester(feed, queueID string, redisClient *redis.Client) *Ingester {
+	return &Ingester{
+		Feed:          feed,
+		QueueID:       queueID,
+		Redis:         redisClient,
+		AllowedIDs:    allowedCorpIDs,
+		RetryInterval: feedRetryInterval,
+		RetryDelay:    feedRetryDelay,
+		CatchUp:       SyncAll,
+	}
+}
+
+// Run 持续监听直到ctx取消，推送不可用时按指数退避重连
+func (i *Ingester) Run(ctx context.Context) error {
+	go i.retryLoop(ctx)
+
+	if i.Feed == FeedRedisQ {
+		return i.runRedisQ(ctx)
+	}
+	return i.runWebsocket(ctx)
+}
+
+// Handle 处理一条推送，与关注的公司/联盟相关时保存，返回是否保存
+// 推送不带击毁邮件时先从ESI获取用于过滤，不使用HTTP缓存，只有相关的击毁邮件在保存时进入缓存
+func (i *Ingester) Handle(ctx context.Context, pkg *esi.RedisQPackage) (bool, error) {
+	km := pkg.Killmail
+	if km == nil {
+		var err error
+		if km, err = esi.GetKillmail(esi.WithoutCache(ctx), pkg.KillID, pkg.Zkb.Hash); err != nil {
+			return false, err
+		}
+	}
+
+	allowed, err := i.allowedIDs()
+	if err != nil {
+		return false, err
+	}
+	if !involves(km, allowed) {
+		return false, nil
+	}
+
+	if _, err := Save(ctx, pkg.KillID, pkg.Zkb.Hash, Creator{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// handle 处理推送，失败时加入重试集合，不阻塞后续推送
+func (i *Ingester) handle(ctx context.Context, pkg *esi.RedisQPackage) {
+	saved, err := i.Handle(ctx, pkg)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		global.Logger.Warnf("处理推送的击毁邮件失败，稍后重试, killID: %v, error: %v", pkg.KillID, err)
+		i.scheduleRetry(ctx, pkg.KillID, pkg.Zkb.Hash)
+		return
+	}
+	if saved {
+		global.Logger.Infof("收到相关击毁邮件, killID: %v", pkg.KillID)
+	}
+}
+
+// retryLoop 定期重试保存失败的击毁邮件，直到ctx取消
+func (i *Ingester) retryLoop(ctx context.Context) {
+	ticker := time.NewTicker(i.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.RetryDue(ctx)
+		}
+	}
+}
+
+// RetryDue 重试已到时间的击毁邮件，返回本次保存的数量
+func (i *Ingester) RetryDue(ctx context.Context) int {
+	members, err := i.dueRetries(ctx)
+	if err != nil {
+		global.Logger.Errorf("读取待重试击毁邮件失败: %v", err)
+		return 0
+	}
+
+	saved := 0
+	for _, member := range members {
+		killID, hash, ok := parseRetryMember(member)
+		if !ok {
+			i.removeRetry(ctx, member)
+			continue
+		}
+		pkg := &esi.RedisQPackage{KillID: killID}
+		pkg.Zkb.Hash = hash
+
+		ok, err := i.Handle(ctx, pkg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return saved
+			}
+			global.Logger.Warnf("重试保存击毁邮件失败, killID: %v, error: %v", killID, err)
+			i.scheduleRetry(ctx, killID, hash)
+			continue
+		}
+		i.removeRetry(ctx, member)
+		if ok {
+			saved++
+			global.Logger.Infof("重试保存击毁邮件成功, killID: %v", killID)
+		}
+	}
+	return saved
+}
+
+// PendingRetries 等待重试的击毁邮件数量
+func (i *Ingester) PendingRetries(ctx context.Context) (int64, error) {
+	if i.Redis == nil {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		return int64(len(i.retries)), nil
+	}
+	return i.Redis.ZCard(ctx, feedRetryKey).Result()
+}
+
+// scheduleRetry 按已重试次数计算下次重试时间，超过次数后放弃
+func (i *Ingester) scheduleRetry(ctx context.Context, killID int, hash string) {
+	member := retryMember(killID, hash)
+
+	var attempts int
+	if i.Redis == nil {
+		i.mu.Lock()
+		if i.retries == nil {
+			i.retries = make(map[string]retryEntry)
+		}
+		attempts = i.retries[member].attempts + 1
+		i.retries[member] = retryEntry{next: time.Now().Add(i.retryDelay(attempts)), attempts: attempts}
+		i.mu.Unlock()
+	} else {
+		count, err := i.Redis.HIncrBy(ctx, feedRetryAttemptsKey, member, 1).Result()
+		if err != nil {
+			global.Logger.Errorf("保存待重试击毁邮件失败, killID: %v, error: %v", killID, err)
+			return
+		}
+		attempts = int(count)
+		next := time.Now().Add(i.retryDelay(attempts))
+		if err := i.Redis.ZAdd(ctx, feedRetryKey, redis.Z{Score: float64(next.Unix()), Member: member}).Err(); err != nil {
+			global.Logger.Errorf("保存待重试击毁邮件失败, killID: %v, error: %v", killID, err)
+			return
+		}
+	}
+
+	if attempts > feedRetryMaxAttempts {
+		global.Logger.Errorf("击毁邮件重试%d次仍失败，放弃, killID: %v", feedRetryMaxAttempts, killID)
+		i.removeRetry(ctx, member)
+	}
+}
+
+// dueRetries 获取已到重试时间的击毁邮件
+func (i *Ingester) dueRetries(ctx context.Context) ([]string, error) {
+	now := time.Now()
+	if i.Redis == nil {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		var members []string
+		for member, entry := range i.retries {
+			if !entry.next.After(now) && len(members) < feedRetryBatch {
+				members = append(members, member)
+			}
+		}
+		return members, nil
+	}
+
+	return i.Redis.ZRangeByScore(ctx, feedRetryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: feedRetryBatch,
+	}).Result()
+}
+
+func (i *Ingester) removeRetry(ctx context.Context, member string) {
+	if i.Redis == nil {
+		i.mu.Lock()
+		delete(i.retries, member)
+		i.mu.Unlock()
+		return
+	}
+	i.Redis.ZRem(ctx, feedRetryKey, member)
+	i.Redis.HDel(ctx, feedRetryAttemptsKey, member)
+}
+
+// retryDelay 第attempts次重试前的等待时间
+func (i *Ingester) retryDelay(attempts int) time.Duration {
+	delay := i.RetryDelay
+	for n := 1; n < attempts && delay < feedRetryMaxDelay; n++ {
+		delay *= 2
+	}
+	return min(delay, feedRetryMaxDelay)
+}
+
+func retryMember(killID int, hash string) string {
+	return fmt.Sprintf("%d:%s", killID, hash)
+}
+
+func parseRetryMember(member string) (int, string, bool) {
+	idStr, hash, ok := strings.Cut(member, ":")
+	if !ok || hash == "" {
+		return 0, "", false
+	}
+	killID, err := strconv.Atoi(idStr)
+	if err != nil || killID <= 0 {
+		return 0, "", false
+	}
+	return killID, hash, true
+}
+
+// allowedIDs 获取关注的公司/联盟ID，定期刷新
+func (i *Ingester) allowedIDs() (map[int]bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.allowed != nil && time.Now().Before(i.refreshAt) {
+		return i.allowed, nil
+	}
+
+	ids, err := i.AllowedIDs()
+	if err != nil {
+		if i.allowed != nil {
+			return i.allowed, nil
+		}
+		return nil, err
+	}
+
+	i.allowed = make(map[int]bool, len(ids))
+	for _, id := range ids {
+		i.allowed[id] = true
+	}
+	i.refreshAt = time.Now().Add(allowedIDsRefreshTTL)
+	return i.allowed, nil
+}
+
+// involves 受害者或任一攻击者属于关注的公司/联盟
+func involves(km *esi.Killmail, allowed map[int]bool) bool {
+	if allowed[km.Victim.CorporationID] || allowed[km.Victim.AllianceID] {
+		return true
+	}
+	for _, attacker := range km.Attackers {
+		if allowed[attacker.CorporationID] || allowed[attacker.AllianceID] {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedCorpIDs 读取系统设置allowed_corp_list，公司和联盟ID都可填写
+func allowedCorpIDs() ([]int, error) {
+	corpList, err := global.Settings.Get("allowed_corp_list")
+	if err != nil {
+		return nil, err
+	}
+	corpIDs, err := utils.StringToIntList(corpList)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(corpIDs))
+	for _, id := range corpIDs {
+		ids = append(ids, int(id))
+	}
+	return ids, nil
+}
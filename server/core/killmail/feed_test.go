@@ -0,0 +1,192 @@
+package killmail
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// otherKillmailID 与关注的公司/联盟无关的击毁邮件
+const otherKillmailID = 123456790
+
+// killmailPath 击毁邮件的ESI路径，用于Hits和FailNext
+func killmailPath(killmailID int, hash string) string {
+	return fmt.Sprintf("/killmails/%d/%s/", killmailID, hash)
+}
+
+// setupFeed 启动模拟服务和内存数据库，关注内置击毁邮件的攻击方公司，并添加一条无关的击毁邮件
+func setupFeed(t *testing.T) (*esitest.Server, *esitest.MemoryCache[esi.CachedResponse]) {
+	t.Helper()
+
+	esitest.UseDB(t, &character.KillmailList{}, &character.KillmailItem{}, &character.UserCharacter{})
+
+	srv := esitest.NewServerWithFixtures()
+	restore := srv.Install()
+	responseCache := esitest.NewMemoryCache[esi.CachedResponse](0)
+	esi.EsiClient.SetCache(responseCache)
+
+	body, err := esitest.Fixtures.ReadFile(fmt.Sprintf("fixtures/killmails/%d_%s.json", esitest.FixtureKillmailID, esitest.FixtureKillmailHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := strings.NewReplacer(
+		fmt.Sprint(esitest.FixtureKillmailID), fmt.Sprint(otherKillmailID),
+		"98000001", "98000003",
+		"98000002", "98000004",
+		"99000001", "99000003",
+		"99000002", "99000004",
+	).Replace(string(body))
+	srv.AddKillmail(otherKillmailID, esitest.FixtureKillmailHash, []byte(other))
+
+	t.Cleanup(func() {
+		restore()
+		srv.Close()
+	})
+	return srv, responseCache
+}
+
+func newTestIngester(feed string) *Ingester {
+	ingester := NewIngester(feed, "esitest", nil)
+	ingester.AllowedIDs = func() ([]int, error) { return []int{98000001}, nil }
+	ingester.RetryInterval = 10 * time.Millisecond
+	ingester.RetryDelay = 10 * time.Millisecond
+	return ingester
+}
+
+// runIngester 后台运行监听，测试结束时停止
+func runIngester(t *testing.T, ingester *Ingester) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ingester.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func killmailSaved(killmailID int) bool {
+	var count int64
+	global.Db.Model(&character.KillmailList{}).Where("kill_mail_id = ?", killmailID).Count(&count)
+	return count > 0
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func cachedKillmail(responseCache *esitest.MemoryCache[esi.CachedResponse], killmailID int) bool {
+	for _, v := range responseCache.Values() {
+		if strings.Contains(string(v.Body), fmt.Sprintf(`"killmail_id":%d`, killmailID)) ||
+			strings.Contains(string(v.Body), fmt.Sprintf(`"killmail_id": %d`, killmailID)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIngesterRedisQ(t *testing.T) {
+	srv, responseCache := setupFeed(t)
+	srv.PushRedisQ(otherKillmailID, esitest.FixtureKillmailHash, false)
+	srv.PushRedisQ(esitest.FixtureKillmailID, esitest.FixtureKillmailHash, false)
+
+	runIngester(t, newTestIngester(FeedRedisQ))
+
+	waitFor(t, "保存相关击毁邮件", func() bool { return killmailSaved(esitest.FixtureKillmailID) })
+	if killmailSaved(otherKillmailID) {
+		t.Error("无关的击毁邮件不应保存")
+	}
+	if srv.Hits(killmailPath(otherKillmailID, esitest.FixtureKillmailHash)) != 1 {
+		t.Error("RedisQ推送不带击毁邮件时应从ESI获取一次用于过滤")
+	}
+	if cachedKillmail(responseCache, otherKillmailID) {
+		t.Error("无关的击毁邮件不应进入HTTP缓存")
+	}
+	if !cachedKillmail(responseCache, esitest.FixtureKillmailID) {
+		t.Error("保存的击毁邮件应进入HTTP缓存")
+	}
+}
+
+func TestIngesterRetry(t *testing.T) {
+	srv, _ := setupFeed(t)
+	path := killmailPath(esitest.FixtureKillmailID, esitest.FixtureKillmailHash)
+	srv.FailNext(path, 404, 1)
+	srv.PushRedisQ(esitest.FixtureKillmailID, esitest.FixtureKillmailHash, false)
+
+	ingester := newTestIngester(FeedRedisQ)
+	runIngester(t, ingester)
+
+	waitFor(t, "重试保存击毁邮件", func() bool { return killmailSaved(esitest.FixtureKillmailID) })
+	waitFor(t, "清空重试集合", func() bool {
+		pending, err := ingester.PendingRetries(context.Background())
+		return err == nil && pending == 0
+	})
+	if hits := srv.Hits(path); hits < 2 {
+		t.Errorf("击毁邮件请求次数 = %d, 应在失败后重试", hits)
+	}
+}
+
+func TestIngesterWebsocket(t *testing.T) {
+	srv, _ := setupFeed(t)
+
+	runIngester(t, newTestIngester(FeedWebsocket))
+
+	waitFor(t, "订阅频道", func() bool { return srv.Subscribed(esi.ZkbCorporationChannel(98000001)) })
+	if srv.Subscribed(esi.ZkbCorporationChannel(98000003)) {
+		t.Error("不应订阅未关注的公司")
+	}
+	if n := srv.PushWebsocket(otherKillmailID, esitest.FixtureKillmailHash); n != 0 {
+		t.Errorf("无关的击毁邮件推送到了%d个连接", n)
+	}
+	if n := srv.PushWebsocket(esitest.FixtureKillmailID, esitest.FixtureKillmailHash); n != 1 {
+		t.Fatalf("相关击毁邮件推送到了%d个连接, want 1", n)
+	}
+
+	waitFor(t, "保存相关击毁邮件", func() bool { return killmailSaved(esitest.FixtureKillmailID) })
+	if srv.Hits(killmailPath(otherKillmailID, esitest.FixtureKillmailHash)) != 0 {
+		t.Error("websocket只推送订阅频道，不应请求无关的击毁邮件")
+	}
+}
+
+func TestIngesterWebsocketCatchUp(t *testing.T) {
+	srv, _ := setupFeed(t)
+
+	var catchUps atomic.Int32
+	ingester := newTestIngester(FeedWebsocket)
+	ingester.CatchUp = func(ctx context.Context) error {
+		catchUps.Add(1)
+		return nil
+	}
+	runIngester(t, ingester)
+
+	channel := esi.ZkbCorporationChannel(98000001)
+	waitFor(t, "订阅频道", func() bool { return srv.Subscribed(channel) })
+	if n := catchUps.Load(); n != 0 {
+		t.Fatalf("首次连接不应补齐, CatchUp调用%d次", n)
+	}
+
+	if n := srv.DropWebsockets(); n != 1 {
+		t.Fatalf("断开了%d个连接, want 1", n)
+	}
+	waitFor(t, "重连后补齐", func() bool { return catchUps.Load() == 1 })
+	if !srv.Subscribed(channel) {
+		t.Error("重连后应重新订阅频道")
+	}
+}
@@ -0,0 +1,119 @@
+package killmail
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	characterRepo "eve-corp-manager/repository/service/character"
+	"eve-corp-manager/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Creator 击毁邮件的提交者，自动任务使用零值
+type Creator struct {
+	ID   uint
+	Name string
+}
+
+// Save 获取、解析并保存击毁邮件，同一KillMailID只保存一次，已存在时直接返回已保存的记录
+func Save(ctx context.Context, killMailID int, killMailHash string, creator Creator) (*character.KillmailList, error) {
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	killmail, stored, err := Load(ctx, killMailID, killMailHash, creator)
+	if err != nil || stored {
+		return killmail, err
+	}
+
+	created, err := killmailRepo.Create(killmail)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		// 并发保存时以先写入的记录为准
+		return killmailRepo.Get(killMailID)
+	}
+
+	global.Logger.Infof("保存击毁邮件成功, killMailID: %v, 角色: %v, 估价: %.2f", killMailID, killmail.CharacterName, killmail.JaniceAmount)
+	return killmail, nil
+}
+
+// Load 返回已保存的击毁邮件，不存在时获取并解析但不写入数据库，stored表示是否为已保存的记录
+func Load(ctx context.Context, killMailID int, killMailHash string, creator Creator) (killmail *character.KillmailList, stored bool, err error) {
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	if killmail, err := killmailRepo.Get(killMailID); err == nil {
+		return killmail, true, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	details := utils.NewKillmailDetails(killMailID, killMailHash)
+	if err := details.Init(ctx); err != nil {
+		return nil, false, err
+	}
+
+	killmail, err = newKillmailList(details, creator)
+	if err != nil {
+		return nil, false, err
+	}
+	return killmail, false, nil
+}
+
+// newKillmailList 将解析结果转换为数据库模型，受害角色已绑定时关联到对应用户
+func newKillmailList(details *utils.KillmailDetails, creator Creator) (*character.KillmailList, error) {
+	killMailTime, err := time.Parse(time.RFC3339, details.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	killmail := &character.KillmailList{
+		KillMailID:      details.KillmailID,
+		KillMailHash:    details.KillmailHash,
+		KillMailTime:    killMailTime,
+		SolarSystemID:   details.SolarSystemID,
+		SolarSystemName: details.SolarSystemName,
+		ShipTypeID:      details.ShipTypeID,
+		ShipTypeName:    details.ShipTypeName,
+		CharacterID:     details.CharacterID,
+		CharacterName:   details.CharacterName,
+		CorporationID:   details.CorporationID,
+		CorporationName: details.CorporationName,
+		AllianceID:      details.AllianceID,
+		AllianceName:    details.AllianceName,
+		JaniceAmount:    details.JaniceAmount,
+		CreateBy:        creator.Name,
+		CreateID:        creator.ID,
+	}
+
+	if details.CharacterID > 0 {
+		userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+		if userCharacter, err := userCharacterRepo.Get(uint(details.CharacterID)); err == nil {
+			killmail.UserID = userCharacter.UserID
+		}
+	}
+
+	killmail.Items = make([]character.KillmailItem, 0, len(details.Items))
+	var appendItems func(items []utils.KillmailItem, parentIndex int)
+	appendItems = func(items []utils.KillmailItem, parentIndex int) {
+		for _, item := range items {
+			itemIndex := len(killmail.Items) + 1
+			killmail.Items = append(killmail.Items, character.KillmailItem{
+				KillMailID:  details.KillmailID,
+				ItemID:      item.ItemID,
+				ItemName:    item.ItemName,
+				ItemNum:     item.ItemNum,
+				DropType:    item.DropType,
+				SlotType:    item.SlotType,
+				ItemIndex:   itemIndex,
+				ParentIndex: parentIndex,
+				CreateBy:    creator.Name,
+				CreateID:    creator.ID,
+			})
+			appendItems(item.Items, itemIndex)
+		}
+	}
+	appendItems(details.Items, 0)
+
+	return killmail, nil
+}
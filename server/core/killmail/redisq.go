@@ -0,0 +1,98 @@
+package killmail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/global"
+	"eve-corp-manager/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisqQueueIDKey = "zkb:redisq:queue_id" // 自动生成的RedisQ队列ID
+
+	redisqTTW = 10 // 队列为空时RedisQ的等待秒数
+)
+
+// runRedisQ 监听RedisQ直到ctx取消，RedisQ不可用时按指数退避重试
+// RedisQ推送全部击毁邮件，需要逐条从ESI获取后过滤
+func (i *Ingester) runRedisQ(ctx context.Context) error {
+	queueID, err := i.queueID(ctx)
+	if err != nil {
+		return err
+	}
+	global.Logger.Infof("开始监听zKillboard RedisQ, queueID: %v", queueID)
+
+	backoff := feedMinBackoff
+	for ctx.Err() == nil {
+		pkg, err := esi.ListenRedisQ(ctx, queueID, redisqTTW)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			wait := backoff
+			var redisqErr *esi.RedisQError
+			if errors.As(err, &redisqErr) && redisqErr.RetryAfter > wait {
+				wait = redisqErr.RetryAfter
+			}
+			global.Logger.Warnf("RedisQ请求失败，%v后重试: %v", wait, err)
+			if !utils.SleepContext(ctx, wait) {
+				break
+			}
+			backoff = min(backoff*2, feedMaxBackoff)
+			continue
+		}
+		backoff = feedMinBackoff
+
+		if pkg != nil {
+			i.handle(ctx, pkg)
+		}
+	}
+
+	global.Logger.Infof("停止监听zKillboard RedisQ")
+	return ctx.Err()
+}
+
+// queueID 获取队列ID，未配置时使用Redis中保存的ID，不存在则生成
+func (i *Ingester) queueID(ctx context.Context) (string, error) {
+	if i.QueueID != "" {
+		return i.QueueID, nil
+	}
+	if i.Redis == nil {
+		i.QueueID = newQueueID()
+		return i.QueueID, nil
+	}
+
+	queueID, err := i.Redis.Get(ctx, redisqQueueIDKey).Result()
+	if err == nil && queueID != "" {
+		i.QueueID = queueID
+		return queueID, nil
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	queueID = newQueueID()
+	ok, err := i.Redis.SetNX(ctx, redisqQueueIDKey, queueID, 0).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// 其他实例已生成
+		if queueID, err = i.Redis.Get(ctx, redisqQueueIDKey).Result(); err != nil {
+			return "", err
+		}
+	}
+	i.QueueID = queueID
+	return queueID, nil
+}
+
+func newQueueID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "eve-corp-manager-" + hex.EncodeToString(buf)
+}
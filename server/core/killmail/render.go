@@ -0,0 +1,64 @@
+package killmail
+
+import (
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/utils"
+	"time"
+)
+
+// Details 将已保存的击毁邮件还原为详情，物品、舰船和分组名称翻译为指定语言，不修改数据库
+func Details(killmail *character.KillmailList, lang string) *utils.KillmailDetails {
+	details := toDetails(killmail)
+	details.Localize(lang)
+	return details
+}
+
+// UIJSON 根据已保存的击毁邮件生成指定语言的UI数据
+func UIJSON(killmail *character.KillmailList, lang string) map[string]interface{} {
+	return Details(killmail, lang).UIJSON
+}
+
+// toDetails 将已保存的击毁邮件还原为解析结果，按ParentIndex重建容器层级
+func toDetails(killmail *character.KillmailList) *utils.KillmailDetails {
+	details := utils.NewKillmailDetails(killmail.KillMailID, killmail.KillMailHash)
+	details.Time = killmail.KillMailTime.UTC().Format(time.RFC3339)
+	details.SolarSystemID = killmail.SolarSystemID
+	details.SolarSystemName = killmail.SolarSystemName
+	details.ShipTypeID = killmail.ShipTypeID
+	details.ShipTypeName = killmail.ShipTypeName
+	details.CharacterID = killmail.CharacterID
+	details.CharacterName = killmail.CharacterName
+	details.CorporationID = killmail.CorporationID
+	details.CorporationName = killmail.CorporationName
+	details.AllianceID = killmail.AllianceID
+	details.AllianceName = killmail.AllianceName
+	details.JaniceAmount = killmail.JaniceAmount
+
+	children := make(map[int][]character.KillmailItem)
+	for _, item := range killmail.Items {
+		children[item.ParentIndex] = append(children[item.ParentIndex], item)
+	}
+
+	var build func(parentIndex int) []utils.KillmailItem
+	build = func(parentIndex int) []utils.KillmailItem {
+		items := make([]utils.KillmailItem, 0, len(children[parentIndex]))
+		for _, item := range children[parentIndex] {
+			entry := utils.KillmailItem{
+				ItemID:   item.ItemID,
+				ItemName: item.ItemName,
+				ItemNum:  item.ItemNum,
+				DropType: item.DropType,
+				SlotType: item.SlotType,
+			}
+			// 旧数据没有序号，全部视为顶层物品
+			if item.ItemIndex > 0 {
+				entry.Items = build(item.ItemIndex)
+			}
+			items = append(items, entry)
+		}
+		return items
+	}
+	details.Items = build(0)
+
+	return details
+}
@@ -0,0 +1,96 @@
+package killmail
+
+import (
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/models/sde"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/utils"
+	"testing"
+)
+
+// setupRenderSde 写入渲染测试使用的物品、分组和中文翻译
+func setupRenderSde(t *testing.T) {
+	t.Helper()
+	sdeDb := esitest.UseSdeDB(t, &sde.InvType{}, &sde.InvGroup{}, &sde.InvCategory{}, &sde.TrnTranslation{})
+	sdeDb.Create(&[]sde.InvCategory{
+		{CategoryID: 4, CategoryName: "Material"},
+		{CategoryID: 6, CategoryName: "Ship"},
+		{CategoryID: 2, CategoryName: "Celestial"},
+	})
+	sdeDb.Create(&[]sde.InvGroup{
+		{GroupID: 18, CategoryID: 4, GroupName: "Mineral"},
+		{GroupID: 25, CategoryID: 6, GroupName: "Frigate"},
+		{GroupID: 340, CategoryID: 2, GroupName: "Secure Cargo Container"},
+	})
+	sdeDb.Create(&[]sde.InvType{
+		{TypeID: 34, GroupID: 18, TypeName: "Tritanium"},
+		{TypeID: 587, GroupID: 25, TypeName: "Rifter"},
+		{TypeID: 3467, GroupID: 340, TypeName: "Small Secure Container"},
+	})
+	sdeDb.Create(&[]sde.TrnTranslation{
+		{TcID: sde.NameTcID, KeyID: 587, LanguageID: "zh", Text: "裂谷级"},
+		{TcID: sde.GroupTcID, KeyID: 25, LanguageID: "zh", Text: "护卫舰"},
+		{TcID: sde.NameTcID, KeyID: 34, LanguageID: "zh", Text: "三钛合金"},
+	})
+}
+
+// storedKillmail 已保存的击毁邮件，货柜仓中有一个装着三钛合金的安全货柜
+func storedKillmail() *character.KillmailList {
+	return &character.KillmailList{
+		KillMailID:   esitest.FixtureKillmailID,
+		KillMailHash: esitest.FixtureKillmailHash,
+		ShipTypeID:   587,
+		ShipTypeName: "Rifter",
+		Items: []character.KillmailItem{
+			{ItemID: 3467, ItemName: "Small Secure Container", ItemNum: 1, SlotType: utils.CargoSlot, ItemIndex: 1},
+			{ItemID: 34, ItemName: "Tritanium", ItemNum: 1000, SlotType: utils.CargoSlot, ItemIndex: 2, ParentIndex: 1},
+		},
+	}
+}
+
+func TestDetails(t *testing.T) {
+	setupRenderSde(t)
+
+	tests := []struct {
+		lang          string
+		wantShip      string
+		wantShipGroup string
+		wantChild     string
+	}{
+		{"zh", "裂谷级", "护卫舰", "三钛合金"},
+		{"zh-CN", "裂谷级", "护卫舰", "三钛合金"},
+		{"en", "Rifter", "Frigate", "Tritanium"}, // 没有翻译时使用英文名称
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			details := Details(storedKillmail(), tt.lang)
+
+			if details.ShipTypeName != tt.wantShip || details.ShipGroupName != tt.wantShipGroup || details.ShipGroupID != 25 {
+				t.Errorf("ship = %s %s(%d), want %s %s(25)", details.ShipTypeName, details.ShipGroupName, details.ShipGroupID, tt.wantShip, tt.wantShipGroup)
+			}
+			if len(details.Items) != 1 || len(details.Items[0].Items) != 1 {
+				t.Fatalf("items = %+v, want one container with one item", details.Items)
+			}
+			container, child := details.Items[0], details.Items[0].Items[0]
+			if container.GroupName != "Secure Cargo Container" || container.CategoryName != "Celestial" {
+				t.Errorf("container group = %s/%s", container.GroupName, container.CategoryName)
+			}
+			if child.ItemName != tt.wantChild || child.GroupName != "Mineral" || child.GroupID != 18 {
+				t.Errorf("child = %s %s(%d), want %s Mineral(18)", child.ItemName, child.GroupName, child.GroupID, tt.wantChild)
+			}
+			if details.UIJSON["shipName"] != tt.wantShip {
+				t.Errorf("ui shipName = %v, want %s", details.UIJSON["shipName"], tt.wantShip)
+			}
+		})
+	}
+}
+
+func TestDetailsDoesNotModifyKillmail(t *testing.T) {
+	setupRenderSde(t)
+
+	stored := storedKillmail()
+	Details(stored, "zh")
+	if stored.ShipTypeName != "Rifter" || stored.Items[1].ItemName != "Tritanium" {
+		t.Errorf("stored killmail modified: %s, %s", stored.ShipTypeName, stored.Items[1].ItemName)
+	}
+}
@@ -0,0 +1,206 @@
+package killmail
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/token"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	characterRepo "eve-corp-manager/repository/service/character"
+	"eve-corp-manager/utils"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 读取击毁邮件所需的ESI权限
+const (
+	ScopeCharacterKillmails   = "esi-killmails.read_killmails.v1"
+	ScopeCorporationKillmails = "esi-killmails.read_corporation_killmails.v1"
+)
+
+// KillmailRef /killmails/recent/ 返回的击毁邮件ID和hash
+type KillmailRef struct {
+	KillmailID   int    `json:"killmail_id"`
+	KillmailHash string `json:"killmail_hash"`
+}
+
+// SyncResult 一次同步的结果
+type SyncResult struct {
+	Total   int // ESI返回的击毁邮件数量
+	Saved   int // 新保存的数量
+	Skipped int // 已存在的数量
+	Failed  int // 获取或保存失败的数量
+}
+
+// SyncRecent 从ESI读取公司/角色最近的击毁邮件，保存尚未入库的记录并更新同步进度
+// 公司需要总监角色的令牌，characterID为令牌所属角色
+func SyncRecent(ctx context.Context, ownerType string, ownerID, characterID uint, tokenSource esi.TokenSource) (SyncResult, error) {
+	var result SyncResult
+
+	var path string
+	switch ownerType {
+	case character.KillmailOwnerCorporation:
+		path = fmt.Sprintf("/corporations/%d/killmails/recent/", ownerID)
+	case character.KillmailOwnerCharacter:
+		path = fmt.Sprintf("/characters/%d/killmails/recent/", ownerID)
+	default:
+		return result, fmt.Errorf("未知的同步对象类型: %s", ownerType)
+	}
+
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	cursor, err := killmailRepo.GetSyncCursor(ownerType, ownerID)
+	if err != nil {
+		return result, err
+	}
+	cursor.CharacterID = characterID
+
+	refs, err := esi.AuthorizedGetAllPages[KillmailRef](ctx, esi.EsiClient, path, nil, tokenSource)
+	if err != nil {
+		cursor.LastError = truncate(err.Error(), 512)
+		if saveErr := killmailRepo.SaveSyncCursor(cursor); saveErr != nil {
+			return result, errors.Join(err, saveErr)
+		}
+		return result, err
+	}
+	result.Total = len(refs)
+
+	ids := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, ref.KillmailID)
+	}
+	existing, err := killmailRepo.ExistingIDs(ids)
+	if err != nil {
+		return result, err
+	}
+
+	// 从旧到新保存，进度只推进到第一条失败之前，失败的击毁邮件在下次同步时重试
+	slices.SortFunc(refs, func(a, b KillmailRef) int { return cmp.Compare(a.KillmailID, b.KillmailID) })
+	var lastErr error
+	for _, ref := range refs {
+		if existing[ref.KillmailID] {
+			result.Skipped++
+		} else if _, err := Save(ctx, ref.KillmailID, ref.KillmailHash, Creator{}); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			global.Logger.Errorf("同步击毁邮件失败, killMailID: %v, error: %v", ref.KillmailID, err)
+			result.Failed++
+			lastErr = err
+			continue
+		} else {
+			result.Saved++
+		}
+		if lastErr == nil {
+			cursor.LastKillmailID = max(cursor.LastKillmailID, ref.KillmailID)
+		}
+	}
+
+	now := time.Now()
+	cursor.LastSyncTime = &now
+	cursor.SyncedCount += result.Saved
+	cursor.LastError = ""
+	if lastErr != nil {
+		cursor.LastError = truncate(lastErr.Error(), 512)
+	}
+	if err := killmailRepo.SaveSyncCursor(cursor); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// SyncAll 同步allowed_corp_list中所有公司和已授权角色的击毁邮件
+// 公司依次尝试本公司拥有公司击毁邮件权限的角色，直到某个角色的令牌可用(需要总监角色)
+func SyncAll(ctx context.Context) error {
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	characters, err := userCharacterRepo.GetAllInAllowedCorp()
+	if err != nil {
+		return err
+	}
+
+	corpIDs, err := allowedCorpIDs()
+	if err != nil {
+		global.Logger.Errorf("读取allowed_corp_list失败，只同步角色击毁邮件: %v", err)
+	}
+
+	directors := make(map[uint][]character.UserCharacter)
+	for _, char := range characters {
+		if char.Status == character.CharacterStatusInvalid {
+			continue
+		}
+		if hasScope(char.Scopes, ScopeCorporationKillmails) {
+			directors[char.CorpID] = append(directors[char.CorpID], char)
+		}
+		if !hasScope(char.Scopes, ScopeCharacterKillmails) {
+			continue
+		}
+
+		result, err := SyncRecent(ctx, character.KillmailOwnerCharacter, char.CharacterID, char.CharacterID, token.ESITokens.Source(char.CharacterID))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			global.Logger.Warnf("同步角色击毁邮件失败, characterID: %v, error: %v", char.CharacterID, err)
+			continue
+		}
+		logSyncResult(character.KillmailOwnerCharacter, char.CharacterID, result)
+	}
+
+	for _, corpID := range corpIDs {
+		if len(directors[uint(corpID)]) == 0 {
+			global.Logger.Warnf("公司%v没有授权公司击毁邮件权限的角色，跳过同步", corpID)
+			continue
+		}
+		for _, director := range directors[uint(corpID)] {
+			result, err := SyncRecent(ctx, character.KillmailOwnerCorporation, uint(corpID), director.CharacterID, token.ESITokens.Source(director.CharacterID))
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				// 非总监角色返回403，换下一个角色
+				global.Logger.Warnf("使用角色%v同步公司%v击毁邮件失败: %v", director.CharacterID, corpID, err)
+				continue
+			}
+			logSyncResult(character.KillmailOwnerCorporation, uint(corpID), result)
+			break
+		}
+	}
+
+	return nil
+}
+
+// RunSync 按interval定时执行SyncAll，直到ctx取消
+func RunSync(ctx context.Context, interval time.Duration) error {
+	for {
+		if err := SyncAll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			global.Logger.Errorf("ESI击毁邮件同步失败: %v", err)
+		}
+		if !utils.SleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+func logSyncResult(ownerType string, ownerID uint, result SyncResult) {
+	if result.Saved > 0 || result.Failed > 0 {
+		global.Logger.Infof("ESI击毁邮件同步完成, %v: %v, 共%v条, 新增%v条, 失败%v条",
+			ownerType, ownerID, result.Total, result.Saved, result.Failed)
+	}
+}
+
+// hasScope 判断以空格分隔的权限列表中是否包含scope
+func hasScope(scopes, scope string) bool {
+	return slices.Contains(strings.Fields(scopes), scope)
+}
+
+// truncate 按字符截断，避免超出字段长度
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
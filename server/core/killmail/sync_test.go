@@ -0,0 +1,111 @@
+package killmail
+
+import (
+	"context"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	corpID           = 98000001
+	storedKillmailID = 123456780 // 同步前已保存的击毁邮件
+	laterKillmailID  = 123456791 // 比失败的击毁邮件更新的击毁邮件
+	recentPath       = "/corporations/98000001/killmails/recent/"
+)
+
+// setupSync 在setupFeed的基础上添加同步进度表、一条已保存的击毁邮件和一条更新的击毁邮件
+func setupSync(t *testing.T) *esitest.Server {
+	t.Helper()
+	srv, _ := setupFeed(t)
+	if err := global.Db.AutoMigrate(&character.KillmailSyncCursor{}); err != nil {
+		t.Fatal(err)
+	}
+	global.Db.Create(&character.KillmailList{KillMailID: storedKillmailID, KillMailHash: esitest.FixtureKillmailHash})
+
+	body, err := esitest.Fixtures.ReadFile(fmt.Sprintf("fixtures/killmails/%d_%s.json", esitest.FixtureKillmailID, esitest.FixtureKillmailHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := strings.ReplaceAll(string(body), fmt.Sprint(esitest.FixtureKillmailID), fmt.Sprint(laterKillmailID))
+	srv.AddKillmail(laterKillmailID, esitest.FixtureKillmailHash, []byte(later))
+
+	// ESI不保证顺序
+	var refs []KillmailRef
+	for _, id := range []int{laterKillmailID, storedKillmailID, otherKillmailID, esitest.FixtureKillmailID} {
+		refs = append(refs, KillmailRef{KillmailID: id, KillmailHash: esitest.FixtureKillmailHash})
+	}
+	srv.SetJSON(recentPath, refs)
+	return srv
+}
+
+// syncCorp 同步测试公司并返回同步后的进度
+func syncCorp(t *testing.T) (SyncResult, *character.KillmailSyncCursor, error) {
+	t.Helper()
+	result, err := SyncRecent(context.Background(), character.KillmailOwnerCorporation, corpID, 2112000001, esi.StaticToken("esitest"))
+
+	var cursor character.KillmailSyncCursor
+	if err := global.Db.Where("owner_type = ? AND owner_id = ?", character.KillmailOwnerCorporation, corpID).Limit(1).Find(&cursor).Error; err != nil {
+		t.Fatal(err)
+	}
+	return result, &cursor, err
+}
+
+func TestSyncRecent(t *testing.T) {
+	srv := setupSync(t)
+	srv.FailNext(killmailPath(otherKillmailID, esitest.FixtureKillmailHash), http.StatusUnprocessableEntity, 1)
+
+	// 第一次同步：otherKillmailID失败，进度停在它之前，之后的击毁邮件仍然保存
+	result, cursor, err := syncCorp(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SyncResult{Total: 4, Saved: 2, Skipped: 1, Failed: 1}); result != want {
+		t.Errorf("first result = %+v, want %+v", result, want)
+	}
+	if cursor.LastKillmailID != esitest.FixtureKillmailID {
+		t.Errorf("first cursor = %d, want %d", cursor.LastKillmailID, esitest.FixtureKillmailID)
+	}
+	if cursor.LastError == "" || cursor.SyncedCount != 2 || cursor.LastSyncTime == nil {
+		t.Errorf("first cursor = %+v", cursor)
+	}
+	if !killmailSaved(laterKillmailID) || killmailSaved(otherKillmailID) {
+		t.Error("失败之后的击毁邮件应保存，失败的击毁邮件不应保存")
+	}
+	if hits := srv.Hits(killmailPath(storedKillmailID, esitest.FixtureKillmailHash)); hits != 0 {
+		t.Errorf("已保存的击毁邮件请求了%d次ESI", hits)
+	}
+
+	// 第二次同步：重试失败的击毁邮件，进度推进到最新
+	result, cursor, err = syncCorp(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SyncResult{Total: 4, Saved: 1, Skipped: 3}); result != want {
+		t.Errorf("second result = %+v, want %+v", result, want)
+	}
+	if cursor.LastKillmailID != laterKillmailID || cursor.LastError != "" || cursor.SyncedCount != 3 {
+		t.Errorf("second cursor = %+v, want last %d without error", cursor, laterKillmailID)
+	}
+	if hits := srv.Hits(killmailPath(esitest.FixtureKillmailID, esitest.FixtureKillmailHash)); hits != 1 {
+		t.Errorf("已保存的击毁邮件重复请求ESI, hits = %d", hits)
+	}
+}
+
+func TestSyncRecentFetchError(t *testing.T) {
+	srv := setupSync(t)
+	srv.FailNext(recentPath, http.StatusForbidden, 1)
+
+	result, cursor, err := syncCorp(t)
+	if err == nil {
+		t.Fatalf("SyncRecent() = %+v, want error", result)
+	}
+	if cursor.ID == 0 || cursor.LastError == "" || cursor.LastKillmailID != 0 || cursor.LastSyncTime != nil {
+		t.Errorf("cursor = %+v, want saved with error only", cursor)
+	}
+}
@@ -0,0 +1,138 @@
+package killmail
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/global"
+	"eve-corp-manager/utils"
+	"time"
+)
+
+// websocketStableTime 连接保持超过该时间后断开时重置退避时间
+const websocketStableTime = time.Minute
+
+// runWebsocket 通过zKillboard websocket订阅关注的公司/联盟频道直到ctx取消，断开时按指数退避重连
+// 重连并订阅成功后执行一次CatchUp，补齐断线期间错过的击毁邮件
+func (i *Ingester) runWebsocket(ctx context.Context) error {
+	global.Logger.Infof("开始监听zKillboard websocket")
+
+	backoff := feedMinBackoff
+	for reconnect := false; ctx.Err() == nil; reconnect = true {
+		start := time.Now()
+		err := i.listenWebsocket(ctx, reconnect)
+		if ctx.Err() != nil {
+			break
+		}
+		if time.Since(start) > websocketStableTime {
+			backoff = feedMinBackoff
+		}
+		global.Logger.Warnf("zKillboard websocket连接断开，%v后重连: %v", backoff, err)
+		if !utils.SleepContext(ctx, backoff) {
+			break
+		}
+		backoff = min(backoff*2, feedMaxBackoff)
+	}
+
+	global.Logger.Infof("停止监听zKillboard websocket")
+	return ctx.Err()
+}
+
+// listenWebsocket 建立连接并订阅频道，读取推送直到连接断开，关注列表变化时调整订阅
+// catchUp为true时订阅成功后在后台执行CatchUp
+func (i *Ingester) listenWebsocket(ctx context.Context, catchUp bool) error {
+	allowed, err := i.allowedIDs()
+	if err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return errors.New("未设置关注的公司/联盟")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ws, err := esi.DialZkbWebsocket(ctx)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	subscribed := make(map[string]bool)
+	if err := syncChannels(ws, subscribed, allowed); err != nil {
+		return err
+	}
+	if catchUp {
+		go i.catchUp(ctx)
+	}
+
+	go func() {
+		ticker := time.NewTicker(allowedIDsRefreshTTL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				allowed, err := i.allowedIDs()
+				if err == nil {
+					err = syncChannels(ws, subscribed, allowed)
+				}
+				if err != nil {
+					global.Logger.Warnf("更新zKillboard websocket订阅失败: %v", err)
+					ws.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		pkg, err := ws.Next()
+		if err != nil {
+			return err
+		}
+		i.handle(ctx, pkg)
+	}
+}
+
+// catchUp 执行CatchUp，上一次尚未完成时跳过
+func (i *Ingester) catchUp(ctx context.Context) {
+	if i.CatchUp == nil || !i.catchingUp.CompareAndSwap(false, true) {
+		return
+	}
+	defer i.catchingUp.Store(false)
+
+	global.Logger.Infof("zKillboard websocket已重连，通过ESI同步补齐断线期间的击毁邮件")
+	if err := i.CatchUp(ctx); err != nil && ctx.Err() == nil {
+		global.Logger.Errorf("补齐断线期间的击毁邮件失败: %v", err)
+	}
+}
+
+// syncChannels 订阅关注ID对应的频道并取消不再关注的频道，allowed中的ID可能是公司或联盟，两种频道都订阅
+func syncChannels(ws *esi.ZkbWebsocket, subscribed map[string]bool, allowed map[int]bool) error {
+	channels := make(map[string]bool, len(allowed)*2)
+	for id := range allowed {
+		channels[esi.ZkbCorporationChannel(id)] = true
+		channels[esi.ZkbAllianceChannel(id)] = true
+	}
+
+	for channel := range channels {
+		if subscribed[channel] {
+			continue
+		}
+		if err := ws.Subscribe(channel); err != nil {
+			return err
+		}
+		subscribed[channel] = true
+	}
+	for channel := range subscribed {
+		if channels[channel] {
+			continue
+		}
+		if err := ws.Unsubscribe(channel); err != nil {
+			return err
+		}
+		delete(subscribed, channel)
+	}
+	return nil
+}
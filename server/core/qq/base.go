@@ -2,6 +2,7 @@ package qq
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -52,29 +53,29 @@ type APIResponse struct {
 }
 
 // SendPrivateMsg 发送私聊消息
-func (c *Client) SendPrivateMsg(userID string, message interface{}, autoEscape bool) (*APIResponse, error) {
+func (c *Client) SendPrivateMsg(ctx context.Context, userID string, message interface{}, autoEscape bool) (*APIResponse, error) {
 	req := SendMessageRequest{
 		MessageType: MessageTypePrivate,
 		UserID:      userID,
 		Message:     message,
 		AutoEscape:  autoEscape,
 	}
-	return c.sendMsg(req)
+	return c.sendMsg(ctx, req)
 }
 
 // SendGroupMsg 发送群聊消息
-func (c *Client) SendGroupMsg(groupID string, message interface{}, autoEscape bool) (*APIResponse, error) {
+func (c *Client) SendGroupMsg(ctx context.Context, groupID string, message interface{}, autoEscape bool) (*APIResponse, error) {
 	req := SendMessageRequest{
 		MessageType: MessageTypeGroup,
 		GroupID:     groupID,
 		Message:     message,
 		AutoEscape:  autoEscape,
 	}
-	return c.sendMsg(req)
+	return c.sendMsg(ctx, req)
 }
 
 // 发送消息的内部方法
-func (c *Client) sendMsg(req SendMessageRequest) (*APIResponse, error) {
+func (c *Client) sendMsg(ctx context.Context, req SendMessageRequest) (*APIResponse, error) {
 	if c == nil {
 		return nil, errors.New("QQ client not initialized")
 	}
@@ -88,7 +89,7 @@ func (c *Client) sendMsg(req SendMessageRequest) (*APIResponse, error) {
 	}
 
 	// 创建HTTP请求
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
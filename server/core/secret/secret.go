@@ -0,0 +1,153 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix 密文前缀，格式为 enc:<密钥ID>:<base64(nonce+密文)>
+const prefix = "enc:"
+
+var (
+	ErrNoActiveKey = errors.New("未配置加密密钥")
+	ErrUnknownKey  = errors.New("未知的加密密钥ID")
+)
+
+// Keyring 加密密钥环，使用当前密钥加密，可使用任意已配置的密钥解密
+type Keyring struct {
+	activeID string
+	aeads    map[string]cipher.AEAD
+}
+
+// TokenKeyring 全局密钥环，用于加密ESI refresh token
+var TokenKeyring *Keyring
+
+// NewKeyring 创建密钥环，keys为密钥ID到32字节密钥的映射
+func NewKeyring(activeID string, keys map[string][]byte) (*Keyring, error) {
+	k := &Keyring{
+		activeID: activeID,
+		aeads:    make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("密钥ID不能为空且不能包含冒号: %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥%s长度必须为32字节", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+	}
+
+	if activeID != "" {
+		if _, ok := k.aeads[activeID]; !ok {
+			return nil, fmt.Errorf("当前密钥%s未配置", activeID)
+		}
+	}
+
+	return k, nil
+}
+
+// ParseKeys 解析 "id1:base64key,id2:base64key" 格式的密钥配置
+func ParseKeys(s string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("密钥格式错误，应为 id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥%s不是有效的base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ActiveKeyID 当前用于加密的密钥ID
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Encrypt 使用当前密钥加密
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead, ok := k.aeads[k.activeID]
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	// 以密钥ID作为附加数据，防止密文被挪用到其他密钥ID下
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.activeID))
+	return prefix + k.activeID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 根据密文中的密钥ID解密，非密文格式的值按明文原样返回以兼容历史数据
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	keyID, err := KeyID(value)
+	if err != nil {
+		return "", err
+	}
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(value[len(prefix)+len(keyID)+1:])
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID 获取密文使用的密钥ID
+func KeyID(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("不是密文")
+	}
+	keyID, _, found := strings.Cut(value[len(prefix):], ":")
+	if !found {
+		return "", errors.New("密文格式错误")
+	}
+	return keyID, nil
+}
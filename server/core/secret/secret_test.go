@@ -0,0 +1,170 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey 生成32字节的测试密钥
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func mustKeyring(t *testing.T, activeID string, keys map[string][]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(activeID, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := mustKeyring(t, "k1", map[string][]byte{"k1": testKey(1)})
+
+	first, err := k.Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := k.Encrypt("refresh-token")
+	if !strings.HasPrefix(first, "enc:k1:") || first == second {
+		t.Errorf("Encrypt() = %q, %q, want enc:k1: prefix and random nonce", first, second)
+	}
+	if plaintext, err := k.Decrypt(first); err != nil || plaintext != "refresh-token" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := mustKeyring(t, "k1", map[string][]byte{"k1": testKey(1)})
+	ciphertext, err := old.Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换期间新旧密钥都可解密，新密文使用新密钥
+	rotating := mustKeyring(t, "k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	if plaintext, err := rotating.Decrypt(ciphertext); err != nil || plaintext != "refresh-token" {
+		t.Errorf("Decrypt() with old key = %q, %v", plaintext, err)
+	}
+	reencrypted, err := rotating.Encrypt("refresh-token")
+	if err != nil || !strings.HasPrefix(reencrypted, "enc:k2:") {
+		t.Errorf("Encrypt() = %q, %v, want enc:k2: prefix", reencrypted, err)
+	}
+
+	// 移除旧密钥后旧密文无法解密
+	rotated := mustKeyring(t, "k2", map[string][]byte{"k2": testKey(2)})
+	if _, err := rotated.Decrypt(ciphertext); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() after removing old key error = %v, want ErrUnknownKey", err)
+	}
+	if plaintext, err := rotated.Decrypt(reencrypted); err != nil || plaintext != "refresh-token" {
+		t.Errorf("Decrypt() with new key = %q, %v", plaintext, err)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	// k1和k2使用相同的密钥，只能通过附加数据区分
+	k := mustKeyring(t, "k1", map[string][]byte{"k1": testKey(1), "k2": testKey(1)})
+	ciphertext, err := k.Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := strings.TrimPrefix(ciphertext, "enc:k1:")
+	tampered, _ := base64.RawStdEncoding.DecodeString(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"明文原样返回", "plain-refresh-token", "plain-refresh-token", false},
+		{"空字符串", "", "", false},
+		{"密文", ciphertext, "refresh-token", false},
+		{"挪用到其他密钥ID", "enc:k2:" + sealed, "", true},
+		{"未知密钥ID", "enc:k3:" + sealed, "", true},
+		{"缺少密钥ID分隔符", "enc:k1", "", true},
+		{"不是base64", "enc:k1:!!!", "", true},
+		{"长度不足", "enc:k1:" + base64.RawStdEncoding.EncodeToString([]byte("short")), "", true},
+		{"密文被篡改", "enc:k1:" + base64.RawStdEncoding.EncodeToString(tampered), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Decrypt(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"enc:k1:abc", "k1", false},
+		{"enc:2025-01:abc", "2025-01", false},
+		{"enc:k1", "", true},
+		{"plain", "", true},
+		{"ENC:k1:abc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := KeyID(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("KeyID(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name     string
+		activeID string
+		keys     map[string][]byte
+		wantErr  bool
+	}{
+		{"正常", "k1", map[string][]byte{"k1": testKey(1), "k0": testKey(0)}, false},
+		{"只解密不加密", "", map[string][]byte{"k1": testKey(1)}, false},
+		{"密钥ID包含冒号", "", map[string][]byte{"k:1": testKey(1)}, true},
+		{"密钥ID为空", "", map[string][]byte{"": testKey(1)}, true},
+		{"密钥长度错误", "", map[string][]byte{"k1": []byte("short")}, true},
+		{"当前密钥未配置", "k2", map[string][]byte{"k1": testKey(1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.activeID, tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	k := mustKeyring(t, "", map[string][]byte{"k1": testKey(1)})
+	if _, err := k.Encrypt("refresh-token"); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("Encrypt() without active key error = %v, want ErrNoActiveKey", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(1))
+
+	keys, err := ParseKeys(" k1:" + encoded + ", ,k2:" + encoded + ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys["k1"], testKey(1)) || !bytes.Equal(keys["k2"], testKey(1)) {
+		t.Errorf("ParseKeys() = %v", keys)
+	}
+
+	for _, value := range []string{"k1", "k1:not base64!"} {
+		if _, err := ParseKeys(value); err == nil {
+			t.Errorf("ParseKeys(%q) should fail", value)
+		}
+	}
+}
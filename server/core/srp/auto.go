@@ -0,0 +1,311 @@
+package srp
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
+	systemModel "eve-corp-manager/models/system"
+	characterRepo "eve-corp-manager/repository/service/character"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	systemRepo "eve-corp-manager/repository/system"
+	"eve-corp-manager/utils"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// autoMatchBatch 每批匹配的损失数量
+const autoMatchBatch = 200
+
+// AutoResult 一次自动匹配的结果
+type AutoResult struct {
+	Created   int // 自动创建申请的数量
+	Unmatched int // 未匹配到舰队的数量
+	Submitted int // 已有申请的数量
+	Failed    int // 处理失败的数量
+}
+
+// MatchLoss 将已绑定角色的损失匹配到其参加的舰队，舰队开启自动补损时创建补损申请，否则标记为待人工处理
+// 舰队的时间范围需要覆盖损失时间，EndTime为空表示舰队尚未结束；FleetLocation中配置了regionId/regionIds时还需要损失发生在这些星域
+func MatchLoss(ctx context.Context, km *character.KillmailList) (*srp.SrpLossMatch, error) {
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	userCharacter, err := userCharacterRepo.Get(uint(km.CharacterID))
+	if err != nil {
+		return nil, err
+	}
+	km.UserID = userCharacter.UserID
+
+	match := &srp.SrpLossMatch{
+		KillMailID:    km.KillMailID,
+		UserID:        km.UserID,
+		CharacterID:   km.CharacterID,
+		CharacterName: km.CharacterName,
+		ShipTypeName:  km.ShipTypeName,
+		KillMailTime:  km.KillMailTime,
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	if request, err := repo.GetByKillMail(km.KillMailID); err == nil {
+		match.FleetID, match.RequestID, match.Status = request.FleetID, request.ID, srp.SrpMatchSubmitted
+		return match, repo.SaveMatch(match)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	f, reason, err := matchFleet(km)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		match.Status, match.Reason = srp.SrpMatchUnmatched, reason
+		return match, repo.SaveMatch(match)
+	}
+
+	request, err := create(ctx, km, f.ID, userName(km), "自动补损: "+f.FleetName, Operator{})
+	if errors.Is(err, ErrAlreadySubmitted) {
+		// 用户同时手动提交
+		request, err = repo.GetByKillMail(km.KillMailID)
+		if err != nil {
+			return nil, err
+		}
+		match.FleetID, match.RequestID, match.Status = request.FleetID, request.ID, srp.SrpMatchSubmitted
+		return match, repo.SaveMatch(match)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match.FleetID, match.RequestID, match.Status = f.ID, request.ID, srp.SrpMatchCreated
+	return match, repo.SaveMatch(match)
+}
+
+// maxFleetDuration 未填写结束时间的舰队按开始后该时长结束匹配
+var maxFleetDuration = 24 * time.Hour
+
+// SetMaxFleetDuration 设置未填写结束时间的舰队的最长匹配时长
+func SetMaxFleetDuration(d time.Duration) {
+	maxFleetDuration = d
+}
+
+// matchFleet 查找覆盖损失时间和地点、开启自动补损的舰队，有多个时使用最近开始的舰队，没有匹配的舰队时返回原因
+func matchFleet(km *character.KillmailList) (*fleet.Fleet, string, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	fleets, err := repo.CharacterFleets(uint(km.CharacterID), km.KillMailTime)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 多个舰队都不匹配时，使用通过检查最多的舰队的原因
+	reason, stage := "损失时间不在角色参加的任何舰队时间内", 0
+	fail := func(s int, r string) {
+		if s > stage {
+			reason, stage = r, s
+		}
+	}
+	var regionID int
+	for i := range fleets {
+		f := &fleets[i]
+		endTime := f.StartTime.Add(maxFleetDuration)
+		if f.EndTime != nil {
+			endTime = *f.EndTime
+		}
+		if km.KillMailTime.After(endTime) {
+			continue
+		}
+
+		if regionIDs := fleetRegionIDs(f.FleetLocation); len(regionIDs) > 0 {
+			if regionID == 0 {
+				system, err := sde.GetSolarSystemByID(km.SolarSystemID)
+				if err != nil {
+					return nil, "", err
+				}
+				regionID = system.RegionID
+			}
+			if !slices.Contains(regionIDs, regionID) {
+				fail(1, fmt.Sprintf("损失星系不在舰队[%s]的星域内", f.FleetName))
+				continue
+			}
+		}
+
+		if !f.Srp {
+			fail(2, fmt.Sprintf("舰队[%s]不提供补损", f.FleetName))
+			continue
+		}
+		if !f.AutoSrp {
+			fail(3, fmt.Sprintf("舰队[%s]未开启自动补损", f.FleetName))
+			continue
+		}
+		return f, "", nil
+	}
+	return nil, reason, nil
+}
+
+// fleetRegionIDs 读取FleetLocation中的regionId/regionIds
+func fleetRegionIDs(location map[string]interface{}) []int {
+	var ids []int
+	appendID := func(v interface{}) {
+		switch id := v.(type) {
+		case float64:
+			ids = append(ids, int(id))
+		case int:
+			ids = append(ids, id)
+		case string:
+			if n, err := strconv.Atoi(id); err == nil {
+				ids = append(ids, n)
+			}
+		}
+	}
+
+	appendID(location["regionId"])
+	if list, ok := location["regionIds"].([]interface{}); ok {
+		for _, v := range list {
+			appendID(v)
+		}
+	}
+	return ids
+}
+
+// userName 损失角色所属用户的昵称，未设置时使用角色名称
+func userName(km *character.KillmailList) string {
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	if user, err := userRepo.Get(&systemModel.User{UserId: km.UserID}); err == nil && user.Name != "" {
+		return user.Name
+	}
+	return km.CharacterName
+}
+
+// AutoMatch 匹配since之后所有尚未处理的损失，未匹配到舰队的损失会在之后的匹配中重新检查
+func AutoMatch(ctx context.Context, since time.Time) (AutoResult, error) {
+	var result AutoResult
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+
+	var afterID uint
+	for {
+		losses, err := repo.PendingLosses(since, afterID, autoMatchBatch)
+		if err != nil {
+			return result, err
+		}
+
+		for _, loss := range losses {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			afterID = loss.ID
+
+			// 计算补损金额需要物品
+			km, err := killmailRepo.Get(loss.KillMailID)
+			if err != nil {
+				global.Logger.Errorf("获取损失击毁邮件失败, killMailID: %v, error: %v", loss.KillMailID, err)
+				result.Failed++
+				continue
+			}
+			match, err := MatchLoss(ctx, km)
+			if err != nil {
+				global.Logger.Errorf("自动补损匹配失败, killMailID: %v, error: %v", loss.KillMailID, err)
+				result.Failed++
+				continue
+			}
+
+			switch match.Status {
+			case srp.SrpMatchCreated:
+				result.Created++
+			case srp.SrpMatchUnmatched:
+				result.Unmatched++
+			case srp.SrpMatchSubmitted:
+				result.Submitted++
+			}
+		}
+
+		if len(losses) < autoMatchBatch {
+			return result, nil
+		}
+	}
+}
+
+// RunAutoSrp 按interval定时匹配最近days天的损失，直到ctx取消
+func RunAutoSrp(ctx context.Context, interval time.Duration, days int) error {
+	for {
+		result, err := AutoMatch(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil && !errors.Is(err, context.Canceled) {
+			global.Logger.Errorf("自动补损匹配失败: %v", err)
+		}
+		if result.Created > 0 || result.Failed > 0 {
+			global.Logger.Infof("自动补损匹配完成, 创建申请%v条, 未匹配%v条, 失败%v条", result.Created, result.Unmatched, result.Failed)
+		}
+
+		if !utils.SleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// AssignLoss 人工将未匹配的损失关联到舰队并代为提交补损申请
+func AssignLoss(ctx context.Context, id, fleetID uint, comment string, operator Operator) (*srp.SrpRequest, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	match, err := getUnmatchedLoss(id)
+	if err != nil {
+		return nil, err
+	}
+
+	killmailRepo := characterRepo.KillmailRepository{DB: global.Db}
+	km, err := killmailRepo.Get(match.KillMailID)
+	if err != nil {
+		return nil, err
+	}
+	km.UserID = match.UserID
+
+	request, err := create(ctx, km, fleetID, userName(km), comment, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	match.FleetID, match.RequestID, match.Status = fleetID, request.ID, srp.SrpMatchSubmitted
+	match.Reason, match.OperatorID, match.OperatorName = comment, operator.ID, operator.Name
+	if err := repo.SaveMatch(match); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// IgnoreLoss 人工确认未匹配的损失不予补损，必须填写原因
+func IgnoreLoss(id uint, comment string, operator Operator) (*srp.SrpLossMatch, error) {
+	if comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	match, err := getUnmatchedLoss(id)
+	if err != nil {
+		return nil, err
+	}
+
+	match.Status, match.Reason = srp.SrpMatchIgnored, comment
+	match.OperatorID, match.OperatorName = operator.ID, operator.Name
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	if err := repo.SaveMatch(match); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// getUnmatchedLoss 获取待人工处理的损失
+func getUnmatchedLoss(id uint) (*srp.SrpLossMatch, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	match, err := repo.GetMatch(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLossNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if match.Status != srp.SrpMatchUnmatched {
+		return nil, ErrLossHandled
+	}
+	return match, nil
+}
@@ -0,0 +1,186 @@
+package srp
+
+import (
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/srp"
+	systemModel "eve-corp-manager/models/system"
+	characterRepo "eve-corp-manager/repository/service/character"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	systemRepo "eve-corp-manager/repository/system"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoApprovedRequest  = errors.New("没有可发放的补损申请")
+	ErrBatchConflict      = errors.New("部分补损申请状态已变化，请重新创建批次")
+	ErrInBatch            = errors.New("申请已加入发放批次")
+	ErrBatchNotFound      = errors.New("发放批次不存在")
+	ErrPayoutItemNotFound = errors.New("发放明细不存在")
+	ErrPayoutItemPaid     = errors.New("该明细已发放")
+)
+
+// CreateBatch 将已批准且未加入批次的申请按收款用户的主角色汇总为发放批次，requestIDs为空时使用全部申请
+func CreateBatch(name string, requestIDs []uint, operator Operator) (*srp.SrpPayoutBatch, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	requests, err := repo.ApprovedUnbatched(requestIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, ErrNoApprovedRequest
+	}
+
+	if name == "" {
+		name = "补损发放 " + time.Now().Format("2006-01-02")
+	}
+	batch := &srp.SrpPayoutBatch{
+		Name:        name,
+		Status:      srp.SrpBatchOpen,
+		CreatorID:   operator.ID,
+		CreatorName: operator.Name,
+	}
+
+	index := make(map[uint]int)
+	var ids [][]uint
+	for _, request := range requests {
+		i, ok := index[request.UserID]
+		if !ok {
+			characterID, characterName := mainCharacter(request)
+			i = len(batch.Items)
+			index[request.UserID] = i
+			batch.Items = append(batch.Items, srp.SrpPayoutItem{
+				UserID:            request.UserID,
+				MainCharacterID:   characterID,
+				MainCharacterName: characterName,
+				Status:            srp.SrpPayoutPending,
+			})
+			ids = append(ids, nil)
+		}
+		batch.Items[i].Amount += request.ApprovedAmount
+		batch.Items[i].RequestCount++
+		ids[i] = append(ids[i], request.ID)
+	}
+	for i := range batch.Items {
+		batch.Items[i].Amount = math.Round(batch.Items[i].Amount*100) / 100
+		batch.Total += batch.Items[i].Amount
+	}
+
+	created, err := repo.CreateBatch(batch, ids)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrBatchConflict
+	}
+
+	global.Logger.Infof("创建补损发放批次, id: %v, 申请%v条, 总额: %.2f, 操作人: %v", batch.ID, len(requests), batch.Total, operator.Name)
+	return repo.GetBatch(batch.ID)
+}
+
+// mainCharacter 申请人的主角色，未设置主角色时使用损失角色
+func mainCharacter(request srp.SrpRequest) (int, string) {
+	userRepo := systemRepo.UserRepository{DB: global.Db}
+	user, err := userRepo.Get(&systemModel.User{UserId: request.UserID})
+	if err != nil || user.MainCharacterId == 0 || user.MainCharacterId == request.CharacterID {
+		return request.CharacterID, request.CharacterName
+	}
+
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	mainCharacter, err := userCharacterRepo.Get(uint(user.MainCharacterId))
+	if err != nil {
+		return request.CharacterID, request.CharacterName
+	}
+	return user.MainCharacterId, mainCharacter.CharacterName
+}
+
+// GetBatch 获取发放批次
+func GetBatch(id uint) (*srp.SrpPayoutBatch, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	batch, err := repo.GetBatch(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	return batch, err
+}
+
+// ExportBatch 导出尚未确认发放的明细，每行为 角色名称<TAB>金额<TAB>转账备注，按角色名称排序
+// 游戏内转账时将转账备注填入原因，钱包对账时按备注匹配
+func ExportBatch(batch *srp.SrpPayoutBatch) string {
+	items := make([]srp.SrpPayoutItem, 0, len(batch.Items))
+	for _, item := range batch.Items {
+		if item.Status != srp.SrpPayoutPaid {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].MainCharacterName) < strings.ToLower(items[j].MainCharacterName)
+	})
+
+	var builder strings.Builder
+	for _, item := range items {
+		builder.WriteString(fmt.Sprintf("%s\t%.2f\t%s\n", item.MainCharacterName, item.Amount, item.Reference))
+	}
+	return builder.String()
+}
+
+// ConfirmPayoutItem 人工确认发放明细已转账，用于钱包对账无法匹配的情况
+func ConfirmPayoutItem(id uint, comment string, operator Operator) (*srp.SrpPayoutItem, error) {
+	if comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	item, err := repo.GetItem(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPayoutItemNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if item.Status == srp.SrpPayoutPaid {
+		return nil, ErrPayoutItemPaid
+	}
+
+	now := time.Now()
+	item.PaidAmount, item.PaidTime = item.Amount, &now
+	item.Note = fmt.Sprintf("%s确认发放: %s", operator.Name, comment)
+	if err := markPaid(item, comment, operator); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// markPaid 将明细及其包含的申请标记为已发放，item的金额、时间和说明由调用方设置
+func markPaid(item *srp.SrpPayoutItem, comment string, operator Operator) error {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	requests, err := repo.ItemRequests(item.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range requests {
+		request := &requests[i]
+		if request.Status != srp.SrpStatusApproved {
+			// 已确认发放
+			continue
+		}
+		_, err := transition(request, []int{srp.SrpStatusApproved}, srp.SrpStatusPaid, map[string]interface{}{
+			"paid_amount": request.ApprovedAmount,
+			"payer_id":    operator.ID,
+			"payer_name":  operator.Name,
+			"paid_time":   *item.PaidTime,
+		}, srp.SrpActionPay, request.ApprovedAmount, comment, operator)
+		if err != nil && !errors.Is(err, ErrInvalidStatus) {
+			return err
+		}
+	}
+
+	item.Status = srp.SrpPayoutPaid
+	return repo.UpdateItem(item)
+}
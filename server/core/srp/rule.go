@@ -0,0 +1,180 @@
+package srp
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"eve-corp-manager/utils"
+	"fmt"
+	"math"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Payout 补损金额的计算结果和依据
+type Payout struct {
+	Amount         float64  `json:"amount"`         // 最终补损金额
+	JaniceAmount   float64  `json:"janiceAmount"`   // 击毁邮件的Janice估价
+	ExcludedAmount float64  `json:"excludedAmount"` // 排除物品的Janice估价
+	ExcludedItems  []string `json:"excludedItems"`  // 排除的物品
+	Basis          float64  `json:"basis"`          // 按比例计算的基数，即Janice估价减去排除物品
+	RuleID         uint     `json:"ruleId"`         // 匹配的规则ID，0表示没有匹配的规则
+	RuleName       string   `json:"ruleName"`       // 匹配的规则名称
+	Multiplier     float64  `json:"multiplier"`     // 舰队类型倍率
+	Capped         bool     `json:"capped"`         // 是否触及补损上限
+	Reasons        []string `json:"reasons"`        // 计算过程说明
+}
+
+// Calculate 按补损规则计算击毁邮件的补损金额，f为nil时不计算舰队倍率
+// 依次匹配舰船固定金额、舰船分组比例、舰船类别比例规则，都不匹配时按Janice估价全额补损
+// 按比例补损时扣除势力/官员等排除物品的估价，最后乘以舰队类型倍率并按规则上限截断
+func Calculate(ctx context.Context, km *character.KillmailList, f *fleet.Fleet) (*Payout, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	rules, err := repo.ListRules(true)
+	if err != nil {
+		return nil, err
+	}
+
+	payout := &Payout{JaniceAmount: km.JaniceAmount, Multiplier: 1, ExcludedItems: []string{}}
+
+	shipInfo, err := sde.GetTypeInfoByID(km.ShipTypeID, utils.DefaultLang)
+	if err != nil {
+		// 舰船固定金额规则不依赖SDE
+		global.Logger.Warnf("获取舰船分类失败, shipTypeID: %v, error: %v", km.ShipTypeID, err)
+		payout.addReason("无法获取舰船分组，跳过分组和类别规则")
+		shipInfo = sde.TypeInfo{TypeID: km.ShipTypeID, TypeName: km.ShipTypeName}
+	}
+
+	rule := matchRule(rules, shipInfo)
+	var amount float64
+	if rule != nil && rule.RuleType == srp.SrpRuleShipFixed {
+		payout.RuleID, payout.RuleName = rule.ID, rule.Name
+		amount = rule.FixedAmount
+		payout.addReason("匹配规则[%s]: %s固定补损%.2f ISK", rule.Name, km.ShipTypeName, rule.FixedAmount)
+	} else {
+		if err := payout.exclude(ctx, km, rules); err != nil {
+			return nil, err
+		}
+
+		percent := 100.0
+		if rule != nil {
+			payout.RuleID, payout.RuleName = rule.ID, rule.Name
+			percent = rule.Percent
+			target := shipInfo.GroupName
+			if rule.RuleType == srp.SrpRuleCategoryPercent {
+				target = shipInfo.CategoryName
+			}
+			payout.addReason("匹配规则[%s]: %s按估价的%.2f%%补损", rule.Name, target, percent)
+		} else {
+			payout.addReason("没有匹配的补损规则，按估价全额补损")
+		}
+		amount = payout.Basis * percent / 100
+	}
+
+	if f != nil {
+		multiplier, err := repo.GetMultiplier(f.FleetType)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil && multiplier.Multiplier != 1 {
+			payout.Multiplier = multiplier.Multiplier
+			amount *= multiplier.Multiplier
+			payout.addReason("舰队类型%d的补损倍率为%.2f", f.FleetType, multiplier.Multiplier)
+		}
+	}
+
+	if rule != nil && rule.MaxAmount > 0 && amount > rule.MaxAmount {
+		amount = rule.MaxAmount
+		payout.Capped = true
+		payout.addReason("超出规则上限，按上限%.2f ISK补损", rule.MaxAmount)
+	}
+
+	payout.Amount = math.Round(amount*100) / 100
+	return payout, nil
+}
+
+// matchRule 按 舰船固定金额 > 分组比例 > 类别比例 的顺序匹配金额规则，rules已按优先级排序
+func matchRule(rules []srp.SrpRule, shipInfo sde.TypeInfo) *srp.SrpRule {
+	levels := []struct {
+		ruleType int
+		targetID int
+	}{
+		{srp.SrpRuleShipFixed, shipInfo.TypeID},
+		{srp.SrpRuleGroupPercent, shipInfo.GroupID},
+		{srp.SrpRuleCategoryPercent, shipInfo.CategoryID},
+	}
+	for _, level := range levels {
+		if level.targetID == 0 {
+			continue
+		}
+		for i := range rules {
+			if rules[i].RuleType == level.ruleType && rules[i].TargetID == level.targetID {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// exclude 计算排除物品的估价，并得出按比例补损的基数
+func (p *Payout) exclude(ctx context.Context, km *character.KillmailList, rules []srp.SrpRule) error {
+	p.Basis = km.JaniceAmount
+
+	excludedTypes := make(map[int]bool)
+	excludedMetaGroups := make(map[int]bool)
+	for _, rule := range rules {
+		switch rule.RuleType {
+		case srp.SrpRuleExcludeType:
+			excludedTypes[rule.TargetID] = true
+		case srp.SrpRuleExcludeMetaGroup:
+			excludedMetaGroups[rule.TargetID] = true
+		}
+	}
+	if len(excludedTypes) == 0 && len(excludedMetaGroups) == 0 {
+		return nil
+	}
+
+	var metaGroups map[int]int
+	if len(excludedMetaGroups) > 0 {
+		typeIDs := make([]int, 0, len(km.Items))
+		for _, item := range km.Items {
+			typeIDs = append(typeIDs, item.ItemID)
+		}
+		var err error
+		if metaGroups, err = sde.GetMetaGroupIDs(typeIDs); err != nil {
+			return err
+		}
+	}
+
+	var query strings.Builder
+	for _, item := range km.Items {
+		if !excludedTypes[item.ItemID] && !excludedMetaGroups[metaGroups[item.ItemID]] {
+			continue
+		}
+		query.WriteString(fmt.Sprintf("%s\t%d\n", item.ItemName, item.ItemNum))
+		p.ExcludedItems = append(p.ExcludedItems, fmt.Sprintf("%s x%d", item.ItemName, item.ItemNum))
+	}
+	if query.Len() == 0 {
+		return nil
+	}
+
+	amount, err := esi.GetAppraisal(ctx, query.String())
+	if err != nil {
+		return err
+	}
+	p.ExcludedAmount = amount
+	p.Basis = math.Max(km.JaniceAmount-amount, 0)
+	p.addReason("排除%d项物品，扣除估价%.2f ISK", len(p.ExcludedItems), amount)
+	return nil
+}
+
+func (p *Payout) addReason(format string, args ...interface{}) {
+	p.Reasons = append(p.Reasons, fmt.Sprintf(format, args...))
+}
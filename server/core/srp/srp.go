@@ -0,0 +1,279 @@
+package srp
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/killmail"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
+	characterRepo "eve-corp-manager/repository/service/character"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidLink      = errors.New("无法识别的击毁邮件链接")
+	ErrNotOwner         = errors.New("损失角色未绑定到当前用户")
+	ErrAlreadySubmitted = errors.New("该损失已提交过补损申请")
+	ErrFleetNotFound    = errors.New("舰队不存在")
+	ErrFleetNoSrp       = errors.New("该舰队不提供补损")
+	ErrInvalidStatus    = errors.New("当前状态不允许该操作")
+	ErrInvalidAmount    = errors.New("金额无效")
+	ErrCommentRequired  = errors.New("请填写备注")
+	ErrLossNotFound     = errors.New("损失记录不存在")
+	ErrLossHandled      = errors.New("该损失已处理")
+)
+
+// Operator 操作人，自动任务使用零值
+type Operator struct {
+	ID   uint
+	Name string
+}
+
+// Submit 提交补损申请，击毁邮件通过killmail.Save解析保存，损失角色必须属于申请人
+func Submit(ctx context.Context, link string, fleetID uint, remark string, operator Operator) (*srp.SrpRequest, error) {
+	killMailID, killMailHash, err := esi.GetKillmailHash(ctx, link)
+	if err != nil || killMailID == 0 || killMailHash == "" {
+		return nil, ErrInvalidLink
+	}
+
+	km, err := killmail.Save(ctx, killMailID, killMailHash, killmail.Creator{ID: operator.ID, Name: operator.Name})
+	if err != nil {
+		return nil, err
+	}
+	// 以当前的角色绑定关系为准，击毁邮件保存时角色可能尚未绑定
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	userCharacter, err := userCharacterRepo.Get(uint(km.CharacterID))
+	if err != nil || userCharacter.UserID != operator.ID {
+		return nil, ErrNotOwner
+	}
+	km.UserID = userCharacter.UserID
+
+	return create(ctx, km, fleetID, operator.Name, remark, operator)
+}
+
+// DryRun 按当前补损规则试算击毁邮件的补损金额，不创建申请也不保存击毁邮件
+// fleetType不为nil时按该舰队类型计算倍率，否则使用fleetID对应舰队的类型
+func DryRun(ctx context.Context, link string, fleetID uint, fleetType *int, operator Operator) (*character.KillmailList, *Payout, error) {
+	killMailID, killMailHash, err := esi.GetKillmailHash(ctx, link)
+	if err != nil || killMailID == 0 || killMailHash == "" {
+		return nil, nil, ErrInvalidLink
+	}
+
+	f, err := getSrpFleet(fleetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fleetType != nil {
+		f = &fleet.Fleet{FleetType: *fleetType}
+	}
+
+	// 试算不写入数据库，未保存的击毁邮件只在内存中解析
+	km, _, err := killmail.Load(ctx, killMailID, killMailHash, killmail.Creator{ID: operator.ID, Name: operator.Name})
+	if err != nil {
+		return nil, nil, err
+	}
+	payout, err := Calculate(ctx, km, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return km, payout, nil
+}
+
+// getSrpFleet 获取提供补损的舰队，fleetID为0时返回nil
+func getSrpFleet(fleetID uint) (*fleet.Fleet, error) {
+	if fleetID == 0 {
+		return nil, nil
+	}
+	var f fleet.Fleet
+	if err := global.Db.First(&f, fleetID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFleetNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !f.Srp {
+		return nil, ErrFleetNoSrp
+	}
+	return &f, nil
+}
+
+// create 根据已保存的击毁邮件为km.UserID创建待审核的补损申请，申请金额按补损规则计算
+func create(ctx context.Context, km *character.KillmailList, fleetID uint, userName, remark string, operator Operator) (*srp.SrpRequest, error) {
+	f, err := getSrpFleet(fleetID)
+	if err != nil {
+		return nil, err
+	}
+	payout, err := Calculate(ctx, km, f)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &srp.SrpRequest{
+		KillMailID:      km.KillMailID,
+		UserID:          km.UserID,
+		UserName:        userName,
+		CharacterID:     km.CharacterID,
+		CharacterName:   km.CharacterName,
+		ShipTypeID:      km.ShipTypeID,
+		ShipTypeName:    km.ShipTypeName,
+		SolarSystemID:   km.SolarSystemID,
+		SolarSystemName: km.SolarSystemName,
+		KillMailTime:    km.KillMailTime,
+		JaniceAmount:    km.JaniceAmount,
+		FleetID:         fleetID,
+		RequestAmount:   payout.Amount,
+		Status:          srp.SrpStatusPending,
+		Remark:          remark,
+	}
+	log := &srp.SrpRequestLog{
+		Action:       srp.SrpActionSubmit,
+		ToStatus:     srp.SrpStatusPending,
+		Amount:       request.RequestAmount,
+		OperatorID:   operator.ID,
+		OperatorName: operator.Name,
+		Comment:      remark,
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	created, err := repo.Create(request, log)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadySubmitted
+	}
+
+	global.Logger.Infof("提交补损申请成功, id: %v, killMailID: %v, 用户: %v", request.ID, request.KillMailID, operator.Name)
+	return request, nil
+}
+
+// Approve 批准待审核的申请，amount为0时按申请金额批准，少于申请金额视为部分补损，与申请金额不同时必须填写原因
+func Approve(id uint, amount float64, comment string, operator Operator) (*srp.SrpRequest, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	request, err := repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != srp.SrpStatusPending {
+		return nil, ErrInvalidStatus
+	}
+	if request.PayoutItemID > 0 {
+		return nil, ErrInBatch
+	}
+
+	if amount == 0 {
+		amount = request.RequestAmount
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if amount != request.RequestAmount && comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	now := time.Now()
+	return transition(request, []int{srp.SrpStatusPending}, srp.SrpStatusApproved, map[string]interface{}{
+		"approved_amount": amount,
+		"review_comment":  comment,
+		"reviewer_id":     operator.ID,
+		"reviewer_name":   operator.Name,
+		"review_time":     now,
+	}, srp.SrpActionApprove, amount, comment, operator)
+}
+
+// Reject 拒绝待审核或已批准但未加入发放批次的申请，必须填写原因
+func Reject(id uint, comment string, operator Operator) (*srp.SrpRequest, error) {
+	if comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	request, err := repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	// 已加入发放批次的申请可能已经转账
+	if request.PayoutItemID > 0 {
+		return nil, ErrInBatch
+	}
+
+	from := []int{srp.SrpStatusPending, srp.SrpStatusApproved}
+	now := time.Now()
+	return transition(request, from, srp.SrpStatusRejected, map[string]interface{}{
+		"review_comment": comment,
+		"reviewer_id":    operator.ID,
+		"reviewer_name":  operator.Name,
+		"review_time":    now,
+	}, srp.SrpActionReject, 0, comment, operator)
+}
+
+// Pay 发放已批准的申请，amount为0时按批准金额发放，少于批准金额为部分发放且必须填写原因
+// 已加入发放批次的申请通过发放明细确认，不能单独发放
+func Pay(id uint, amount float64, comment string, operator Operator) (*srp.SrpRequest, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	request, err := repo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != srp.SrpStatusApproved {
+		return nil, ErrInvalidStatus
+	}
+	if request.PayoutItemID > 0 {
+		return nil, ErrInBatch
+	}
+
+	if amount == 0 {
+		amount = request.ApprovedAmount
+	}
+	if amount <= 0 || amount > request.ApprovedAmount {
+		return nil, ErrInvalidAmount
+	}
+	if amount < request.ApprovedAmount && comment == "" {
+		return nil, ErrCommentRequired
+	}
+
+	now := time.Now()
+	return transition(request, []int{srp.SrpStatusApproved}, srp.SrpStatusPaid, map[string]interface{}{
+		"paid_amount": amount,
+		"payer_id":    operator.ID,
+		"payer_name":  operator.Name,
+		"paid_time":   now,
+	}, srp.SrpActionPay, amount, comment, operator)
+}
+
+// transition 执行状态变更并记录审计日志，返回更新后的申请
+func transition(request *srp.SrpRequest, from []int, to int, updates map[string]interface{}, action string, amount float64, comment string, operator Operator) (*srp.SrpRequest, error) {
+	if !slices.Contains(from, request.Status) {
+		return nil, ErrInvalidStatus
+	}
+
+	updates["status"] = to
+	log := &srp.SrpRequestLog{
+		Action:       action,
+		FromStatus:   request.Status,
+		ToStatus:     to,
+		Amount:       amount,
+		OperatorID:   operator.ID,
+		OperatorName: operator.Name,
+		Comment:      comment,
+	}
+
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	// 以读取时的状态和发放明细为条件，防止并发审核覆盖或与创建发放批次冲突
+	changed, err := repo.Transition(request.ID, []int{request.Status}, request.PayoutItemID, updates, log)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrInvalidStatus
+	}
+
+	global.Logger.Infof("补损申请状态变更, id: %v, %v -> %v, 操作人: %v", request.ID, request.Status, to, operator.Name)
+	return repo.Get(request.ID)
+}
@@ -0,0 +1,700 @@
+package srp
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/core/killmail"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
+	systemModel "eve-corp-manager/models/system"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureJanice 内置击毁邮件的Janice估价，见esitest/fixtures/prices.json
+const fixtureJanice = 606000
+
+// domainRegionID 内置击毁邮件所在星系Amarr的星域
+const domainRegionID = 10000043
+
+var testOperator = Operator{ID: 1, Name: "reviewer"}
+
+// setupSrp 使用内存数据库和模拟ESI服务
+func setupSrp(t *testing.T) *esitest.Server {
+	t.Helper()
+
+	esitest.UseDB(t,
+		&srp.SrpRequest{}, &srp.SrpRequestLog{}, &srp.SrpRule{}, &srp.SrpFleetMultiplier{},
+		&srp.SrpPayoutBatch{}, &srp.SrpPayoutItem{}, &srp.SrpLossMatch{}, &fleet.Fleet{}, &fleet.CharacterFleetAssociation{},
+		&character.KillmailList{}, &character.KillmailItem{}, &character.UserCharacter{}, &systemModel.User{})
+
+	srv := esitest.NewServerWithFixtures()
+	restore := srv.Install()
+	t.Cleanup(func() {
+		restore()
+		srv.Close()
+	})
+	return srv
+}
+
+// setupSde 使用只包含Rifter、一件势力装备和Amarr星系的SDE
+func setupSde(t *testing.T) {
+	t.Helper()
+	sdeDb := esitest.UseSdeDB(t, &sde.InvType{}, &sde.InvGroup{}, &sde.InvCategory{}, &sde.InvMetaType{}, &sde.TrnTranslation{},
+		&sde.MapRegion{}, &sde.MapSolarSystem{})
+	sdeDb.Create(&sde.InvCategory{CategoryID: 6, CategoryName: "Ship"})
+	sdeDb.Create(&sde.InvGroup{GroupID: 25, CategoryID: 6, GroupName: "Frigate"})
+	sdeDb.Create(&sde.InvType{TypeID: 587, GroupID: 25, TypeName: "Rifter"})
+	sdeDb.Create(&sde.InvMetaType{TypeID: 2046, MetaGroupID: sde.MetaGroupFaction})
+	sdeDb.Create(&sde.MapRegion{RegionID: domainRegionID, RegionName: "Domain"})
+	sdeDb.Create(&sde.MapSolarSystem{SolarSystemID: 30002187, SolarSystemName: "Amarr", RegionID: domainRegionID, Security: 1})
+}
+
+// createRequest 直接写入一条补损申请，申请金额100，已批准时批准金额80
+func createRequest(t *testing.T, userID uint, characterID, status int, payoutItemID uint) *srp.SrpRequest {
+	t.Helper()
+	var count int64
+	global.Db.Model(&srp.SrpRequest{}).Count(&count)
+
+	request := &srp.SrpRequest{
+		KillMailID:    1000 + int(count),
+		UserID:        userID,
+		CharacterID:   characterID,
+		CharacterName: fmt.Sprintf("character %d", characterID),
+		RequestAmount: 100,
+		Status:        status,
+		PayoutItemID:  payoutItemID,
+	}
+	if status == srp.SrpStatusApproved {
+		request.ApprovedAmount = 80
+	}
+	if err := global.Db.Create(request).Error; err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func getRequest(t *testing.T, id uint) *srp.SrpRequest {
+	t.Helper()
+	var request srp.SrpRequest
+	if err := global.Db.Preload("Logs").First(&request, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &request
+}
+
+func TestTransitions(t *testing.T) {
+	setupSrp(t)
+
+	approve := func(amount float64, comment string) func(id uint) (*srp.SrpRequest, error) {
+		return func(id uint) (*srp.SrpRequest, error) { return Approve(id, amount, comment, testOperator) }
+	}
+	reject := func(comment string) func(id uint) (*srp.SrpRequest, error) {
+		return func(id uint) (*srp.SrpRequest, error) { return Reject(id, comment, testOperator) }
+	}
+	pay := func(amount float64, comment string) func(id uint) (*srp.SrpRequest, error) {
+		return func(id uint) (*srp.SrpRequest, error) { return Pay(id, amount, comment, testOperator) }
+	}
+
+	tests := []struct {
+		name       string
+		status     int
+		inBatch    bool
+		op         func(id uint) (*srp.SrpRequest, error)
+		wantErr    error
+		wantStatus int
+		wantAmount float64 // 批准或发放金额
+	}{
+		{"按申请金额批准", srp.SrpStatusPending, false, approve(0, ""), nil, srp.SrpStatusApproved, 100},
+		{"部分批准需要备注", srp.SrpStatusPending, false, approve(60, ""), ErrCommentRequired, srp.SrpStatusPending, 0},
+		{"部分批准", srp.SrpStatusPending, false, approve(60, "保险已赔付"), nil, srp.SrpStatusApproved, 60},
+		{"超出申请金额需要备注", srp.SrpStatusPending, false, approve(120, ""), ErrCommentRequired, srp.SrpStatusPending, 0},
+		{"超出申请金额", srp.SrpStatusPending, false, approve(120, "补发装配"), nil, srp.SrpStatusApproved, 120},
+		{"批准金额无效", srp.SrpStatusPending, false, approve(-1, "x"), ErrInvalidAmount, srp.SrpStatusPending, 0},
+		{"重复批准", srp.SrpStatusApproved, false, approve(0, ""), ErrInvalidStatus, srp.SrpStatusApproved, 80},
+		{"已加入批次不能批准", srp.SrpStatusPending, true, approve(0, ""), ErrInBatch, srp.SrpStatusPending, 0},
+		{"拒绝需要原因", srp.SrpStatusPending, false, reject(""), ErrCommentRequired, srp.SrpStatusPending, 0},
+		{"拒绝待审核", srp.SrpStatusPending, false, reject("不在舰队中"), nil, srp.SrpStatusRejected, 0},
+		{"拒绝已批准", srp.SrpStatusApproved, false, reject("重复申请"), nil, srp.SrpStatusRejected, 80},
+		{"已加入批次不能拒绝", srp.SrpStatusApproved, true, reject("重复申请"), ErrInBatch, srp.SrpStatusApproved, 80},
+		{"已发放不能拒绝", srp.SrpStatusPaid, false, reject("重复申请"), ErrInvalidStatus, srp.SrpStatusPaid, 0},
+		{"按批准金额发放", srp.SrpStatusApproved, false, pay(0, ""), nil, srp.SrpStatusPaid, 80},
+		{"部分发放需要备注", srp.SrpStatusApproved, false, pay(50, ""), ErrCommentRequired, srp.SrpStatusApproved, 80},
+		{"部分发放", srp.SrpStatusApproved, false, pay(50, "已先发放一部分"), nil, srp.SrpStatusPaid, 50},
+		{"发放超出批准金额", srp.SrpStatusApproved, false, pay(90, "x"), ErrInvalidAmount, srp.SrpStatusApproved, 80},
+		{"未批准不能发放", srp.SrpStatusPending, false, pay(0, ""), ErrInvalidStatus, srp.SrpStatusPending, 0},
+		{"已加入批次不能单独发放", srp.SrpStatusApproved, true, pay(0, ""), ErrInBatch, srp.SrpStatusApproved, 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payoutItemID uint
+			if tt.inBatch {
+				payoutItemID = 1
+			}
+			request := createRequest(t, 1, 2112000010, tt.status, payoutItemID)
+
+			_, err := tt.op(request.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			got := getRequest(t, request.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got.Status, tt.wantStatus)
+			}
+			amount := got.ApprovedAmount
+			if got.Status == srp.SrpStatusPaid {
+				amount = got.PaidAmount
+			}
+			if amount != tt.wantAmount {
+				t.Errorf("amount = %v, want %v", amount, tt.wantAmount)
+			}
+
+			// 成功的状态变更写入一条审计日志
+			wantLogs := 0
+			if tt.wantErr == nil {
+				wantLogs = 1
+			}
+			if len(got.Logs) != wantLogs {
+				t.Fatalf("logs = %d, want %d", len(got.Logs), wantLogs)
+			}
+			if wantLogs == 1 {
+				log := got.Logs[0]
+				if log.FromStatus != tt.status || log.ToStatus != tt.wantStatus || log.OperatorID != testOperator.ID {
+					t.Errorf("log = %+v", log)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	setupSrp(t)
+	setupSde(t)
+
+	km, stored, err := killmail.Load(context.Background(), esitest.FixtureKillmailID, esitest.FixtureKillmailHash, killmail.Creator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored || km.JaniceAmount != fixtureJanice {
+		t.Fatalf("killmail stored = %v, janice = %v", stored, km.JaniceAmount)
+	}
+
+	fixed := func(amount float64) srp.SrpRule {
+		return srp.SrpRule{Name: "Rifter", RuleType: srp.SrpRuleShipFixed, TargetID: 587, FixedAmount: amount, Enabled: true}
+	}
+	group := func(percent, max float64, priority int) srp.SrpRule {
+		return srp.SrpRule{Name: "Frigate", RuleType: srp.SrpRuleGroupPercent, TargetID: 25, Percent: percent, MaxAmount: max, Priority: priority, Enabled: true}
+	}
+	category := srp.SrpRule{Name: "Ship", RuleType: srp.SrpRuleCategoryPercent, TargetID: 6, Percent: 80, Enabled: true}
+	disabled := fixed(1)
+	disabled.Enabled = false
+	excludeRig := srp.SrpRule{Name: "rig", RuleType: srp.SrpRuleExcludeType, TargetID: 31788, Enabled: true}
+	excludeFaction := srp.SrpRule{Name: "faction", RuleType: srp.SrpRuleExcludeMetaGroup, TargetID: sde.MetaGroupFaction, Enabled: true}
+
+	tests := []struct {
+		name         string
+		rules        []srp.SrpRule
+		multiplier   float64 // 舰队类型2的倍率，0表示不设置
+		wantAmount   float64
+		wantRule     string
+		wantExcluded float64
+		wantCapped   bool
+	}{
+		{name: "没有规则按估价全额", wantAmount: fixtureJanice},
+		{name: "舰船固定金额", rules: []srp.SrpRule{fixed(500000)}, wantAmount: 500000, wantRule: "Rifter"},
+		{name: "分组比例", rules: []srp.SrpRule{group(50, 0, 0)}, wantAmount: 303000, wantRule: "Frigate"},
+		{name: "类别比例", rules: []srp.SrpRule{category}, wantAmount: 484800, wantRule: "Ship"},
+		{name: "分组优先于类别", rules: []srp.SrpRule{category, group(50, 0, 0)}, wantAmount: 303000, wantRule: "Frigate"},
+		{name: "固定金额优先于分组", rules: []srp.SrpRule{group(50, 0, 0), fixed(500000)}, wantAmount: 500000, wantRule: "Rifter"},
+		{name: "同级按优先级", rules: []srp.SrpRule{group(50, 0, 1), group(70, 0, 5)}, wantAmount: 424200, wantRule: "Frigate"},
+		{name: "忽略停用的规则", rules: []srp.SrpRule{disabled, group(50, 0, 0)}, wantAmount: 303000, wantRule: "Frigate"},
+		{name: "补损上限", rules: []srp.SrpRule{group(100, 400000, 0)}, wantAmount: 400000, wantRule: "Frigate", wantCapped: true},
+		{name: "排除物品", rules: []srp.SrpRule{excludeRig, group(50, 0, 0)}, wantAmount: 273000, wantRule: "Frigate", wantExcluded: 60000},
+		{name: "排除势力装备", rules: []srp.SrpRule{excludeFaction}, wantAmount: 581000, wantExcluded: 25000},
+		{name: "固定金额不排除物品", rules: []srp.SrpRule{excludeRig, fixed(500000)}, wantAmount: 500000, wantRule: "Rifter"},
+		{name: "舰队倍率", rules: []srp.SrpRule{group(50, 0, 0)}, multiplier: 1.5, wantAmount: 454500, wantRule: "Frigate"},
+		{name: "倍率后按上限截断", rules: []srp.SrpRule{group(100, 700000, 0)}, multiplier: 1.5, wantAmount: 700000, wantRule: "Frigate", wantCapped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global.Db.Exec("DELETE FROM srp_rule")
+			global.Db.Exec("DELETE FROM srp_fleet_multiplier")
+			for _, rule := range tt.rules {
+				if err := global.Db.Create(&rule).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.multiplier > 0 {
+				global.Db.Create(&srp.SrpFleetMultiplier{FleetType: 2, Multiplier: tt.multiplier})
+			}
+
+			payout, err := Calculate(context.Background(), km, &fleet.Fleet{FleetType: 2})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			if payout.Amount != tt.wantAmount || payout.RuleName != tt.wantRule ||
+				payout.ExcludedAmount != tt.wantExcluded || payout.Capped != tt.wantCapped {
+				t.Errorf("Calculate() = amount %v rule %q excluded %v capped %v, want %v %q %v %v (%v)",
+					payout.Amount, payout.RuleName, payout.ExcludedAmount, payout.Capped,
+					tt.wantAmount, tt.wantRule, tt.wantExcluded, tt.wantCapped, payout.Reasons)
+			}
+		})
+	}
+}
+
+func TestDryRunDoesNotSave(t *testing.T) {
+	setupSrp(t)
+	setupSde(t)
+
+	link := fmt.Sprintf("https://zkillboard.com/kill/%d/", esitest.FixtureKillmailID)
+	km, payout, err := DryRun(context.Background(), link, 0, nil, testOperator)
+	if err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	if km.KillMailID != esitest.FixtureKillmailID || payout.Amount != fixtureJanice {
+		t.Errorf("DryRun() = %v, %v", km.KillMailID, payout.Amount)
+	}
+
+	for _, model := range []interface{}{&character.KillmailList{}, &character.KillmailItem{}, &srp.SrpRequest{}} {
+		var count int64
+		global.Db.Model(model).Count(&count)
+		if count != 0 {
+			t.Errorf("%T rows = %d, want 0", model, count)
+		}
+	}
+}
+
+// lossTime 测试损失的时间
+var lossTime = time.Date(2025, 5, 1, 12, 34, 56, 0, time.UTC)
+
+// createLoss 保存绑定角色在Amarr损失的Rifter，角色属于userID
+func createLoss(t *testing.T, killMailID, characterID int, userID uint) *character.KillmailList {
+	t.Helper()
+	if userID > 0 {
+		global.Db.Create(&character.UserCharacter{CharacterID: uint(characterID), CharacterName: "Esitest Victim", UserID: userID})
+	}
+	km := &character.KillmailList{
+		KillMailID:      killMailID,
+		KillMailHash:    esitest.FixtureKillmailHash,
+		KillMailTime:    lossTime,
+		CharacterID:     characterID,
+		CharacterName:   "Esitest Victim",
+		ShipTypeID:      587,
+		ShipTypeName:    "Rifter",
+		SolarSystemID:   30002187,
+		SolarSystemName: "Amarr",
+		JaniceAmount:    fixtureJanice,
+	}
+	if err := global.Db.Create(km).Error; err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+// testFleet 相对损失时间的舰队，end为0表示未填写结束时间，characterIDs为参加的角色
+type testFleet struct {
+	name         string
+	start, end   time.Duration
+	srp, autoSrp bool
+	location     map[string]interface{}
+	characterIDs []uint
+}
+
+func (tf testFleet) create(t *testing.T) *fleet.Fleet {
+	t.Helper()
+	f := &fleet.Fleet{
+		FleetName:     tf.name,
+		FleetLocation: tf.location,
+		Srp:           tf.srp,
+		AutoSrp:       tf.autoSrp,
+		StartTime:     lossTime.Add(tf.start),
+	}
+	if tf.end != 0 {
+		end := lossTime.Add(tf.end)
+		f.EndTime = &end
+	}
+	if err := global.Db.Create(f).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, characterID := range tf.characterIDs {
+		global.Db.Create(&fleet.CharacterFleetAssociation{FleetID: f.ID, CharacterID: characterID})
+	}
+	return f
+}
+
+func TestMatchLoss(t *testing.T) {
+	const victim = 2112000010
+	auto := func(name string, start, end time.Duration) testFleet {
+		return testFleet{name: name, start: start, end: end, srp: true, autoSrp: true, characterIDs: []uint{victim}}
+	}
+	withRegions := func(f testFleet, location map[string]interface{}) testFleet {
+		f.location = location
+		return f
+	}
+
+	tests := []struct {
+		name       string
+		fleets     []testFleet
+		submitted  bool // 已有补损申请
+		wantStatus int
+		wantFleet  string
+		wantReason string
+	}{
+		{name: "覆盖损失时间的舰队", fleets: []testFleet{auto("A", -time.Hour, time.Hour)},
+			wantStatus: srp.SrpMatchCreated, wantFleet: "A"},
+		{name: "没有参加舰队", fleets: []testFleet{{name: "A", start: -time.Hour, end: time.Hour, srp: true, autoSrp: true}},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "不在角色参加的任何舰队时间内"},
+		{name: "舰队已结束", fleets: []testFleet{auto("A", -time.Hour, -10*time.Minute)},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "不在角色参加的任何舰队时间内"},
+		{name: "舰队晚于损失开始", fleets: []testFleet{auto("A", 10*time.Minute, time.Hour)},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "不在角色参加的任何舰队时间内"},
+		{name: "未结束的舰队在最长时长内", fleets: []testFleet{auto("A", -2*time.Hour, 0)},
+			wantStatus: srp.SrpMatchCreated, wantFleet: "A"},
+		{name: "未结束的舰队超过最长时长", fleets: []testFleet{auto("A", -25*time.Hour, 0)},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "不在角色参加的任何舰队时间内"},
+		{name: "星域不匹配", fleets: []testFleet{withRegions(auto("A", -time.Hour, time.Hour), map[string]interface{}{"regionId": 10000002})},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "不在舰队[A]的星域内"},
+		{name: "星域列表匹配", fleets: []testFleet{withRegions(auto("A", -time.Hour, time.Hour), map[string]interface{}{"regionIds": []interface{}{10000002, "10000043"}})},
+			wantStatus: srp.SrpMatchCreated, wantFleet: "A"},
+		{name: "舰队不提供补损", fleets: []testFleet{{name: "A", start: -time.Hour, end: time.Hour, characterIDs: []uint{victim}}},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "舰队[A]不提供补损"},
+		{name: "舰队未开启自动补损", fleets: []testFleet{{name: "A", start: -time.Hour, end: time.Hour, srp: true, characterIDs: []uint{victim}}},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "舰队[A]未开启自动补损"},
+		{name: "使用最近开始的舰队", fleets: []testFleet{auto("A", -3*time.Hour, time.Hour), auto("B", -time.Hour, time.Hour)},
+			wantStatus: srp.SrpMatchCreated, wantFleet: "B"},
+		{name: "跳过不匹配的舰队", fleets: []testFleet{auto("A", -3*time.Hour, time.Hour), withRegions(auto("B", -time.Hour, time.Hour), map[string]interface{}{"regionId": 10000002})},
+			wantStatus: srp.SrpMatchCreated, wantFleet: "A"},
+		{
+			name: "使用通过检查最多的舰队的原因",
+			fleets: []testFleet{
+				withRegions(auto("A", -time.Hour, time.Hour), map[string]interface{}{"regionId": 10000002}),
+				{name: "B", start: -2 * time.Hour, end: time.Hour, srp: true, characterIDs: []uint{victim}},
+			},
+			wantStatus: srp.SrpMatchUnmatched, wantReason: "舰队[B]未开启自动补损",
+		},
+		{name: "已提交申请", fleets: []testFleet{auto("A", -time.Hour, time.Hour)}, submitted: true,
+			wantStatus: srp.SrpMatchSubmitted, wantFleet: "A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupSrp(t)
+			setupSde(t)
+			km := createLoss(t, esitest.FixtureKillmailID, victim, 7)
+			fleets := make(map[uint]string)
+			for _, tf := range tt.fleets {
+				f := tf.create(t)
+				fleets[f.ID] = f.FleetName
+			}
+			if tt.submitted {
+				if _, err := create(context.Background(), km, 1, "", "", Operator{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			match, err := MatchLoss(context.Background(), km)
+			if err != nil {
+				t.Fatalf("MatchLoss() error = %v", err)
+			}
+			if match.Status != tt.wantStatus || fleets[match.FleetID] != tt.wantFleet || !strings.Contains(match.Reason, tt.wantReason) {
+				t.Errorf("MatchLoss() = status %d fleet %q reason %q, want %d %q %q",
+					match.Status, fleets[match.FleetID], match.Reason, tt.wantStatus, tt.wantFleet, tt.wantReason)
+			}
+			if match.UserID != 7 {
+				t.Errorf("MatchLoss() user = %d, want 7", match.UserID)
+			}
+
+			// 未匹配时不创建申请
+			var requests int64
+			global.Db.Model(&srp.SrpRequest{}).Count(&requests)
+			wantRequests := int64(1)
+			if tt.wantStatus == srp.SrpMatchUnmatched {
+				wantRequests = 0
+			}
+			if requests != wantRequests {
+				t.Errorf("requests = %d, want %d", requests, wantRequests)
+			}
+		})
+	}
+}
+
+func TestMatchLossUnboundCharacter(t *testing.T) {
+	setupSrp(t)
+	km := createLoss(t, esitest.FixtureKillmailID, 2112000010, 0)
+	if _, err := MatchLoss(context.Background(), km); err == nil {
+		t.Error("MatchLoss() should fail for a character without a user")
+	}
+}
+
+func TestAutoMatch(t *testing.T) {
+	setupSrp(t)
+	setupSde(t)
+
+	// 两个绑定角色的损失和一个未绑定角色的损失，只有第一个角色参加了舰队
+	createLoss(t, 1001, 2112000010, 7)
+	createLoss(t, 1002, 2112000011, 8)
+	createLoss(t, 1003, 2112000012, 0)
+	testFleet{name: "A", start: -time.Hour, end: time.Hour, srp: true, autoSrp: true, characterIDs: []uint{2112000010}}.create(t)
+
+	since := lossTime.Add(-24 * time.Hour)
+	result, err := AutoMatch(context.Background(), since)
+	if err != nil {
+		t.Fatalf("AutoMatch() error = %v", err)
+	}
+	if result != (AutoResult{Created: 1, Unmatched: 1}) {
+		t.Errorf("AutoMatch() = %+v", result)
+	}
+
+	// 已创建申请的损失不再处理，未匹配的损失在舰队补录参加者后重新匹配
+	testFleet{name: "B", start: -time.Hour, end: time.Hour, srp: true, autoSrp: true, characterIDs: []uint{2112000011}}.create(t)
+	result, err = AutoMatch(context.Background(), since)
+	if err != nil {
+		t.Fatalf("AutoMatch() again error = %v", err)
+	}
+	if result != (AutoResult{Created: 1}) {
+		t.Errorf("AutoMatch() again = %+v", result)
+	}
+
+	// 早于since的损失不处理
+	if result, err := AutoMatch(context.Background(), lossTime.Add(time.Minute)); err != nil || result != (AutoResult{}) {
+		t.Errorf("AutoMatch() after loss = %+v, %v", result, err)
+	}
+}
+
+func TestReconcileEntries(t *testing.T) {
+	setupSrp(t)
+
+	// 用户1有两条申请且主角色与损失角色不同，用户2和3没有主角色，按损失角色收款
+	global.Db.Create(&systemModel.User{UserId: 1, MainCharacterId: 2112000001})
+	global.Db.Create(&character.UserCharacter{CharacterID: 2112000001, CharacterName: "Esitest Hunter", UserID: 1})
+	r1 := createRequest(t, 1, 2112000011, srp.SrpStatusApproved, 0)
+	r2 := createRequest(t, 1, 2112000012, srp.SrpStatusApproved, 0)
+	r3 := createRequest(t, 2, 2112000002, srp.SrpStatusApproved, 0)
+	r4 := createRequest(t, 3, 2112000010, srp.SrpStatusApproved, 0)
+
+	batch, err := CreateBatch("", nil, testOperator)
+	if err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	if len(batch.Items) != 3 || batch.Total != 320 {
+		t.Fatalf("batch = %d items, total %v", len(batch.Items), batch.Total)
+	}
+	items := make(map[int]srp.SrpPayoutItem)
+	for _, item := range batch.Items {
+		items[item.MainCharacterID] = item
+	}
+	if item := items[2112000001]; item.Amount != 160 || item.RequestCount != 2 || item.MainCharacterName != "Esitest Hunter" {
+		t.Fatalf("main character item = %+v", item)
+	}
+	if _, err := CreateBatch("", nil, testOperator); !errors.Is(err, ErrNoApprovedRequest) {
+		t.Errorf("CreateBatch() again error = %v, want %v", err, ErrNoApprovedRequest)
+	}
+
+	now := time.Now().Add(time.Minute)
+	entries := []JournalEntry{
+		// 备注匹配
+		{ID: 1, Date: now, SecondPartyID: 2112000001, Amount: -160, Reason: "pay " + items[2112000001].Reference},
+		// 没有备注时按金额匹配
+		{ID: 2, Date: now, SecondPartyID: 2112000002, Amount: -80},
+		// 金额不符，备注只是前缀相同，不按备注匹配
+		{ID: 3, Date: now, SecondPartyID: 2112000010, Amount: -75, Reason: items[2112000010].Reference + "0"},
+		// 批次创建之前的转账不参与匹配
+		{ID: 4, Date: now.Add(-time.Hour), SecondPartyID: 2112000010, Amount: -80},
+	}
+
+	result, err := ReconcileEntries(entries)
+	if err != nil {
+		t.Fatalf("ReconcileEntries() error = %v", err)
+	}
+	if result != (ReconcileResult{Entries: 4, Paid: 2, Mismatch: 1}) {
+		t.Errorf("ReconcileEntries() = %+v", result)
+	}
+
+	for _, tt := range []struct {
+		request *srp.SrpRequest
+		want    int
+	}{
+		{r1, srp.SrpStatusPaid},
+		{r2, srp.SrpStatusPaid},
+		{r3, srp.SrpStatusPaid},
+		{r4, srp.SrpStatusApproved},
+	} {
+		if got := getRequest(t, tt.request.ID); got.Status != tt.want {
+			t.Errorf("request %d status = %d, want %d", tt.request.ID, got.Status, tt.want)
+		}
+	}
+
+	var mismatch srp.SrpPayoutItem
+	global.Db.First(&mismatch, items[2112000010].ID)
+	if mismatch.Status != srp.SrpPayoutMismatch || mismatch.JournalID != 0 {
+		t.Errorf("mismatch item = %+v", mismatch)
+	}
+
+	// 已使用的流水不会重复匹配，金额不符的明细不会重复记录
+	if result, err := ReconcileEntries(entries); err != nil || result.Paid != 0 || result.Mismatch != 0 {
+		t.Errorf("ReconcileEntries() again = %+v, %v", result, err)
+	}
+
+	// 人工确认最后一条明细后批次完成
+	if _, err := ConfirmPayoutItem(mismatch.ID, "已线下补齐", testOperator); err != nil {
+		t.Fatalf("ConfirmPayoutItem() error = %v", err)
+	}
+	batch, err = GetBatch(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != srp.SrpBatchCompleted || batch.PaidTotal != 320 {
+		t.Errorf("batch status = %d, paid total = %v", batch.Status, batch.PaidTotal)
+	}
+	if got := getRequest(t, r4.ID); got.Status != srp.SrpStatusPaid {
+		t.Errorf("request %d status = %d, want paid", r4.ID, got.Status)
+	}
+}
+
+func TestHasReference(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   bool
+	}{
+		{"SRP1-1", true},
+		{"pay SRP1-1 thanks", true},
+		{"补损SRP1-1。", true},
+		{"srp1-1", true},
+		{"SRP1-12", false},
+		{"SRP11-1", false},
+		{"xSRP1-1", false},
+		{"SRP1-1-2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := hasReference(tt.reason, "SRP1-1"); got != tt.want {
+			t.Errorf("hasReference(%q) = %v, want %v", tt.reason, got, tt.want)
+		}
+	}
+	if hasReference("SRP1-1", "") {
+		t.Error("empty reference should never match")
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		selectIDs  func(approved, pending, batched *srp.SrpRequest) []uint
+		wantErr    error
+		wantTotal  float64
+		wantInItem []bool // approved, pending, batched 是否加入新批次
+	}{
+		{
+			name:       "全部已批准申请",
+			selectIDs:  func(approved, pending, batched *srp.SrpRequest) []uint { return nil },
+			wantTotal:  80,
+			wantInItem: []bool{true, false, false},
+		},
+		{
+			name:       "指定申请",
+			selectIDs:  func(approved, pending, batched *srp.SrpRequest) []uint { return []uint{approved.ID, pending.ID} },
+			wantTotal:  80,
+			wantInItem: []bool{true, false, false},
+		},
+		{
+			name:       "待审核和已加入批次的申请不能发放",
+			selectIDs:  func(approved, pending, batched *srp.SrpRequest) []uint { return []uint{pending.ID, batched.ID} },
+			wantErr:    ErrNoApprovedRequest,
+			wantInItem: []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupSrp(t)
+			approved := createRequest(t, 1, 2112000010, srp.SrpStatusApproved, 0)
+			pending := createRequest(t, 1, 2112000010, srp.SrpStatusPending, 0)
+			batched := createRequest(t, 2, 2112000002, srp.SrpStatusApproved, 99)
+
+			batch, err := CreateBatch("批次", tt.selectIDs(approved, pending, batched), testOperator)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateBatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if batch.Total != tt.wantTotal || len(batch.Items) != 1 || batch.Status != srp.SrpBatchOpen {
+					t.Errorf("CreateBatch() = %+v", batch)
+				}
+				if item := batch.Items[0]; item.Reference != fmt.Sprintf("SRP%d-%d", batch.ID, item.ID) || item.Status != srp.SrpPayoutPending {
+					t.Errorf("item = %+v", item)
+				}
+			}
+
+			for i, request := range []*srp.SrpRequest{approved, pending, batched} {
+				got := getRequest(t, request.ID)
+				inItem := got.PayoutItemID != 0 && got.PayoutItemID != request.PayoutItemID
+				if inItem != tt.wantInItem[i] {
+					t.Errorf("request %d payout item = %d, want in new batch %v", i, got.PayoutItemID, tt.wantInItem[i])
+				}
+				if got.Status != request.Status {
+					t.Errorf("request %d status = %d, want unchanged %d", i, got.Status, request.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmPayoutItem(t *testing.T) {
+	setupSrp(t)
+	r1 := createRequest(t, 1, 2112000010, srp.SrpStatusApproved, 0)
+	r2 := createRequest(t, 1, 2112000010, srp.SrpStatusApproved, 0)
+	r3 := createRequest(t, 2, 2112000002, srp.SrpStatusApproved, 0)
+	batch, err := CreateBatch("", nil, testOperator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := batch.Items[0]
+
+	if _, err := ConfirmPayoutItem(item.ID, "", testOperator); !errors.Is(err, ErrCommentRequired) {
+		t.Errorf("ConfirmPayoutItem() without comment error = %v", err)
+	}
+	if _, err := ConfirmPayoutItem(9999, "x", testOperator); !errors.Is(err, ErrPayoutItemNotFound) {
+		t.Errorf("ConfirmPayoutItem() unknown item error = %v", err)
+	}
+
+	confirmed, err := ConfirmPayoutItem(item.ID, "已转账", testOperator)
+	if err != nil {
+		t.Fatalf("ConfirmPayoutItem() error = %v", err)
+	}
+	if confirmed.Status != srp.SrpPayoutPaid || confirmed.PaidAmount != 160 || confirmed.PaidTime == nil {
+		t.Errorf("ConfirmPayoutItem() = %+v", confirmed)
+	}
+	if _, err := ConfirmPayoutItem(item.ID, "已转账", testOperator); !errors.Is(err, ErrPayoutItemPaid) {
+		t.Errorf("ConfirmPayoutItem() again error = %v", err)
+	}
+
+	// 明细包含的申请全部发放并记录日志，其他明细的申请不变
+	for _, request := range []*srp.SrpRequest{r1, r2} {
+		got := getRequest(t, request.ID)
+		if got.Status != srp.SrpStatusPaid || got.PaidAmount != 80 || got.PayerID != testOperator.ID {
+			t.Errorf("request %d = status %d paid %v payer %d", got.ID, got.Status, got.PaidAmount, got.PayerID)
+		}
+		if len(got.Logs) != 1 || got.Logs[0].Action != srp.SrpActionPay || got.Logs[0].Comment != "已转账" {
+			t.Errorf("request %d logs = %+v", got.ID, got.Logs)
+		}
+	}
+	if got := getRequest(t, r3.ID); got.Status != srp.SrpStatusApproved {
+		t.Errorf("request %d status = %d, want approved", got.ID, got.Status)
+	}
+
+	batch, err = GetBatch(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Status != srp.SrpBatchOpen || batch.PaidTotal != 160 {
+		t.Errorf("batch status = %d, paid total = %v", batch.Status, batch.PaidTotal)
+	}
+}
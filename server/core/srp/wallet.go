@@ -0,0 +1,247 @@
+package srp
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/token"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/srp"
+	characterRepo "eve-corp-manager/repository/service/character"
+	srpRepo "eve-corp-manager/repository/service/srp"
+	"eve-corp-manager/utils"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ScopeCorporationWallet 读取公司钱包流水所需的ESI权限，需要总监或会计角色
+const ScopeCorporationWallet = "esi-wallet.read_corporation_wallets.v1"
+
+// walletDivisions 公司钱包分部数量
+const walletDivisions = 7
+
+// 补损转账可能出现的流水类型，从公司钱包直接转给角色为corporation_account_withdrawal
+var payoutRefTypes = []string{"player_donation", "corporation_account_withdrawal"}
+
+// JournalEntry 公司钱包流水
+type JournalEntry struct {
+	ID            int64     `json:"id"`
+	Date          time.Time `json:"date"`
+	RefType       string    `json:"ref_type"`
+	FirstPartyID  int       `json:"first_party_id"`
+	SecondPartyID int       `json:"second_party_id"`
+	Amount        float64   `json:"amount"` // 转出为负数
+	Reason        string    `json:"reason"`
+	Description   string    `json:"description"`
+}
+
+// ReconcileResult 一次钱包对账的结果
+type ReconcileResult struct {
+	Entries  int // 读取的转账流水数量
+	Paid     int // 确认发放的明细数量
+	Mismatch int // 金额不符的明细数量
+}
+
+// FetchPayoutJournal 读取公司所有钱包分部中since之后的转出流水
+func FetchPayoutJournal(ctx context.Context, corpID uint, tokenSource esi.TokenSource, since time.Time) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	for division := 1; division <= walletDivisions; division++ {
+		path := fmt.Sprintf("/corporations/%d/wallets/%d/journal/", corpID, division)
+		journal, err := esi.AuthorizedGetAllPages[JournalEntry](ctx, esi.EsiClient, path, nil, tokenSource)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range journal {
+			if entry.Amount < 0 && !entry.Date.Before(since) && slices.Contains(payoutRefTypes, entry.RefType) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Reconcile 读取已授权钱包权限的公司的钱包流水，确认未发放的明细
+// 每个公司依次尝试拥有钱包权限的角色，直到某个角色的令牌可用(需要总监或会计角色)
+func Reconcile(ctx context.Context) (ReconcileResult, error) {
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	items, err := repo.UnpaidItems()
+	if err != nil || len(items) == 0 {
+		return ReconcileResult{}, err
+	}
+	since := items[0].CreatedAt
+	for _, item := range items {
+		if item.CreatedAt.Before(since) {
+			since = item.CreatedAt
+		}
+	}
+
+	userCharacterRepo := characterRepo.UserCharacterRepository{DB: global.Db}
+	characters, err := userCharacterRepo.GetAllInAllowedCorp()
+	if err != nil {
+		return ReconcileResult{}, err
+	}
+	accountants := make(map[uint][]character.UserCharacter)
+	var corpIDs []uint
+	for _, char := range characters {
+		if char.Status == character.CharacterStatusInvalid || !slices.Contains(strings.Fields(char.Scopes), ScopeCorporationWallet) {
+			continue
+		}
+		if len(accountants[char.CorpID]) == 0 {
+			corpIDs = append(corpIDs, char.CorpID)
+		}
+		accountants[char.CorpID] = append(accountants[char.CorpID], char)
+	}
+	if len(corpIDs) == 0 {
+		return ReconcileResult{}, errors.New("没有授权公司钱包权限的角色")
+	}
+
+	var entries []JournalEntry
+	for _, corpID := range corpIDs {
+		for _, accountant := range accountants[corpID] {
+			journal, err := FetchPayoutJournal(ctx, corpID, token.ESITokens.Source(accountant.CharacterID), since)
+			if ctx.Err() != nil {
+				return ReconcileResult{}, ctx.Err()
+			}
+			if err != nil {
+				// 没有钱包角色时返回403，换下一个角色
+				global.Logger.Warnf("使用角色%v读取公司%v钱包流水失败: %v", accountant.CharacterID, corpID, err)
+				continue
+			}
+			entries = append(entries, journal...)
+			break
+		}
+	}
+
+	return ReconcileEntries(entries)
+}
+
+// ReconcileEntries 用转账流水确认未发放的明细
+// 优先按转账原因中的备注匹配，备注匹配但金额不符时标记为金额不符；没有备注时按收款角色和金额匹配
+// 收款角色在批次创建后收到转账但金额都不符时同样标记为金额不符，等待人工确认
+func ReconcileEntries(entries []JournalEntry) (ReconcileResult, error) {
+	result := ReconcileResult{Entries: len(entries)}
+	repo := srpRepo.SrpRepository{DB: global.Db}
+	items, err := repo.UnpaidItems()
+	if err != nil {
+		return result, err
+	}
+
+	journalIDs := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		journalIDs = append(journalIDs, entry.ID)
+	}
+	used, err := repo.UsedJournalIDs(journalIDs)
+	if err != nil {
+		return result, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		var candidates []*JournalEntry
+		for j := range entries {
+			entry := &entries[j]
+			if entry.SecondPartyID == item.MainCharacterID && !used[entry.ID] && !entry.Date.Before(item.CreatedAt) {
+				candidates = append(candidates, entry)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		var matched *JournalEntry
+		for _, entry := range candidates {
+			if hasReference(entry.Reason, item.Reference) {
+				matched = entry
+				break
+			}
+		}
+		if matched == nil {
+			for _, entry := range candidates {
+				if amountEqual(-entry.Amount, item.Amount) {
+					matched = entry
+					break
+				}
+			}
+		}
+
+		if matched == nil {
+			if item.Status == srp.SrpPayoutMismatch {
+				continue
+			}
+			amounts := make([]string, 0, len(candidates))
+			for _, entry := range candidates {
+				amounts = append(amounts, fmt.Sprintf("%.2f", -entry.Amount))
+			}
+			item.Status = srp.SrpPayoutMismatch
+			item.Note = fmt.Sprintf("收款角色收到转账%s ISK，与应发金额%.2f ISK不符", strings.Join(amounts, ", "), item.Amount)
+			if err := repo.UpdateItem(item); err != nil {
+				return result, err
+			}
+			result.Mismatch++
+			continue
+		}
+
+		used[matched.ID] = true
+		paidTime := matched.Date
+		item.JournalID, item.PaidAmount, item.PaidTime = matched.ID, -matched.Amount, &paidTime
+		if !amountEqual(-matched.Amount, item.Amount) {
+			item.Status = srp.SrpPayoutMismatch
+			item.Note = fmt.Sprintf("流水%d转账%.2f ISK，与应发金额%.2f ISK不符", matched.ID, -matched.Amount, item.Amount)
+			if err := repo.UpdateItem(item); err != nil {
+				return result, err
+			}
+			result.Mismatch++
+			continue
+		}
+
+		item.Note = fmt.Sprintf("钱包流水%d确认发放", matched.ID)
+		if err := markPaid(item, item.Note, Operator{}); err != nil {
+			return result, err
+		}
+		result.Paid++
+	}
+
+	return result, nil
+}
+
+// RunReconcile 按interval定时执行钱包对账，直到ctx取消
+func RunReconcile(ctx context.Context, interval time.Duration) error {
+	for {
+		result, err := Reconcile(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			global.Logger.Errorf("补损钱包对账失败: %v", err)
+		}
+		if result.Paid > 0 || result.Mismatch > 0 {
+			global.Logger.Infof("补损钱包对账完成, 确认发放%v条, 金额不符%v条", result.Paid, result.Mismatch)
+		}
+
+		if !utils.SleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// hasReference 转账原因中是否包含完整的转账备注，备注前后不能紧接字母、数字或连字符，避免SRP1-1匹配SRP1-12
+func hasReference(reason, reference string) bool {
+	if reference == "" {
+		return false
+	}
+	tokens := strings.FieldsFunc(reason, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-')
+	})
+	for _, token := range tokens {
+		if strings.EqualFold(token, reference) {
+			return true
+		}
+	}
+	return false
+}
+
+// amountEqual 按ISK的最小单位0.01比较金额
+func amountEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
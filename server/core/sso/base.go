@@ -0,0 +1,181 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuthorizeURL = "https://login.eveonline.com/v2/oauth/authorize"
+	defaultTokenURL     = "https://login.eveonline.com/v2/oauth/token"
+	defaultJwksURL      = "https://login.eveonline.com/oauth/jwks"
+	defaultIssuer       = "https://login.eveonline.com"
+)
+
+// Options SSO客户端配置
+type Options struct {
+	ClientID     string
+	ClientSecret string
+	CallbackURL  string
+	Scopes       []string
+	AuthorizeURL string // 留空使用官方地址
+	TokenURL     string // 留空使用官方地址
+	JwksURL      string // 留空使用官方地址
+	Issuer       string // 留空使用官方地址
+	HTTPClient   *http.Client
+}
+
+// Client EVE SSO v2 客户端
+type Client struct {
+	opts Options
+
+	jwksMu      sync.RWMutex
+	jwks        map[string]interface{} // kid -> 公钥
+	jwksFetched time.Time
+}
+
+// Token SSO令牌响应
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Error SSO返回的错误
+type Error struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("SSO错误 (状态码: %d): %s %s", e.StatusCode, e.Code, e.Description)
+}
+
+// SsoClient 全局SSO客户端实例
+var SsoClient *Client
+
+// NewClient 创建一个新的SSO客户端
+func NewClient(opts Options) *Client {
+	if opts.AuthorizeURL == "" {
+		opts.AuthorizeURL = defaultAuthorizeURL
+	}
+	if opts.TokenURL == "" {
+		opts.TokenURL = defaultTokenURL
+	}
+	if opts.JwksURL == "" {
+		opts.JwksURL = defaultJwksURL
+	}
+	if opts.Issuer == "" {
+		opts.Issuer = defaultIssuer
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{opts: opts}
+}
+
+// NewPKCE 生成PKCE的code_verifier和code_challenge(S256)
+func NewPKCE() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
+
+// NewState 生成随机state
+func NewState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthorizeURL 构造跳转到SSO的授权地址
+func (c *Client) AuthorizeURL(state, codeChallenge string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", c.opts.CallbackURL)
+	query.Set("client_id", c.opts.ClientID)
+	query.Set("scope", strings.Join(c.opts.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	return c.opts.AuthorizeURL + "?" + query.Encode()
+}
+
+// ExchangeCode 使用授权码换取令牌
+func (c *Client) ExchangeCode(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("client_id", c.opts.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	return c.postToken(ctx, form)
+}
+
+// RefreshToken 使用refresh_token刷新令牌
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", c.opts.ClientID)
+
+	return c.postToken(ctx, form)
+}
+
+// postToken 请求令牌端点
+func (c *Client) postToken(ctx context.Context, form url.Values) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.opts.ClientSecret != "" {
+		req.SetBasicAuth(c.opts.ClientID, c.opts.ClientSecret)
+	}
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		ssoErr := &Error{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(body, ssoErr)
+		return nil, ssoErr
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("解析SSO令牌失败: %w", err)
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,204 @@
+package sso
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksCacheTTL JWKS公钥缓存时间
+const jwksCacheTTL = time.Hour
+
+// Scopes 授权范围，SSO在只有一个scope时返回字符串，多个时返回数组
+type Scopes []string
+
+// UnmarshalJSON 兼容字符串和数组两种格式
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = Scopes{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// CharacterClaims SSO访问令牌中的角色信息
+type CharacterClaims struct {
+	jwt.RegisteredClaims
+	Name   string `json:"name"`
+	Owner  string `json:"owner"`
+	Scopes Scopes `json:"scp"`
+	Azp    string `json:"azp"`
+}
+
+// CharacterID 从sub(CHARACTER:EVE:<id>)中解析角色ID
+func (c *CharacterClaims) CharacterID() (uint, error) {
+	parts := strings.Split(c.Subject, ":")
+	if len(parts) != 3 || parts[0] != "CHARACTER" {
+		return 0, fmt.Errorf("无效的sub: %s", c.Subject)
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的角色ID: %s", parts[2])
+	}
+	return uint(id), nil
+}
+
+// jwk JWKS中的单个公钥
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// VerifyAccessToken 使用SSO的JWKS校验访问令牌并返回角色信息
+func (c *Client) VerifyAccessToken(accessToken string) (*CharacterClaims, error) {
+	claims := &CharacterClaims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, c.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("SSO令牌校验失败: %w", err)
+	}
+
+	// EVE签发的iss可能带或不带协议头
+	issuer := strings.TrimPrefix(strings.TrimPrefix(c.opts.Issuer, "https://"), "http://")
+	if claims.Issuer != c.opts.Issuer && claims.Issuer != issuer {
+		return nil, fmt.Errorf("SSO令牌签发者无效: %s", claims.Issuer)
+	}
+
+	audOk := false
+	for _, aud := range claims.Audience {
+		if aud == c.opts.ClientID {
+			audOk = true
+			break
+		}
+	}
+	if !audOk {
+		return nil, errors.New("SSO令牌受众无效")
+	}
+
+	return claims, nil
+}
+
+// keyFunc 根据kid返回对应公钥，未命中时重新拉取JWKS
+func (c *Client) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	c.jwksMu.RLock()
+	key, ok := c.jwks[kid]
+	fresh := time.Since(c.jwksFetched) < jwksCacheTTL
+	c.jwksMu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := c.fetchJwks(); err != nil {
+		return nil, err
+	}
+
+	c.jwksMu.RLock()
+	defer c.jwksMu.RUnlock()
+	if key, ok := c.jwks[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未找到kid对应的公钥: %s", kid)
+}
+
+// fetchJwks 拉取并解析JWKS
+func (c *Client) fetchJwks() error {
+	resp, err := c.opts.HTTPClient.Get(c.opts.JwksURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("获取JWKS失败 (状态码: %d)", resp.StatusCode)
+	}
+
+	var data struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{}, len(data.Keys))
+	for _, k := range data.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	c.jwksMu.Lock()
+	c.jwks = keys
+	c.jwksFetched = time.Now()
+	c.jwksMu.Unlock()
+
+	return nil
+}
+
+// publicKey 将JWK转换为公钥
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+	}
+}
@@ -0,0 +1,149 @@
+package system
+
+import (
+	"eve-corp-manager/core/cache"
+	"eve-corp-manager/models/system"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// SuperRoleCode 超级管理员角色编码，拥有全部权限
+const SuperRoleCode = "super"
+
+// 接口权限码
+const (
+	PermCorpPapAdd     = "Service:CorpPap:Add"
+	PermCorpPapConsume = "Service:CorpPap:Consume"
+	PermRoleManage     = "System:Role:Manage"
+	PermMenuManage     = "System:Menu:Manage"
+	PermUserRole       = "System:User:Role"
+	PermEsiStatus      = "System:Esi:Status"
+	PermSrpReview      = "Service:Srp:Review"
+	PermSrpPay         = "Service:Srp:Pay"
+	PermSrpRule        = "Service:Srp:Rule"
+	PermFleetManage    = "Service:Fleet:Manage"
+)
+
+// UserPermission 用户的角色和权限
+type UserPermission struct {
+	Roles   []string `json:"roles"`   // 角色编码
+	Codes   []string `json:"codes"`   // 权限码
+	MenuIds []uint   `json:"menuIds"` // 可访问的菜单ID
+	Super   bool     `json:"super"`   // 是否超级管理员
+	Active  bool     `json:"active"`  // 用户存在且未被禁用
+}
+
+// Permissions 用户权限管理结构，同时缓存用户状态，禁用的用户在缓存过期或清空后失去访问权限
+type Permissions struct {
+	db    *gorm.DB
+	cache cache.Cache[UserPermission]
+}
+
+// NewPermissions 创建用户权限管理器
+func NewPermissions(db *gorm.DB, redisClient *redis.Client) *Permissions {
+	// 权限变更时会主动清空缓存，这里的过期时间只是兜底
+	redisCache := cache.NewRedisCache[UserPermission](redisClient, "sys:permissions", time.Minute*10, time.Minute*10)
+
+	return &Permissions{
+		db:    db,
+		cache: redisCache,
+	}
+}
+
+// Get 获取用户的角色和权限
+func (p *Permissions) Get(userID uint) (UserPermission, error) {
+	key := strconv.FormatUint(uint64(userID), 10)
+	if value, found := p.cache.Get(key); found {
+		return value, nil
+	}
+
+	value, err := p.loadFromDB(userID)
+	if err != nil {
+		return value, err
+	}
+
+	p.cache.SetDefault(key, value)
+	return value, nil
+}
+
+// HasCode 判断用户是否拥有权限码
+func (p *Permissions) HasCode(userID uint, code string) (bool, error) {
+	perm, err := p.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	if perm.Super {
+		return true, nil
+	}
+	for _, c := range perm.Codes {
+		if c == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Flush 清空权限缓存，角色、菜单或授权变化后调用
+func (p *Permissions) Flush() {
+	p.cache.Flush()
+}
+
+func (p *Permissions) loadFromDB(userID uint) (UserPermission, error) {
+	perm := UserPermission{
+		Roles:   []string{},
+		Codes:   []string{},
+		MenuIds: []uint{},
+	}
+
+	var user system.User
+	err := p.db.Select("user_id", "status").Where("user_id = ?", userID).Limit(1).Find(&user).Error
+	if err != nil {
+		return perm, err
+	}
+	// 已删除或禁用的用户没有任何权限
+	perm.Active = user.UserId != 0 && user.Status != system.UserStatusDisabled
+	if !perm.Active {
+		return perm, nil
+	}
+
+	var roles []system.SysRole
+	err = p.db.Where("status = ? AND id IN (?)", system.StatusEnabled,
+		p.db.Model(&system.Role{}).Select("role_id").Where("user_id = ?", userID),
+	).Find(&roles).Error
+	if err != nil {
+		return perm, err
+	}
+	if len(roles) == 0 {
+		return perm, nil
+	}
+
+	roleIds := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.ID)
+		perm.Roles = append(perm.Roles, role.Code)
+		if role.Code == SuperRoleCode {
+			perm.Super = true
+		}
+	}
+
+	var menus []system.Menu
+	db := p.db.Where("status = ?", system.StatusEnabled)
+	if !perm.Super {
+		db = db.Where("id IN (?)", p.db.Model(&system.RoleMenu{}).Select("menu_id").Where("role_id IN ?", roleIds))
+	}
+	if err := db.Find(&menus).Error; err != nil {
+		return perm, err
+	}
+
+	for _, menu := range menus {
+		perm.MenuIds = append(perm.MenuIds, menu.ID)
+		if menu.AuthCode != "" {
+			perm.Codes = append(perm.Codes, menu.AuthCode)
+		}
+	}
+
+	return perm, nil
+}
@@ -0,0 +1,205 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/sso"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	characterRepo "eve-corp-manager/repository/service/character"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenKeyPrefix = "esi:access_token:" // 角色ID -> 访问令牌
+	refreshLockKeyPrefix = "esi:refresh_lock:" // 角色ID -> 刷新锁，防止多实例同时刷新
+
+	// expiryMargin 提前过期时间，避免令牌在请求途中过期
+	expiryMargin = time.Minute
+	// refreshLockTTL 刷新锁的最长持有时间
+	refreshLockTTL = 15 * time.Second
+)
+
+// releaseLockScript 只删除自己持有的锁，锁超时后可能已被其他实例获得
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var (
+	ErrCharacterInvalid = errors.New("角色授权已失效，需要重新登录")
+	ErrNoRefreshToken   = errors.New("角色没有refresh token")
+)
+
+// Manager 管理各角色的ESI访问令牌
+type Manager struct {
+	redis *redis.Client
+	db    *gorm.DB
+	sso   *sso.Client
+
+	locks sync.Map // 角色ID -> *sync.Mutex
+}
+
+// ESITokens 全局ESI令牌管理器
+var ESITokens *Manager
+
+// NewManager 创建ESI令牌管理器
+func NewManager(redisClient *redis.Client, db *gorm.DB, ssoClient *sso.Client) *Manager {
+	return &Manager{
+		redis: redisClient,
+		db:    db,
+		sso:   ssoClient,
+	}
+}
+
+// Source 获取角色的令牌源，可直接传给esi.Client的授权请求
+func (m *Manager) Source(characterID uint) esi.TokenSource {
+	return &Source{manager: m, characterID: characterID}
+}
+
+// Source 单个角色的令牌源
+type Source struct {
+	manager     *Manager
+	characterID uint
+}
+
+// Token 返回缓存的访问令牌，过期时使用refresh token刷新
+func (s *Source) Token(ctx context.Context) (string, error) {
+	return s.manager.token(ctx, s.characterID)
+}
+
+// CacheKey 同一角色的授权响应共用缓存
+func (s *Source) CacheKey() string {
+	return "character:" + strconv.FormatUint(uint64(s.characterID), 10)
+}
+
+// Invalidate 删除缓存的访问令牌
+func (s *Source) Invalidate(ctx context.Context) {
+	s.manager.redis.Del(ctx, accessTokenKey(s.characterID))
+}
+
+// Store 缓存SSO登录时获得的访问令牌
+func (m *Manager) Store(ctx context.Context, characterID uint, accessToken string, expiresIn int) error {
+	ttl := time.Duration(expiresIn)*time.Second - expiryMargin
+	if ttl <= 0 {
+		return nil
+	}
+	return m.redis.Set(ctx, accessTokenKey(characterID), accessToken, ttl).Err()
+}
+
+func (m *Manager) token(ctx context.Context, characterID uint) (string, error) {
+	if accessToken, err := m.redis.Get(ctx, accessTokenKey(characterID)).Result(); err == nil {
+		return accessToken, nil
+	}
+
+	// 同一进程内按角色串行刷新
+	lockValue, _ := m.locks.LoadOrStore(characterID, &sync.Mutex{})
+	lock := lockValue.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// 等待锁期间可能已被其他请求刷新
+	if accessToken, err := m.redis.Get(ctx, accessTokenKey(characterID)).Result(); err == nil {
+		return accessToken, nil
+	}
+
+	// 多实例之间通过Redis锁串行刷新，未抢到锁时等待其他实例写入缓存
+	lockKey := refreshLockKeyPrefix + strconv.FormatUint(uint64(characterID), 10)
+	owner, err := lockOwner()
+	if err != nil {
+		return "", err
+	}
+	deadline := time.Now().Add(refreshLockTTL)
+	for {
+		ok, err := m.redis.SetNX(ctx, lockKey, owner, refreshLockTTL).Result()
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			return "", errors.New("等待其他实例刷新令牌超时")
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+		if accessToken, err := m.redis.Get(ctx, accessTokenKey(characterID)).Result(); err == nil {
+			return accessToken, nil
+		}
+	}
+	defer releaseLockScript.Run(context.WithoutCancel(ctx), m.redis, []string{lockKey}, owner)
+
+	// 抢到锁前其他实例可能刚好完成刷新并释放锁
+	if accessToken, err := m.redis.Get(ctx, accessTokenKey(characterID)).Result(); err == nil {
+		return accessToken, nil
+	}
+
+	return m.refresh(ctx, characterID)
+}
+
+// refresh 使用refresh token换取新的访问令牌并写入缓存
+func (m *Manager) refresh(ctx context.Context, characterID uint) (string, error) {
+	repo := characterRepo.UserCharacterRepository{DB: m.db}
+	userCharacter, err := repo.Get(characterID)
+	if err != nil {
+		return "", err
+	}
+	if userCharacter.Status == character.CharacterStatusInvalid {
+		return "", ErrCharacterInvalid
+	}
+	if userCharacter.RefreshToken == "" {
+		return "", ErrNoRefreshToken
+	}
+
+	token, err := m.sso.RefreshToken(ctx, userCharacter.RefreshToken)
+	if err != nil {
+		var ssoErr *sso.Error
+		if errors.As(err, &ssoErr) && ssoErr.Code == "invalid_grant" {
+			global.Logger.Warnf("角色refresh token已失效, characterID: %v", characterID)
+			if err := repo.UpdateStatus(characterID, character.CharacterStatusInvalid); err != nil {
+				return "", err
+			}
+			return "", ErrCharacterInvalid
+		}
+		return "", err
+	}
+
+	// SSO可能轮换refresh token
+	if token.RefreshToken != "" && token.RefreshToken != userCharacter.RefreshToken {
+		if err := repo.UpdateRefreshToken(characterID, token.RefreshToken); err != nil {
+			return "", err
+		}
+	}
+
+	if err := m.Store(ctx, characterID, token.AccessToken, token.ExpiresIn); err != nil {
+		global.Logger.Errorf("缓存ESI访问令牌失败, characterID: %v, error: %v", characterID, err)
+	}
+
+	return token.AccessToken, nil
+}
+
+func accessTokenKey(characterID uint) string {
+	return accessTokenKeyPrefix + strconv.FormatUint(uint64(characterID), 10)
+}
+
+// lockOwner 生成刷新锁的持有者标识
+func lockOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
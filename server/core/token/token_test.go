@@ -0,0 +1,208 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/core/sso"
+	"eve-corp-manager/models/service/character"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	characterID  = 2112000001
+	refreshToken = "esitest-refresh-token"
+)
+
+// setupManager 启动模拟SSO和Redis，写入一个授权有效的角色并创建令牌管理器
+func setupManager(t *testing.T) (*Manager, *esitest.Server, *esitest.Redis, *gorm.DB) {
+	t.Helper()
+	srv := esitest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddRefreshToken(refreshToken, esitest.Character{CharacterID: characterID, Name: "Esitest Pilot"})
+
+	redisServer := esitest.NewRedis()
+	t.Cleanup(redisServer.Close)
+	redisServer.HandleScript(releaseLockScript.Hash(), func(data map[string]string, keys, args []string) interface{} {
+		if data[keys[0]] == args[0] {
+			delete(data, keys[0])
+			return 1
+		}
+		return 0
+	})
+	redisClient := redisServer.Client()
+	t.Cleanup(func() { redisClient.Close() })
+
+	db := esitest.UseDB(t, &character.UserCharacter{})
+	db.Create(&character.UserCharacter{CharacterID: characterID, RefreshToken: refreshToken, Status: character.CharacterStatusValid})
+
+	return NewManager(redisClient, db, sso.NewClient(srv.SSOOptions("esitest"))), srv, redisServer, db
+}
+
+// storedCharacter 读取数据库中的角色
+func storedCharacter(t *testing.T, db *gorm.DB) character.UserCharacter {
+	t.Helper()
+	var userCharacter character.UserCharacter
+	if err := db.First(&userCharacter, characterID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return userCharacter
+}
+
+func TestTokenCached(t *testing.T) {
+	m, srv, redisServer, _ := setupManager(t)
+	redisServer.Set(accessTokenKey(characterID), "cached-access-token", time.Minute)
+
+	accessToken, err := m.Source(characterID).Token(context.Background())
+	if err != nil || accessToken != "cached-access-token" {
+		t.Fatalf("Token() = %q, %v, want cached token", accessToken, err)
+	}
+	if n := srv.Refreshes(); n != 0 {
+		t.Errorf("refreshed %d times, want 0", n)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate bool
+	}{
+		{"refresh token不变", false},
+		{"SSO轮换refresh token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, srv, redisServer, db := setupManager(t)
+			srv.SetRotateRefreshToken(tt.rotate)
+			ctx := context.Background()
+
+			accessToken, err := m.Source(characterID).Token(ctx)
+			if err != nil || accessToken == "" {
+				t.Fatalf("Token() = %q, %v", accessToken, err)
+			}
+			if cached, _ := redisServer.Get(accessTokenKey(characterID)); cached != accessToken {
+				t.Errorf("cached = %q, want refreshed token", cached)
+			}
+			// SSO令牌有效期20分钟，提前1分钟过期
+			if ttl := redisServer.TTL(accessTokenKey(characterID)); ttl <= 18*time.Minute || ttl > 19*time.Minute {
+				t.Errorf("ttl = %v, want about 19m", ttl)
+			}
+			if _, locked := redisServer.Get(refreshLockKeyPrefix + "2112000001"); locked {
+				t.Error("刷新后未释放刷新锁")
+			}
+
+			stored := storedCharacter(t, db).RefreshToken
+			if rotated := stored != refreshToken; rotated != tt.rotate {
+				t.Errorf("stored refresh token = %q, rotated %v, want %v", stored, rotated, tt.rotate)
+			}
+
+			// 之后直接使用缓存
+			if again, err := m.Source(characterID).Token(ctx); err != nil || again != accessToken {
+				t.Errorf("second Token() = %q, %v, want cached token", again, err)
+			}
+			if n := srv.Refreshes(); n != 1 {
+				t.Errorf("refreshed %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestTokenInvalidGrant(t *testing.T) {
+	m, srv, _, db := setupManager(t)
+	srv.RevokeRefreshToken(refreshToken)
+
+	if _, err := m.Source(characterID).Token(context.Background()); !errors.Is(err, ErrCharacterInvalid) {
+		t.Fatalf("Token() error = %v, want ErrCharacterInvalid", err)
+	}
+	if status := storedCharacter(t, db).Status; status != character.CharacterStatusInvalid {
+		t.Errorf("status = %d, want invalid", status)
+	}
+
+	// 已失效的角色不再请求SSO
+	if _, err := m.Source(characterID).Token(context.Background()); !errors.Is(err, ErrCharacterInvalid) {
+		t.Errorf("second Token() error = %v, want ErrCharacterInvalid", err)
+	}
+	if n := srv.Refreshes(); n != 1 {
+		t.Errorf("refreshed %d times, want 1", n)
+	}
+}
+
+func TestTokenConcurrentRefresh(t *testing.T) {
+	m, srv, _, db := setupManager(t)
+	// 旧refresh token刷新后失效，重复刷新会失败
+	srv.SetRotateRefreshToken(true)
+	// 另一个实例共用Redis和数据库，但进程内的锁独立
+	other := NewManager(m.redis, db, m.sso)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		manager := m
+		if i%2 == 1 {
+			manager = other
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens[i], errs[i] = manager.Source(characterID).Token(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] != tokens[0] {
+			t.Errorf("Token() #%d = %q, %v, want same token", i, tokens[i], errs[i])
+		}
+	}
+	if n := srv.Refreshes(); n != 1 {
+		t.Errorf("refreshed %d times, want 1", n)
+	}
+}
+
+func TestTokenWaitsForOtherInstance(t *testing.T) {
+	m, srv, redisServer, _ := setupManager(t)
+	lockKey := refreshLockKeyPrefix + "2112000001"
+	redisServer.Set(lockKey, "other-instance", refreshLockTTL)
+
+	// 锁被其他实例持有时等待，直到ctx取消
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := m.Source(characterID).Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Token() error = %v, want deadline exceeded", err)
+	}
+
+	// 其他实例写入缓存后直接使用
+	time.AfterFunc(300*time.Millisecond, func() {
+		redisServer.Set(accessTokenKey(characterID), "other-access-token", time.Minute)
+	})
+	accessToken, err := m.Source(characterID).Token(context.Background())
+	if err != nil || accessToken != "other-access-token" {
+		t.Fatalf("Token() = %q, %v, want token from other instance", accessToken, err)
+	}
+	if n := srv.Refreshes(); n != 0 {
+		t.Errorf("refreshed %d times, want 0", n)
+	}
+	if owner, _ := redisServer.Get(lockKey); owner != "other-instance" {
+		t.Errorf("lock owner = %q, 不应释放其他实例的锁", owner)
+	}
+}
+
+func TestTokenRefreshCanceled(t *testing.T) {
+	m, srv, _, _ := setupManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 直接调用refresh，确认取消的ctx传递到SSO请求
+	if _, err := m.refresh(ctx, characterID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("refresh() error = %v, want canceled", err)
+	}
+	if n := srv.Refreshes(); n != 0 {
+		t.Errorf("refreshed %d times, want 0", n)
+	}
+}
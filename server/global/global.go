@@ -14,5 +14,6 @@ var (
 	SdeDb           *gorm.DB
 	Redis           *redis.Client
 	Settings        *system.SysSettings
+	Permissions     *system.Permissions
 	Qq_notification bool
 )
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/rand"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/auth"
+	"eve-corp-manager/global"
+	"time"
+)
+
+// InitTokenManager 初始化登录令牌管理器
+func InitTokenManager() {
+	cfg := config.AppConfig.Auth
+
+	secret := []byte(cfg.JwtSecret)
+	if len(secret) == 0 {
+		global.Logger.Warn("Auth.JwtSecret未配置，使用随机密钥，重启后所有登录状态失效")
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+
+	accessTTL := time.Duration(cfg.AccessTokenTTL) * time.Minute
+	if accessTTL <= 0 {
+		accessTTL = 30 * time.Minute
+	}
+	refreshTTL := time.Duration(cfg.RefreshTokenTTL) * time.Hour
+	if refreshTTL <= 0 {
+		refreshTTL = 7 * 24 * time.Hour
+	}
+
+	auth.TokenManager = auth.NewManager(global.Redis, auth.Options{
+		Secret:     secret,
+		AccessTTL:  accessTTL,
+		RefreshTTL: refreshTTL,
+	})
+}
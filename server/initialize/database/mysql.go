@@ -3,7 +3,9 @@ package database
 import (
 	"eve-corp-manager/config"
 	system2 "eve-corp-manager/core/system"
+	"eve-corp-manager/models/service/character"
 	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
 	"eve-corp-manager/models/system"
 	"log"
 	"os"
@@ -74,12 +76,27 @@ func CreateDatabase(db *gorm.DB) error {
 
 	err := db.AutoMigrate(
 		&system.User{},
+		&system.SysRole{},
 		&system.Role{},
+		&system.Menu{},
 		&system.RoleMenu{},
 		&system2.SystemSetting{},
 
+		&character.UserCharacter{},
+		&character.KillmailList{},
+		&character.KillmailItem{},
+		&character.KillmailSyncCursor{},
+
 		&fleet.Fleet{},
 		&fleet.CharacterFleetAssociation{},
+
+		&srp.SrpRequest{},
+		&srp.SrpRequestLog{},
+		&srp.SrpRule{},
+		&srp.SrpFleetMultiplier{},
+		&srp.SrpLossMatch{},
+		&srp.SrpPayoutBatch{},
+		&srp.SrpPayoutItem{},
 	)
 
 	// 创建数据表
@@ -3,12 +3,17 @@ package initialize
 import (
 	"eve-corp-manager/config"
 	"eve-corp-manager/global"
+	"eve-corp-manager/initialize/auth"
 	"eve-corp-manager/initialize/database"
 	"eve-corp-manager/initialize/esi"
+	"eve-corp-manager/initialize/killmail"
 	"eve-corp-manager/initialize/qq"
 	"eve-corp-manager/initialize/redis"
 	"eve-corp-manager/initialize/run_log"
 	"eve-corp-manager/initialize/sde"
+	"eve-corp-manager/initialize/secret"
+	"eve-corp-manager/initialize/srp"
+	"eve-corp-manager/initialize/sso"
 	"eve-corp-manager/initialize/system"
 	"eve-corp-manager/models"
 	"github.com/gin-gonic/gin"
@@ -30,6 +35,11 @@ func StartUp() {
 		log.Panicln("SDE 数据库初始化错误", err)
 	}
 
+	// 加载refresh token加密密钥
+	if err := secret.InitTokenKeyring(); err != nil {
+		log.Panicln("Encryption key initialization error", err)
+	}
+
 	// 启动数据库服务
 	startDb()
 
@@ -46,14 +56,36 @@ func StartUp() {
 
 	// 加载系统缓存
 	system.InitSettings()
+	system.InitPermissions()
+	if err := system.InitRbac(); err != nil {
+		log.Panicln("RBAC initialization error", err)
+	}
 
 	// 启动HTTP Client
 	esi.InitESIClient()
 	esi.InitJaniceClient()
 
+	// 启动EVE SSO客户端
+	sso.InitSSOClient()
+	esi.InitESITokens()
+
+	// 启动登录令牌服务
+	auth.InitTokenManager()
+
 	// 启动QQ通知服务
 	qq.InitQQClient()
 
+	// 启动zKillboard RedisQ监听
+	killmail.InitRedisQ()
+
+	// 启动ESI击毁邮件定时同步
+	killmail.InitKillmailSync()
+
+	// 启动自动补损匹配
+	srp.InitAutoSrp()
+
+	// 启动补损钱包对账
+	srp.InitReconcile()
 }
 
 func startDb() {
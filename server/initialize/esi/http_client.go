@@ -2,7 +2,12 @@ package esi
 
 import (
 	"eve-corp-manager/config"
+	"eve-corp-manager/core/cache"
 	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/sso"
+	"eve-corp-manager/core/token"
+	"eve-corp-manager/global"
+	"time"
 )
 
 // InitESIClient 初始化ESI HTTP客户端
@@ -14,7 +19,18 @@ func InitESIClient() {
 
 	apiType := "esi"
 
-	esi.EsiClient = esi.NewClient(proxyHost, proxyPort, userAgent, apiType)
+	esi.EsiClient = esi.NewClient(proxyHost, proxyPort, userAgent, apiType, config.AppConfig.Esi.BaseUrl)
+	esi.EsiClient.SetDatasource(config.AppConfig.Esi.Datasource)
+	esi.SetZkillboardURL(config.AppConfig.Zkillboard.BaseUrl)
+
+	// ESI响应按Expires缓存，过期后使用ETag重新验证
+	// 每个响应单独保存并设置过期时间，一次性的地址(击毁邮件、分页、各角色的授权接口)到期后由Redis删除
+	esi.EsiClient.SetCache(cache.NewRedisKeyCache[esi.CachedResponse](global.Redis, "esi:http_cache", time.Hour))
+
+	// ID与名称的对应关系长期缓存，名称反查的键来自用户输入，每项单独过期
+	esi.Names = esi.NewNameResolver(esi.EsiClient,
+		cache.NewRedisKeyCache[esi.Name](global.Redis, "esi:names", 30*24*time.Hour),
+		cache.NewRedisKeyCache[[]esi.Name](global.Redis, "esi:ids", 7*24*time.Hour))
 }
 
 // InitJaniceClient 初始化Janice HTTP客户端
@@ -26,5 +42,10 @@ func InitJaniceClient() {
 
 	apiType := "janice"
 
-	esi.JaniceClient = esi.NewClient(proxyHost, proxyPort, userAgent, apiType)
+	esi.JaniceClient = esi.NewClient(proxyHost, proxyPort, userAgent, apiType, config.AppConfig.Janice.BaseUrl)
+}
+
+// InitESITokens 初始化角色ESI令牌管理器
+func InitESITokens() {
+	token.ESITokens = token.NewManager(global.Redis, global.Db, sso.SsoClient)
 }
@@ -0,0 +1,26 @@
+package killmail
+
+import (
+	"context"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/esi"
+	"eve-corp-manager/core/killmail"
+	"eve-corp-manager/global"
+)
+
+// InitRedisQ 启动zKillboard推送监听(websocket或RedisQ)，自动保存本公司相关的击毁邮件
+func InitRedisQ() {
+	if !config.AppConfig.Zkillboard.Listen {
+		global.Logger.Info("zKillboard推送监听未启用")
+		return
+	}
+
+	esi.SetRedisQURL(config.AppConfig.Zkillboard.RedisqUrl)
+	esi.SetZkbWebsocketURL(config.AppConfig.Zkillboard.WebsocketUrl)
+	ingester := killmail.NewIngester(config.AppConfig.Zkillboard.Feed, config.AppConfig.Zkillboard.QueueId, global.Redis)
+	go func() {
+		if err := ingester.Run(context.Background()); err != nil {
+			global.Logger.Errorf("zKillboard推送监听退出: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package killmail
+
+import (
+	"context"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/killmail"
+	"eve-corp-manager/global"
+	"time"
+)
+
+// InitKillmailSync 启动ESI击毁邮件定时同步
+func InitKillmailSync() {
+	interval := config.AppConfig.Killmail.SyncInterval
+	if interval <= 0 {
+		global.Logger.Info("ESI击毁邮件同步未启用")
+		return
+	}
+
+	go func() {
+		if err := killmail.RunSync(context.Background(), time.Duration(interval)*time.Minute); err != nil {
+			global.Logger.Errorf("ESI击毁邮件同步退出: %v", err)
+		}
+	}()
+}
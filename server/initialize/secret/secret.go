@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/secret"
+	"eve-corp-manager/global"
+	"os"
+)
+
+// InitTokenKeyring 初始化refresh token加密密钥，环境变量优先于配置文件
+func InitTokenKeyring() error {
+	activeKey := config.AppConfig.Encryption.ActiveKey
+	if env := os.Getenv("EVE_CORP_TOKEN_ACTIVE_KEY"); env != "" {
+		activeKey = env
+	}
+	keysStr := config.AppConfig.Encryption.Keys
+	if env := os.Getenv("EVE_CORP_TOKEN_KEYS"); env != "" {
+		keysStr = env
+	}
+
+	keys, err := secret.ParseKeys(keysStr)
+	if err != nil {
+		return err
+	}
+	keyring, err := secret.NewKeyring(activeKey, keys)
+	if err != nil {
+		return err
+	}
+
+	if activeKey == "" {
+		global.Logger.Warn("未配置refresh token加密密钥，refresh token将以明文存储")
+	}
+
+	secret.TokenKeyring = keyring
+	return nil
+}
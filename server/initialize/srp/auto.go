@@ -0,0 +1,31 @@
+package srp
+
+import (
+	"context"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/srp"
+	"eve-corp-manager/global"
+	"time"
+)
+
+// InitAutoSrp 启动损失与舰队的定时自动匹配
+func InitAutoSrp() {
+	interval := config.AppConfig.Srp.AutoInterval
+	if interval <= 0 {
+		global.Logger.Info("自动补损未启用")
+		return
+	}
+	days := config.AppConfig.Srp.AutoDays
+	if days <= 0 {
+		days = 7
+	}
+	if hours := config.AppConfig.Srp.MaxFleetHours; hours > 0 {
+		srp.SetMaxFleetDuration(time.Duration(hours) * time.Hour)
+	}
+
+	go func() {
+		if err := srp.RunAutoSrp(context.Background(), time.Duration(interval)*time.Minute, days); err != nil {
+			global.Logger.Errorf("自动补损匹配退出: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,24 @@
+package srp
+
+import (
+	"context"
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/srp"
+	"eve-corp-manager/global"
+	"time"
+)
+
+// InitReconcile 启动补损发放的钱包定时对账
+func InitReconcile() {
+	interval := config.AppConfig.Srp.ReconcileInterval
+	if interval <= 0 {
+		global.Logger.Info("补损钱包对账未启用")
+		return
+	}
+
+	go func() {
+		if err := srp.RunReconcile(context.Background(), time.Duration(interval)*time.Minute); err != nil {
+			global.Logger.Errorf("补损钱包对账退出: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,23 @@
+package sso
+
+import (
+	"eve-corp-manager/config"
+	"eve-corp-manager/core/sso"
+	"eve-corp-manager/utils"
+)
+
+// InitSSOClient 初始化EVE SSO客户端
+func InitSSOClient() {
+	cfg := config.AppConfig.Sso
+
+	sso.SsoClient = sso.NewClient(sso.Options{
+		ClientID:     cfg.ClientId,
+		ClientSecret: cfg.ClientSecret,
+		CallbackURL:  cfg.CallbackUrl,
+		Scopes:       utils.StringToStringList(cfg.Scopes),
+		AuthorizeURL: cfg.AuthorizeUrl,
+		TokenURL:     cfg.TokenUrl,
+		JwksURL:      cfg.JwksUrl,
+		Issuer:       cfg.Issuer,
+	})
+}
@@ -9,3 +9,8 @@ import (
 func InitSettings() {
 	global.Settings = system.NewSysSettings(global.Db, global.Redis)
 }
+
+// InitPermissions 初始化用户权限缓存
+func InitPermissions() {
+	global.Permissions = system.NewPermissions(global.Db, global.Redis)
+}
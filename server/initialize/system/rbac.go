@@ -0,0 +1,59 @@
+package system
+
+import (
+	"errors"
+	"eve-corp-manager/core/system"
+	"eve-corp-manager/global"
+	systemModel "eve-corp-manager/models/system"
+
+	"gorm.io/gorm"
+)
+
+// defaultPermissions 接口使用的按钮权限，启动时确保存在，便于在后台分配给角色
+var defaultPermissions = []systemModel.Menu{
+	{Name: "CorpPapAdd", AuthCode: system.PermCorpPapAdd, Meta: systemModel.MenuMeta{Title: "增加PAP"}},
+	{Name: "CorpPapConsume", AuthCode: system.PermCorpPapConsume, Meta: systemModel.MenuMeta{Title: "消费PAP"}},
+	{Name: "SystemRoleManage", AuthCode: system.PermRoleManage, Meta: systemModel.MenuMeta{Title: "角色管理"}},
+	{Name: "SystemMenuManage", AuthCode: system.PermMenuManage, Meta: systemModel.MenuMeta{Title: "菜单管理"}},
+	{Name: "SystemUserRole", AuthCode: system.PermUserRole, Meta: systemModel.MenuMeta{Title: "用户角色分配"}},
+	{Name: "SystemEsiStatus", AuthCode: system.PermEsiStatus, Meta: systemModel.MenuMeta{Title: "ESI状态"}},
+	{Name: "SrpReview", AuthCode: system.PermSrpReview, Meta: systemModel.MenuMeta{Title: "补损审核"}},
+	{Name: "SrpPay", AuthCode: system.PermSrpPay, Meta: systemModel.MenuMeta{Title: "补损发放"}},
+	{Name: "SrpRule", AuthCode: system.PermSrpRule, Meta: systemModel.MenuMeta{Title: "补损规则"}},
+	{Name: "FleetManage", AuthCode: system.PermFleetManage, Meta: systemModel.MenuMeta{Title: "舰队管理"}},
+}
+
+// InitRbac 初始化超级管理员角色和默认按钮权限
+func InitRbac() error {
+	var superRole systemModel.SysRole
+	err := global.Db.Where("code = ?", system.SuperRoleCode).First(&superRole).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		superRole = systemModel.SysRole{
+			Name:   "超级管理员",
+			Code:   system.SuperRoleCode,
+			Status: systemModel.StatusEnabled,
+		}
+		err = global.Db.Create(&superRole).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, perm := range defaultPermissions {
+		var count int64
+		if err := global.Db.Model(&systemModel.Menu{}).Where("auth_code = ?", perm.AuthCode).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		perm.Type = systemModel.MenuTypeButton
+		perm.Status = systemModel.StatusEnabled
+		if err := global.Db.Create(&perm).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"eve-corp-manager/core/auth"
+	"eve-corp-manager/global"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ContextUserID   = "userId"
+	ContextUserName = "userName"
+)
+
+// JWTAuth 校验请求头中的访问令牌，用户已删除或被禁用时同样返回401
+func JWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := parseToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "未登录或登录已过期"})
+			return
+		}
+
+		perm, err := global.Permissions.Get(claims.UserID)
+		if err != nil {
+			global.Logger.Error("获取用户权限失败:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户权限失败"})
+			return
+		}
+		if !perm.Active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在或已被禁用"})
+			return
+		}
+
+		c.Set(ContextUserID, claims.UserID)
+		c.Set(ContextUserName, claims.Name)
+		c.Next()
+	}
+}
+
+// GetUserID 获取当前登录用户ID，未登录返回0
+func GetUserID(c *gin.Context) uint {
+	return c.GetUint(ContextUserID)
+}
+
+// GetUserName 获取当前登录用户名称
+func GetUserName(c *gin.Context) string {
+	return c.GetString(ContextUserName)
+}
+
+func parseToken(c *gin.Context) (*auth.Claims, bool) {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return nil, false
+	}
+
+	claims, err := auth.TokenManager.ParseAccessToken(token)
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"eve-corp-manager/global"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission 校验当前用户是否拥有权限码，需在JWTAuth之后使用
+func Permission(code string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := global.Permissions.HasCode(GetUserID(c), code)
+		if err != nil {
+			global.Logger.Error("获取用户权限失败:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户权限失败"})
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": 403, "message": "没有操作权限"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"eve-corp-manager/core/secret"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer 字符串字段加密存储，使用方式 `gorm:"serializer:encrypted"`
+// 未配置加密密钥时按明文存储，历史明文数据读取时原样返回
+type EncryptedSerializer struct{}
+
+// Scan 从数据库读取时解密
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("加密字段%s类型错误: %T", field.Name, dbValue)
+	}
+
+	if value != "" && secret.IsEncrypted(value) {
+		if secret.TokenKeyring == nil {
+			return errors.New("加密密钥未初始化")
+		}
+		plaintext, err := secret.TokenKeyring.Decrypt(value)
+		if err != nil {
+			return err
+		}
+		value = plaintext
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+// Value 写入数据库时加密
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("加密字段%s必须为字符串", field.Name)
+	}
+	if value == "" || secret.TokenKeyring == nil || secret.TokenKeyring.ActiveKeyID() == "" {
+		return value, nil
+	}
+	return secret.TokenKeyring.Encrypt(value)
+}
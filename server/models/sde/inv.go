@@ -1,6 +1,11 @@
 package sde
 
-import "eve-corp-manager/global"
+import (
+	"errors"
+	"eve-corp-manager/global"
+
+	"gorm.io/gorm"
+)
 
 // 翻译相关的常量
 const (
@@ -22,6 +27,19 @@ func (InvFlag) TableName() string {
 	return "invFlags"
 }
 
+// GetInvFlags 获取全部物品标志
+func GetInvFlags() ([]InvFlag, error) {
+	if global.SdeDb == nil {
+		return nil, errors.New("SDE数据库未初始化")
+	}
+
+	var flags []InvFlag
+	if err := global.SdeDb.Order("flagID ASC").Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
 // InvGroup 物品组定义
 type InvGroup struct {
 	GroupID              int    `gorm:"primaryKey;column:groupID"`
@@ -40,6 +58,19 @@ func (InvGroup) TableName() string {
 	return "invGroups"
 }
 
+// InvCategory 物品分类定义
+type InvCategory struct {
+	CategoryID   int    `gorm:"primaryKey;column:categoryID"`
+	CategoryName string `gorm:"column:categoryName"`
+	IconID       int    `gorm:"column:iconID"`
+	Published    bool   `gorm:"column:published"`
+}
+
+// TableName 指定表名
+func (InvCategory) TableName() string {
+	return "invCategories"
+}
+
 // InvType 物品类型定义
 type InvType struct {
 	TypeID        int     `gorm:"primaryKey;column:typeID"`
@@ -90,18 +121,26 @@ type TypeInfo struct {
 // GetTypeInfoByID 根据物品ID和语言代码获取物品及其分类信息
 func GetTypeInfoByID(typeID int, lang string) (TypeInfo, error) {
 	var info TypeInfo
+	if global.SdeDb == nil {
+		return info, errors.New("SDE数据库未初始化")
+	}
 
 	// 1. 获取基本信息（type -> group -> category 的ID链和英文名称）
 	err := global.SdeDb.Raw(`
-		SELECT t.typeID, t.typeName, t.groupID, g.groupName, g.categoryID
+		SELECT t.typeID AS type_id, t.typeName AS type_name, t.groupID AS group_id, g.groupName AS group_name,
+			g.categoryID AS category_id, c.categoryName AS category_name
 		FROM invTypes t
 		JOIN invGroups g ON t.groupID = g.groupID
+		LEFT JOIN invCategories c ON g.categoryID = c.categoryID
 		WHERE t.typeID = ?
 	`, typeID).Scan(&info).Error
 
 	if err != nil {
 		return info, err
 	}
+	if info.TypeID == 0 {
+		return info, gorm.ErrRecordNotFound
+	}
 
 	// 2. 如果指定了语言，获取对应的翻译名称
 	if lang != "" {
@@ -132,3 +171,46 @@ func GetTypeInfoByID(typeID int, lang string) (TypeInfo, error) {
 
 	return info, nil
 }
+
+// InvMetaType 物品的衍生关系和元组
+type InvMetaType struct {
+	TypeID       int `gorm:"primaryKey;column:typeID"`
+	ParentTypeID int `gorm:"column:parentTypeID"`
+	MetaGroupID  int `gorm:"column:metaGroupID"`
+}
+
+// TableName 指定表名
+func (InvMetaType) TableName() string {
+	return "invMetaTypes"
+}
+
+// 常用的元组ID
+const (
+	MetaGroupTech1     = 1  // 一级科技
+	MetaGroupTech2     = 2  // 二级科技
+	MetaGroupStoryline = 3  // 故事线
+	MetaGroupFaction   = 4  // 势力
+	MetaGroupOfficer   = 5  // 官员
+	MetaGroupDeadspace = 6  // 死亡空间
+	MetaGroupTech3     = 14 // 三级科技
+)
+
+// GetMetaGroupIDs 批量获取物品的元组ID，不在invMetaTypes中的物品不返回
+func GetMetaGroupIDs(typeIDs []int) (map[int]int, error) {
+	result := make(map[int]int, len(typeIDs))
+	if len(typeIDs) == 0 {
+		return result, nil
+	}
+	if global.SdeDb == nil {
+		return nil, errors.New("SDE数据库未初始化")
+	}
+
+	var metaTypes []InvMetaType
+	if err := global.SdeDb.Where("typeID IN ?", typeIDs).Find(&metaTypes).Error; err != nil {
+		return nil, err
+	}
+	for _, metaType := range metaTypes {
+		result[metaType.TypeID] = metaType.MetaGroupID
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package sde
+
+import (
+	"errors"
+	"eve-corp-manager/global"
+
+	"gorm.io/gorm"
+)
+
+// MapRegion 星域定义
+type MapRegion struct {
+	RegionID   int    `gorm:"primaryKey;column:regionID"`
+	RegionName string `gorm:"column:regionName"`
+}
+
+// TableName 指定表名
+func (MapRegion) TableName() string {
+	return "mapRegions"
+}
+
+// MapSolarSystem 星系定义
+type MapSolarSystem struct {
+	SolarSystemID   int     `gorm:"primaryKey;column:solarSystemID"`
+	SolarSystemName string  `gorm:"column:solarSystemName"`
+	RegionID        int     `gorm:"column:regionID"`
+	ConstellationID int     `gorm:"column:constellationID"`
+	Security        float64 `gorm:"column:security"`
+}
+
+// TableName 指定表名
+func (MapSolarSystem) TableName() string {
+	return "mapSolarSystems"
+}
+
+// SolarSystemInfo 星系及所属星域信息
+type SolarSystemInfo struct {
+	SolarSystemID   int     `json:"solarSystemId"`
+	SolarSystemName string  `json:"solarSystemName"`
+	ConstellationID int     `json:"constellationId"`
+	RegionID        int     `json:"regionId"`
+	RegionName      string  `json:"regionName"`
+	Security        float64 `json:"security"`
+}
+
+// GetSolarSystemByID 根据星系ID获取星系及所属星域信息
+func GetSolarSystemByID(solarSystemID int) (SolarSystemInfo, error) {
+	var info SolarSystemInfo
+	if global.SdeDb == nil {
+		return info, errors.New("SDE数据库未初始化")
+	}
+
+	err := global.SdeDb.Raw(`
+		SELECT s.solarSystemID AS solar_system_id, s.solarSystemName AS solar_system_name,
+			s.constellationID AS constellation_id, s.regionID AS region_id, r.regionName AS region_name,
+			s.security AS security
+		FROM mapSolarSystems s
+		LEFT JOIN mapRegions r ON s.regionID = r.regionID
+		WHERE s.solarSystemID = ?
+	`, solarSystemID).Scan(&info).Error
+
+	if err != nil {
+		return info, err
+	}
+	if info.SolarSystemID == 0 {
+		return info, gorm.ErrRecordNotFound
+	}
+
+	return info, nil
+}
@@ -2,15 +2,29 @@ package character
 
 import "eve-corp-manager/models/common"
 
+// 角色授权状态
+const (
+	CharacterStatusValid   = 1 // 授权有效
+	CharacterStatusInvalid = 2 // 授权失效，需要重新登录
+)
+
 type UserCharacter struct {
 	common.BaseModelNoId
 
 	CharacterID   uint    `gorm:"primaryKey;type:uint" json:"character_id"`
+	UserID        uint    `gorm:"index;type:uint" json:"user_id"`
 	CharacterName string  `gorm:"type:varchar(50)" json:"character_name"`
-	RefreshToken  string  `gorm:"type:varchar(255)" json:"refresh_token"`
+	OwnerHash     string  `gorm:"type:varchar(64)" json:"-"`
+	RefreshToken  string  `gorm:"type:varchar(512);serializer:encrypted" json:"-"`
+	Scopes        string  `gorm:"type:text" json:"scopes"`
 	SkillPoint    float64 `gorm:"type:decimal(15,2)" json:"skill_point"`
 	Isk           float64 `gorm:"type:decimal(15,2)" json:"isk"`
 	Status        int     `gorm:"type:tinyint(1)" json:"status"`
 	CorpID        uint    `gorm:"type:uint" json:"corp_id"`
 	AllianceID    uint    `gorm:"type:uint" json:"alliance_id"`
 }
+
+// TableName 设置表名
+func (UserCharacter) TableName() string {
+	return "user_character"
+}
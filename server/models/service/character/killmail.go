@@ -8,7 +8,7 @@ import (
 // KillmailList 击毁邮件列表
 type KillmailList struct {
 	common.BaseModel
-	KillMailID      int       `gorm:"column:kill_mail_id;type:int;index" json:"killMailId"`              // 击毁邮件ID
+	KillMailID      int       `gorm:"column:kill_mail_id;type:int;uniqueIndex" json:"killMailId"`        // 击毁邮件ID
 	KillMailHash    string    `gorm:"column:kill_mail_hash;type:varchar(64)" json:"killMailHash"`        // 击毁邮件哈希
 	KillMailTime    time.Time `gorm:"column:kill_mail_time;type:datetime" json:"killMailTime"`           // 击毁时间
 	SolarSystemID   int       `gorm:"column:solar_system_id;type:int" json:"solarSystemId"`              // 星系ID
@@ -37,17 +37,42 @@ func (KillmailList) TableName() string {
 // KillmailItem 击毁邮件物品
 type KillmailItem struct {
 	common.BaseModel
-	KillMailID int    `gorm:"column:kill_mail_id;type:int;index" json:"killMailId"` // 击毁邮件ID
-	ItemID     int    `gorm:"column:item_id;type:int" json:"itemId"`                // 物品ID
-	ItemName   string `gorm:"column:item_name;type:varchar(100)" json:"itemName"`   // 物品名称
-	ItemNum    int    `gorm:"column:item_num;type:int" json:"itemNum"`              // 物品数量
-	DropType   bool   `gorm:"column:drop_type;type:tinyint(1)" json:"dropType"`     // 掉落类型：true-掉落 false-摧毁
-	SlotType   int    `gorm:"column:slot_type;type:int" json:"slotType"`            // 槽位类型
-	CreateBy   string `gorm:"column:create_by;type:varchar(64)" json:"createBy"`    // 创建者
-	CreateID   uint   `gorm:"column:create_id;type:uint" json:"createId"`           // 创建者ID
+	KillMailID  int    `gorm:"column:kill_mail_id;type:int;index" json:"killMailId"` // 击毁邮件ID
+	ItemID      int    `gorm:"column:item_id;type:int" json:"itemId"`                // 物品ID
+	ItemName    string `gorm:"column:item_name;type:varchar(100)" json:"itemName"`   // 物品名称
+	ItemNum     int    `gorm:"column:item_num;type:int" json:"itemNum"`              // 物品数量
+	DropType    bool   `gorm:"column:drop_type;type:tinyint(1)" json:"dropType"`     // 掉落类型：true-掉落 false-摧毁
+	SlotType    int    `gorm:"column:slot_type;type:int" json:"slotType"`            // 槽位类型，见utils中的槽位枚举
+	ItemIndex   int    `gorm:"column:item_index;type:int" json:"itemIndex"`          // 物品在击毁邮件中的序号，从1开始
+	ParentIndex int    `gorm:"column:parent_index;type:int" json:"parentIndex"`      // 所在容器的序号，0表示不在容器内
+	CreateBy    string `gorm:"column:create_by;type:varchar(64)" json:"createBy"`    // 创建者
+	CreateID    uint   `gorm:"column:create_id;type:uint" json:"createId"`           // 创建者ID
 }
 
 // TableName 设置表名
 func (KillmailItem) TableName() string {
 	return "killmail_item"
 }
+
+// 击毁邮件同步对象类型
+const (
+	KillmailOwnerCorporation = "corporation" // 公司，使用总监令牌读取
+	KillmailOwnerCharacter   = "character"   // 角色
+)
+
+// KillmailSyncCursor ESI击毁邮件同步进度，每个公司/角色一条
+type KillmailSyncCursor struct {
+	common.BaseModel
+	OwnerType      string     `gorm:"column:owner_type;type:varchar(20);uniqueIndex:idx_killmail_sync_owner" json:"ownerType"` // 同步对象类型
+	OwnerID        uint       `gorm:"column:owner_id;type:uint;uniqueIndex:idx_killmail_sync_owner" json:"ownerId"`            // 公司ID或角色ID
+	CharacterID    uint       `gorm:"column:character_id;type:uint" json:"characterId"`                                        // 最近一次使用其令牌的角色ID
+	LastKillmailID int        `gorm:"column:last_killmail_id;type:int" json:"lastKillmailId"`                                  // 已连续保存的最大击毁邮件ID，之前的击毁邮件均已保存
+	LastSyncTime   *time.Time `gorm:"column:last_sync_time;type:datetime" json:"lastSyncTime"`                                 // 最近一次成功同步时间
+	SyncedCount    int        `gorm:"column:synced_count;type:int" json:"syncedCount"`                                         // 累计新保存的击毁邮件数量
+	LastError      string     `gorm:"column:last_error;type:varchar(512)" json:"lastError"`                                    // 最近一次同步失败的原因
+}
+
+// TableName 设置表名
+func (KillmailSyncCursor) TableName() string {
+	return "killmail_sync_cursor"
+}
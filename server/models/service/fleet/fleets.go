@@ -8,27 +8,27 @@ import (
 // Fleet 结构体
 type Fleet struct {
 	common.BaseModel
-	FleetName          string
-	FleetType          int
-	FleetExtraInfo     string
-	FleetCommanderID   uint
-	FleetCommanderName string
-	FleetLocation      map[string]interface{} `gorm:"serializer:json"`
-	Sig                int
-	Srp                bool
-	CorpPap            int
-	AutoSrp            bool
-	StartTime          time.Time
-	EndTime            time.Time
+	FleetName          string                 `json:"fleetName"`                            // 舰队名称
+	FleetType          int                    `json:"fleetType"`                            // 舰队类型
+	FleetExtraInfo     string                 `json:"fleetExtraInfo"`                       // 附加信息
+	FleetCommanderID   uint                   `json:"fleetCommanderId"`                     // 指挥角色ID
+	FleetCommanderName string                 `json:"fleetCommanderName"`                   // 指挥角色名称
+	FleetLocation      map[string]interface{} `gorm:"serializer:json" json:"fleetLocation"` // 舰队地点，regionId/regionIds用于自动补损匹配星域
+	Sig                int                    `json:"sig"`                                  // 所属SIG
+	Srp                bool                   `json:"srp"`                                  // 是否提供补损
+	CorpPap            int                    `json:"corpPap"`                              // 参与获得的PAP
+	AutoSrp            bool                   `json:"autoSrp"`                              // 是否自动创建补损申请
+	StartTime          time.Time              `json:"startTime"`                            // 开始时间
+	EndTime            *time.Time             `json:"endTime"`                              // 结束时间，为空表示尚未结束
 	// 修改关联关系定义
-	CharacterFleetAssociations []CharacterFleetAssociation `gorm:"foreignKey:FleetID"`
+	CharacterFleetAssociations []CharacterFleetAssociation `gorm:"foreignKey:FleetID" json:"-"`
 }
 
 // CharacterFleetAssociation 结构体
 type CharacterFleetAssociation struct {
 	common.BaseModel
-	FleetID     uint `gorm:"index"` // 外键字段
-	CharacterID uint `gorm:"index"` // 角色ID
+	FleetID     uint `gorm:"index" json:"fleetId"`     // 外键字段
+	CharacterID uint `gorm:"index" json:"characterId"` // 角色ID
 	// 修改关联关系定义
-	Fleet Fleet `gorm:"foreignKey:FleetID"` // 使用FleetID作为外键
+	Fleet Fleet `gorm:"foreignKey:FleetID" json:"-"` // 使用FleetID作为外键
 }
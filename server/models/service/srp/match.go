@@ -0,0 +1,36 @@
+package srp
+
+import (
+	"eve-corp-manager/models/common"
+	"time"
+)
+
+// 损失的自动补损匹配结果
+const (
+	SrpMatchCreated   = 1 // 已匹配舰队并自动创建补损申请
+	SrpMatchUnmatched = 2 // 未匹配到舰队，待人工处理
+	SrpMatchSubmitted = 3 // 已由用户或管理员提交补损申请
+	SrpMatchIgnored   = 4 // 已人工确认不补损
+)
+
+// SrpLossMatch 已绑定角色的损失与自动补损舰队的匹配记录，每个击毁邮件一条
+type SrpLossMatch struct {
+	common.BaseModel
+	KillMailID    int       `gorm:"column:kill_mail_id;type:int;uniqueIndex" json:"killMailId"`   // 击毁邮件ID
+	UserID        uint      `gorm:"column:user_id;type:uint;index" json:"userId"`                 // 损失角色所属用户ID
+	CharacterID   int       `gorm:"column:character_id;type:int" json:"characterId"`              // 损失角色ID
+	CharacterName string    `gorm:"column:character_name;type:varchar(100)" json:"characterName"` // 损失角色名称
+	ShipTypeName  string    `gorm:"column:ship_type_name;type:varchar(100)" json:"shipTypeName"`  // 舰船类型名称
+	KillMailTime  time.Time `gorm:"column:kill_mail_time;type:datetime" json:"killMailTime"`      // 损失时间
+	FleetID       uint      `gorm:"column:fleet_id;type:uint" json:"fleetId"`                     // 匹配的舰队ID
+	RequestID     uint      `gorm:"column:request_id;type:uint" json:"requestId"`                 // 补损申请ID
+	Status        int       `gorm:"column:status;type:tinyint;index" json:"status"`               // 匹配结果
+	Reason        string    `gorm:"column:reason;type:varchar(255)" json:"reason"`                // 未匹配原因或处理备注
+	OperatorID    uint      `gorm:"column:operator_id;type:uint" json:"operatorId"`               // 人工处理人ID，自动匹配为0
+	OperatorName  string    `gorm:"column:operator_name;type:varchar(64)" json:"operatorName"`    // 人工处理人名称
+}
+
+// TableName 设置表名
+func (SrpLossMatch) TableName() string {
+	return "srp_loss_match"
+}
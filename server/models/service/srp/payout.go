@@ -0,0 +1,59 @@
+package srp
+
+import (
+	"eve-corp-manager/models/common"
+	"time"
+)
+
+// 发放批次状态
+const (
+	SrpBatchOpen      = 1 // 待发放
+	SrpBatchCompleted = 2 // 已全部发放
+)
+
+// 发放明细状态
+const (
+	SrpPayoutPending  = 1 // 待发放
+	SrpPayoutPaid     = 2 // 已在钱包流水中确认
+	SrpPayoutMismatch = 3 // 钱包流水与应发金额不符，需要人工核对
+)
+
+// SrpPayoutBatch 补损发放批次，将已批准的申请按收款主角色汇总
+type SrpPayoutBatch struct {
+	common.BaseModel
+	Name        string  `gorm:"column:name;type:varchar(64)" json:"name"`                // 批次名称
+	Status      int     `gorm:"column:status;type:tinyint;index" json:"status"`          // 状态
+	Total       float64 `gorm:"column:total;type:decimal(20,2)" json:"total"`            // 应发总额
+	PaidTotal   float64 `gorm:"column:paid_total;type:decimal(20,2)" json:"paidTotal"`   // 已确认发放总额
+	CreatorID   uint    `gorm:"column:creator_id;type:uint" json:"creatorId"`            // 创建人ID
+	CreatorName string  `gorm:"column:creator_name;type:varchar(64)" json:"creatorName"` // 创建人名称
+	// 关联关系
+	Items []SrpPayoutItem `gorm:"foreignKey:BatchID" json:"items,omitempty"` // 发放明细
+}
+
+// TableName 设置表名
+func (SrpPayoutBatch) TableName() string {
+	return "srp_payout_batch"
+}
+
+// SrpPayoutItem 发放明细，每个收款主角色一条，对应一笔游戏内转账
+type SrpPayoutItem struct {
+	common.BaseModel
+	BatchID           uint       `gorm:"column:batch_id;type:uint;index" json:"batchId"`                        // 发放批次ID
+	UserID            uint       `gorm:"column:user_id;type:uint;index" json:"userId"`                          // 收款用户ID
+	MainCharacterID   int        `gorm:"column:main_character_id;type:int;index" json:"mainCharacterId"`        // 收款主角色ID
+	MainCharacterName string     `gorm:"column:main_character_name;type:varchar(100)" json:"mainCharacterName"` // 收款主角色名称
+	Amount            float64    `gorm:"column:amount;type:decimal(20,2)" json:"amount"`                        // 应发金额
+	RequestCount      int        `gorm:"column:request_count;type:int" json:"requestCount"`                     // 包含的补损申请数量
+	Reference         string     `gorm:"column:reference;type:varchar(32);index" json:"reference"`              // 转账备注，用于钱包对账
+	Status            int        `gorm:"column:status;type:tinyint;index" json:"status"`                        // 状态
+	JournalID         int64      `gorm:"column:journal_id;type:bigint;index" json:"journalId"`                  // 匹配的钱包流水ID
+	PaidAmount        float64    `gorm:"column:paid_amount;type:decimal(20,2)" json:"paidAmount"`               // 钱包流水中的转账金额
+	PaidTime          *time.Time `gorm:"column:paid_time;type:datetime" json:"paidTime"`                        // 转账时间
+	Note              string     `gorm:"column:note;type:varchar(255)" json:"note"`                             // 对账说明
+}
+
+// TableName 设置表名
+func (SrpPayoutItem) TableName() string {
+	return "srp_payout_item"
+}
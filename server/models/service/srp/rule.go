@@ -0,0 +1,44 @@
+package srp
+
+import "eve-corp-manager/models/common"
+
+// 补损规则类型
+const (
+	SrpRuleShipFixed        = 1 // 指定舰船固定金额，TargetID为舰船类型ID
+	SrpRuleGroupPercent     = 2 // 按舰船分组的Janice估价比例，TargetID为分组ID
+	SrpRuleCategoryPercent  = 3 // 按舰船类别的Janice估价比例，TargetID为类别ID
+	SrpRuleExcludeMetaGroup = 4 // 按比例计算时排除该元组的物品，TargetID为元组ID(4势力 5官员 6死亡空间)
+	SrpRuleExcludeType      = 5 // 按比例计算时排除指定物品，TargetID为物品类型ID
+)
+
+// SrpRule 补损规则，金额规则按 舰船固定金额 > 分组比例 > 类别比例 的顺序匹配，同一级别按优先级从高到低
+type SrpRule struct {
+	common.BaseModel
+	Name        string  `gorm:"column:name;type:varchar(64)" json:"name"`                  // 规则名称
+	RuleType    int     `gorm:"column:rule_type;type:tinyint;index" json:"ruleType"`       // 规则类型
+	TargetID    int     `gorm:"column:target_id;type:int" json:"targetId"`                 // 匹配对象ID，含义由规则类型决定
+	FixedAmount float64 `gorm:"column:fixed_amount;type:decimal(20,2)" json:"fixedAmount"` // 固定金额
+	Percent     float64 `gorm:"column:percent;type:decimal(6,2)" json:"percent"`           // Janice估价百分比，100表示全额
+	MaxAmount   float64 `gorm:"column:max_amount;type:decimal(20,2)" json:"maxAmount"`     // 补损上限，0表示不限制
+	Priority    int     `gorm:"column:priority;type:int" json:"priority"`                  // 优先级，越大越优先
+	Enabled     bool    `gorm:"column:enabled;type:tinyint(1)" json:"enabled"`             // 是否启用
+	Remark      string  `gorm:"column:remark;type:varchar(255)" json:"remark"`             // 备注
+}
+
+// TableName 设置表名
+func (SrpRule) TableName() string {
+	return "srp_rule"
+}
+
+// SrpFleetMultiplier 舰队类型的补损倍率，未配置的舰队类型按1倍计算
+type SrpFleetMultiplier struct {
+	common.BaseModel
+	FleetType  int     `gorm:"column:fleet_type;type:int;uniqueIndex" json:"fleetType"` // 舰队类型
+	Multiplier float64 `gorm:"column:multiplier;type:decimal(6,2)" json:"multiplier"`   // 倍率
+	Remark     string  `gorm:"column:remark;type:varchar(255)" json:"remark"`           // 备注
+}
+
+// TableName 设置表名
+func (SrpFleetMultiplier) TableName() string {
+	return "srp_fleet_multiplier"
+}
@@ -0,0 +1,77 @@
+package srp
+
+import (
+	"eve-corp-manager/models/common"
+	"time"
+)
+
+// 补损申请状态，流转：待审核 -> 已批准 -> 已发放，待审核/已批准 -> 已拒绝
+const (
+	SrpStatusPending  = 1 // 待审核
+	SrpStatusApproved = 2 // 已批准
+	SrpStatusPaid     = 3 // 已发放
+	SrpStatusRejected = 4 // 已拒绝
+)
+
+// 补损操作类型
+const (
+	SrpActionSubmit  = "submit"  // 提交申请
+	SrpActionApprove = "approve" // 批准
+	SrpActionReject  = "reject"  // 拒绝
+	SrpActionPay     = "pay"     // 发放
+)
+
+// SrpRequest 补损申请
+type SrpRequest struct {
+	common.BaseModel
+	KillMailID      int        `gorm:"column:kill_mail_id;type:int;uniqueIndex" json:"killMailId"`        // 击毁邮件ID，每个损失只能申请一次
+	UserID          uint       `gorm:"column:user_id;type:uint;index" json:"userId"`                      // 申请用户ID
+	UserName        string     `gorm:"column:user_name;type:varchar(64)" json:"userName"`                 // 申请用户名称
+	CharacterID     int        `gorm:"column:character_id;type:int;index" json:"characterId"`             // 损失角色ID
+	CharacterName   string     `gorm:"column:character_name;type:varchar(100)" json:"characterName"`      // 损失角色名称
+	ShipTypeID      int        `gorm:"column:ship_type_id;type:int" json:"shipTypeId"`                    // 舰船类型ID
+	ShipTypeName    string     `gorm:"column:ship_type_name;type:varchar(100)" json:"shipTypeName"`       // 舰船类型名称
+	SolarSystemID   int        `gorm:"column:solar_system_id;type:int" json:"solarSystemId"`              // 星系ID
+	SolarSystemName string     `gorm:"column:solar_system_name;type:varchar(100)" json:"solarSystemName"` // 星系名称
+	KillMailTime    time.Time  `gorm:"column:kill_mail_time;type:datetime" json:"killMailTime"`           // 损失时间
+	JaniceAmount    float64    `gorm:"column:janice_amount;type:decimal(20,2)" json:"janiceAmount"`       // Janice估价
+	FleetID         uint       `gorm:"column:fleet_id;type:uint;index" json:"fleetId"`                    // 关联舰队ID，0表示未关联
+	RequestAmount   float64    `gorm:"column:request_amount;type:decimal(20,2)" json:"requestAmount"`     // 申请金额
+	ApprovedAmount  float64    `gorm:"column:approved_amount;type:decimal(20,2)" json:"approvedAmount"`   // 批准金额
+	PaidAmount      float64    `gorm:"column:paid_amount;type:decimal(20,2)" json:"paidAmount"`           // 实际发放金额，少于批准金额时为部分发放
+	Status          int        `gorm:"column:status;type:tinyint;index" json:"status"`                    // 状态
+	Remark          string     `gorm:"column:remark;type:varchar(255)" json:"remark"`                     // 申请备注
+	ReviewComment   string     `gorm:"column:review_comment;type:varchar(255)" json:"reviewComment"`      // 审核意见
+	ReviewerID      uint       `gorm:"column:reviewer_id;type:uint" json:"reviewerId"`                    // 审核人ID
+	ReviewerName    string     `gorm:"column:reviewer_name;type:varchar(64)" json:"reviewerName"`         // 审核人名称
+	ReviewTime      *time.Time `gorm:"column:review_time;type:datetime" json:"reviewTime"`                // 审核时间
+	PayerID         uint       `gorm:"column:payer_id;type:uint" json:"payerId"`                          // 发放人ID
+	PayerName       string     `gorm:"column:payer_name;type:varchar(64)" json:"payerName"`               // 发放人名称
+	PaidTime        *time.Time `gorm:"column:paid_time;type:datetime" json:"paidTime"`                    // 发放时间
+	PayoutItemID    uint       `gorm:"column:payout_item_id;type:uint;index" json:"payoutItemId"`         // 所在发放明细ID，0表示未加入发放批次
+	// 关联关系
+	Logs []SrpRequestLog `gorm:"foreignKey:RequestID" json:"logs,omitempty"` // 审计日志
+}
+
+// TableName 设置表名
+func (SrpRequest) TableName() string {
+	return "srp_request"
+}
+
+// SrpRequestLog 补损申请状态变更日志
+type SrpRequestLog struct {
+	common.BaseModel
+	RequestID    uint    `gorm:"column:request_id;type:uint;index" json:"requestId"`        // 补损申请ID
+	Action       string  `gorm:"column:action;type:varchar(20)" json:"action"`              // 操作类型
+	FromStatus   int     `gorm:"column:from_status;type:tinyint" json:"fromStatus"`         // 操作前状态，提交时为0
+	ToStatus     int     `gorm:"column:to_status;type:tinyint" json:"toStatus"`             // 操作后状态
+	Amount       float64 `gorm:"column:amount;type:decimal(20,2)" json:"amount"`            // 本次操作涉及的金额
+	OperatorID   uint    `gorm:"column:operator_id;type:uint" json:"operatorId"`            // 操作人ID
+	OperatorName string  `gorm:"column:operator_name;type:varchar(64)" json:"operatorName"` // 操作人名称
+	Comment      string  `gorm:"column:comment;type:varchar(255)" json:"comment"`           // 操作备注
+}
+
+// TableName 设置表名
+func (SrpRequestLog) TableName() string {
+	return "srp_request_log"
+}
@@ -0,0 +1,37 @@
+package system
+
+import "eve-corp-manager/models/common"
+
+// 菜单类型
+const (
+	MenuTypeCatalog = "catalog" // 目录
+	MenuTypeMenu    = "menu"    // 菜单
+	MenuTypeButton  = "button"  // 按钮
+)
+
+// MenuMeta 菜单元信息，与前端路由meta一致
+type MenuMeta struct {
+	Title      string `json:"title"`
+	Icon       string `json:"icon,omitempty"`
+	Order      int    `json:"order,omitempty"`
+	KeepAlive  bool   `json:"keepAlive,omitempty"`
+	AffixTab   bool   `json:"affixTab,omitempty"`
+	HideInMenu bool   `json:"hideInMenu,omitempty"`
+	Link       string `json:"link,omitempty"`
+}
+
+// Menu 菜单及按钮权限
+type Menu struct {
+	common.BaseModel
+
+	Pid       uint     `gorm:"index;type:uint" json:"pid"`              // 父菜单ID
+	Name      string   `gorm:"type:varchar(50)" json:"name"`            // 路由名称
+	Path      string   `gorm:"type:varchar(255)" json:"path"`           // 路由地址
+	Component string   `gorm:"type:varchar(255)" json:"component"`      // 前端组件
+	Redirect  string   `gorm:"type:varchar(255)" json:"redirect"`       // 重定向地址
+	Type      string   `gorm:"type:varchar(20)" json:"type"`            // 类型：catalog/menu/button
+	AuthCode  string   `gorm:"type:varchar(100);index" json:"authCode"` // 权限码
+	Status    int      `gorm:"type:tinyint(1)" json:"status"`           // 状态：1-启用 0-停用
+	Meta      MenuMeta `gorm:"serializer:json;type:text" json:"meta"`   // 菜单元信息
+	Children  []*Menu  `gorm:"-" json:"children,omitempty"`
+}
@@ -2,6 +2,23 @@ package system
 
 import "eve-corp-manager/models/common"
 
+// 角色/菜单状态
+const (
+	StatusEnabled  = 1 // 启用
+	StatusDisabled = 0 // 停用
+)
+
+// SysRole 角色定义
+type SysRole struct {
+	common.BaseModel
+
+	Name   string `gorm:"type:varchar(50)" json:"name"`             // 角色名称
+	Code   string `gorm:"type:varchar(50);uniqueIndex" json:"code"` // 角色编码
+	Status int    `gorm:"type:tinyint(1)" json:"status"`            // 状态：1-启用 0-停用
+	Remark string `gorm:"type:varchar(255)" json:"remark"`          // 备注
+}
+
+// Role 用户与角色的关联
 type Role struct {
 	common.BaseModel
 
@@ -9,6 +26,7 @@ type Role struct {
 	RoleId uint `gorm:"primaryKey;index;type:uint" json:"roleId"` // 角色ID
 }
 
+// RoleMenu 角色与菜单的关联
 type RoleMenu struct {
 	common.BaseModel
 
@@ -1,9 +1,12 @@
 package character
 
 import (
+	"eve-corp-manager/core/secret"
 	"eve-corp-manager/global"
 	"eve-corp-manager/models/service/character"
 	"eve-corp-manager/utils"
+	"fmt"
+
 	"gorm.io/gorm"
 )
 
@@ -14,7 +17,7 @@ type UserCharacterRepository struct {
 func (r *UserCharacterRepository) Update(userID, characterID uint, newUserCharacter character.UserCharacter) (*character.UserCharacter, error) {
 	err := r.DB.Model(&character.UserCharacter{}).
 		Where("user_id = ? AND character_id = ?", userID, characterID).
-		Save(newUserCharacter).
+		Save(&newUserCharacter).
 		Error
 	if err != nil {
 		global.Logger.Errorf("Failed to update user character, userID: %v, characterID: %v, error: %v", userID, characterID, err)
@@ -23,16 +26,150 @@ func (r *UserCharacterRepository) Update(userID, characterID uint, newUserCharac
 	return &newUserCharacter, nil
 }
 
+func (r *UserCharacterRepository) Get(characterID uint) (*character.UserCharacter, error) {
+	var userCharacter character.UserCharacter
+	err := r.DB.Where("character_id = ?", characterID).First(&userCharacter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &userCharacter, nil
+}
+
+func (r *UserCharacterRepository) Save(userCharacter *character.UserCharacter) (*character.UserCharacter, error) {
+	err := r.DB.Save(userCharacter).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to save user character, characterID: %v, error: %v", userCharacter.CharacterID, err)
+		return nil, err
+	}
+	return userCharacter, nil
+}
+
+func (r *UserCharacterRepository) UpdateStatus(characterID uint, status int) error {
+	err := r.DB.Model(&character.UserCharacter{}).
+		Where("character_id = ?", characterID).
+		Update("status", status).
+		Error
+	if err != nil {
+		global.Logger.Errorf("Failed to update character status, characterID: %v, error: %v", characterID, err)
+	}
+	return err
+}
+
+// UpdateRefreshToken 更新refresh token，通过结构体更新以便经过加密序列化
+func (r *UserCharacterRepository) UpdateRefreshToken(characterID uint, refreshToken string) error {
+	err := r.DB.Model(&character.UserCharacter{CharacterID: characterID}).
+		Select("refresh_token").
+		Updates(&character.UserCharacter{RefreshToken: refreshToken}).
+		Error
+	if err != nil {
+		global.Logger.Errorf("Failed to update refresh token, characterID: %v, error: %v", characterID, err)
+	}
+	return err
+}
+
+func (r *UserCharacterRepository) ListByUser(userID uint) ([]character.UserCharacter, error) {
+	var characters []character.UserCharacter
+	err := r.DB.Where("user_id = ?", userID).Find(&characters).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list user characters, userID: %v, error: %v", userID, err)
+		return nil, err
+	}
+	return characters, nil
+}
+
+func (r *UserCharacterRepository) ListByIDs(characterIDs []uint) ([]character.UserCharacter, error) {
+	var characters []character.UserCharacter
+	if len(characterIDs) == 0 {
+		return characters, nil
+	}
+	err := r.DB.Where("character_id IN ?", characterIDs).Find(&characters).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list user characters by ids, error: %v", err)
+		return nil, err
+	}
+	return characters, nil
+}
+
 func (r *UserCharacterRepository) GetAllInAllowedCorp() ([]character.UserCharacter, error) {
 	corpList, err := global.Settings.Get("allowed_corp_list")
 	if err != nil {
 		global.Logger.Errorf("allowed_corp_list变量未设置，提取所有\n %v", err)
 	}
 	corpIdList, err := utils.StringToIntList(corpList)
-	result := r.DB.Where("corporation_id IN ?", corpIdList).Find(&character.UserCharacter{})
+	if err != nil {
+		global.Logger.Errorf("allowed_corp_list格式错误: %v", err)
+		return nil, err
+	}
+
+	var characters []character.UserCharacter
+	db := r.DB
+	if len(corpIdList) > 0 {
+		db = db.Where("corp_id IN ?", corpIdList)
+	}
+	result := db.Find(&characters)
 	if result.Error != nil {
 		global.Logger.Errorf("获取公司列表失败: %v", result.Error)
 		return nil, result.Error
 	}
-	return result, nil
+	return characters, nil
+}
+
+// ReencryptRefreshTokens 使用当前密钥重新加密所有refresh token，返回更新的数量
+// 直接读写原始列，跳过已使用当前密钥加密的记录；更新时比较原密文，期间被刷新的令牌保持不变
+// 有记录解密失败时继续处理其余记录，最后返回错误，此时不能移除旧密钥
+func (r *UserCharacterRepository) ReencryptRefreshTokens(keyring *secret.Keyring, batchSize int) (int, error) {
+	type row struct {
+		CharacterID  uint
+		RefreshToken string
+	}
+
+	updated, failed := 0, 0
+	var lastID uint
+	for {
+		var rows []row
+		err := r.DB.Table(character.UserCharacter{}.TableName()).
+			Select("character_id, refresh_token").
+			Where("character_id > ? AND refresh_token <> ''", lastID).
+			Order("character_id ASC").
+			Limit(batchSize).
+			Scan(&rows).Error
+		if err != nil {
+			return updated, err
+		}
+		if len(rows) == 0 {
+			if failed > 0 {
+				return updated, fmt.Errorf("%d条refresh token解密失败", failed)
+			}
+			return updated, nil
+		}
+
+		for _, item := range rows {
+			lastID = item.CharacterID
+
+			if keyID, err := secret.KeyID(item.RefreshToken); err == nil && keyID == keyring.ActiveKeyID() {
+				continue
+			}
+
+			plaintext, err := keyring.Decrypt(item.RefreshToken)
+			if err != nil {
+				global.Logger.Errorf("Failed to decrypt refresh token, characterID: %v, error: %v", item.CharacterID, err)
+				failed++
+				continue
+			}
+			ciphertext, err := keyring.Encrypt(plaintext)
+			if err != nil {
+				return updated, err
+			}
+
+			result := r.DB.Table(character.UserCharacter{}.TableName()).
+				Where("character_id = ? AND refresh_token = ?", item.CharacterID, item.RefreshToken).
+				UpdateColumn("refresh_token", ciphertext)
+			if result.Error != nil {
+				return updated, result.Error
+			}
+			if result.RowsAffected > 0 {
+				updated++
+			}
+		}
+	}
 }
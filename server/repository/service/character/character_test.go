@@ -0,0 +1,80 @@
+package character
+
+import (
+	"bytes"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/core/secret"
+	"eve-corp-manager/models/service/character"
+	"strings"
+	"testing"
+)
+
+func TestReencryptRefreshTokens(t *testing.T) {
+	db := esitest.UseDB(t, &character.UserCharacter{})
+
+	key := func(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+	oldRing, err := secret.NewKeyring("k1", map[string][]byte{"k1": key(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lostRing, _ := secret.NewKeyring("k0", map[string][]byte{"k0": key(0)})
+	keyring, _ := secret.NewKeyring("k2", map[string][]byte{"k1": key(1), "k2": key(2)})
+
+	encrypt := func(k *secret.Keyring, plaintext string) string {
+		ciphertext, err := k.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ciphertext
+	}
+	current := encrypt(keyring, "token-3")
+	lost := encrypt(lostRing, "token-4")
+
+	// 直接写入原始列，不经过加密序列化
+	stored := map[uint]string{
+		1: "token-1",                   // 历史明文
+		2: encrypt(oldRing, "token-2"), // 旧密钥
+		3: current,                     // 已使用当前密钥
+		4: lost,                        // 密钥已丢失
+		5: "",                          // 没有令牌
+		6: encrypt(oldRing, "token-6"),
+	}
+	for id, value := range stored {
+		if err := db.Table("user_character").Create(map[string]interface{}{"character_id": id, "refresh_token": value}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw := func(id uint) string {
+		var value string
+		db.Table("user_character").Where("character_id = ?", id).Select("refresh_token").Scan(&value)
+		return value
+	}
+
+	repo := UserCharacterRepository{DB: db}
+	updated, err := repo.ReencryptRefreshTokens(keyring, 2)
+	if err == nil || !strings.Contains(err.Error(), "1条") {
+		t.Errorf("error = %v, want one decrypt failure", err)
+	}
+	if updated != 3 {
+		t.Errorf("updated = %d, want 3", updated)
+	}
+
+	for id, want := range map[uint]string{1: "token-1", 2: "token-2", 6: "token-6"} {
+		value := raw(id)
+		if keyID, _ := secret.KeyID(value); keyID != "k2" {
+			t.Errorf("character %d stored %q, want encrypted with k2", id, value)
+		}
+		if plaintext, err := keyring.Decrypt(value); err != nil || plaintext != want {
+			t.Errorf("character %d = %q, %v, want %q", id, plaintext, err, want)
+		}
+	}
+	if raw(3) != current || raw(4) != lost || raw(5) != "" {
+		t.Error("已使用当前密钥、解密失败和没有令牌的记录不应修改")
+	}
+
+	// 处理完解密失败的记录后再次执行，没有需要更新的记录
+	db.Table("user_character").Where("character_id = ?", 4).Delete(nil)
+	if updated, err := repo.ReencryptRefreshTokens(keyring, 2); err != nil || updated != 0 {
+		t.Errorf("second run = %d, %v, want 0, nil", updated, err)
+	}
+}
@@ -0,0 +1,138 @@
+package character
+
+import (
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type KillmailRepository struct {
+	DB *gorm.DB
+}
+
+// KillmailFilter 击毁邮件查询条件，零值表示不限制
+type KillmailFilter struct {
+	CharacterID int
+	UserID      uint
+	StartTime   time.Time
+	EndTime     time.Time
+	Page        int
+	Limit       int
+}
+
+// Get 获取击毁邮件及其物品
+func (r *KillmailRepository) Get(killMailID int) (*character.KillmailList, error) {
+	var killmail character.KillmailList
+	err := r.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("item_index ASC")
+	}).Where("kill_mail_id = ?", killMailID).First(&killmail).Error
+	if err != nil {
+		return nil, err
+	}
+	return &killmail, nil
+}
+
+// Create 保存击毁邮件及其物品，已存在相同KillMailID时不做修改并返回false
+func (r *KillmailRepository) Create(killmail *character.KillmailList) (bool, error) {
+	created := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit("Items").
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "kill_mail_id"}}, DoNothing: true}).
+			Create(killmail)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		created = true
+
+		if len(killmail.Items) == 0 {
+			return nil
+		}
+		for i := range killmail.Items {
+			killmail.Items[i].KillMailID = killmail.KillMailID
+		}
+		return tx.Create(&killmail.Items).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to create killmail, killMailID: %v, error: %v", killmail.KillMailID, err)
+		return false, err
+	}
+	return created, nil
+}
+
+// List 按角色、用户和时间分页查询击毁邮件，不包含物品
+func (r *KillmailRepository) List(filter KillmailFilter) ([]character.KillmailList, int64, error) {
+	db := r.DB.Model(&character.KillmailList{})
+	if filter.CharacterID > 0 {
+		db = db.Where("character_id = ?", filter.CharacterID)
+	}
+	if filter.UserID > 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if !filter.StartTime.IsZero() {
+		db = db.Where("kill_mail_time >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		db = db.Where("kill_mail_time < ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		global.Logger.Errorf("Failed to count killmails, error: %v", err)
+		return nil, 0, err
+	}
+
+	var killmails []character.KillmailList
+	offset := (filter.Page - 1) * filter.Limit
+	err := db.Order("kill_mail_time DESC").Offset(offset).Limit(filter.Limit).Find(&killmails).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list killmails, error: %v", err)
+		return nil, 0, err
+	}
+	return killmails, total, nil
+}
+
+// ExistingIDs 返回已保存的击毁邮件ID
+func (r *KillmailRepository) ExistingIDs(killMailIDs []int) (map[int]bool, error) {
+	existing := make(map[int]bool, len(killMailIDs))
+	if len(killMailIDs) == 0 {
+		return existing, nil
+	}
+
+	var ids []int
+	err := r.DB.Model(&character.KillmailList{}).Where("kill_mail_id IN ?", killMailIDs).Pluck("kill_mail_id", &ids).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to query existing killmails, error: %v", err)
+		return nil, err
+	}
+	for _, id := range ids {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+// GetSyncCursor 获取公司/角色的同步进度，不存在时返回零值
+func (r *KillmailRepository) GetSyncCursor(ownerType string, ownerID uint) (*character.KillmailSyncCursor, error) {
+	cursor := character.KillmailSyncCursor{OwnerType: ownerType, OwnerID: ownerID}
+	err := r.DB.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).First(&cursor).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.Logger.Errorf("Failed to get killmail sync cursor, owner: %v %v, error: %v", ownerType, ownerID, err)
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// SaveSyncCursor 保存同步进度
+func (r *KillmailRepository) SaveSyncCursor(cursor *character.KillmailSyncCursor) error {
+	err := r.DB.Save(cursor).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to save killmail sync cursor, owner: %v %v, error: %v", cursor.OwnerType, cursor.OwnerID, err)
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package character
+
+import (
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/models/service/character"
+	"testing"
+)
+
+func TestCreateIsIdempotent(t *testing.T) {
+	db := esitest.UseDB(t, &character.KillmailList{}, &character.KillmailItem{})
+	repo := KillmailRepository{DB: db}
+
+	newKillmail := func(shipName string) *character.KillmailList {
+		return &character.KillmailList{
+			KillMailID:   esitest.FixtureKillmailID,
+			KillMailHash: esitest.FixtureKillmailHash,
+			ShipTypeName: shipName,
+			Items: []character.KillmailItem{
+				{ItemID: 34, ItemName: "Tritanium", ItemNum: 1000, ItemIndex: 1},
+				{ItemID: 2046, ItemName: "Damage Control I", ItemNum: 1, ItemIndex: 2},
+			},
+		}
+	}
+
+	created, err := repo.Create(newKillmail("Rifter"))
+	if err != nil || !created {
+		t.Fatalf("first Create() = %v, %v, want true", created, err)
+	}
+	// 重复提交同一击毁邮件(如推送和ESI同步同时保存)不报错，也不重复写入物品
+	created, err = repo.Create(newKillmail("Slasher"))
+	if err != nil || created {
+		t.Fatalf("second Create() = %v, %v, want false", created, err)
+	}
+
+	var killmails []character.KillmailList
+	db.Find(&killmails)
+	if len(killmails) != 1 || killmails[0].ShipTypeName != "Rifter" {
+		t.Errorf("killmails = %+v, want the first one only", killmails)
+	}
+	var items int64
+	db.Model(&character.KillmailItem{}).Where("kill_mail_id = ?", esitest.FixtureKillmailID).Count(&items)
+	if items != 2 {
+		t.Errorf("items = %d, want 2", items)
+	}
+}
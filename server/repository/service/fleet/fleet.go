@@ -0,0 +1,149 @@
+package fleet
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/fleet"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FleetRepository struct {
+	DB *gorm.DB
+}
+
+// FleetFilter 舰队查询条件，零值表示不限制
+type FleetFilter struct {
+	CommanderID uint
+	FleetType   int
+	StartTime   time.Time // 开始时间不早于
+	EndTime     time.Time // 开始时间早于
+	Page        int
+	Limit       int
+}
+
+func (r *FleetRepository) Get(fleetID uint) (*fleet.Fleet, error) {
+	var f fleet.Fleet
+	if err := r.DB.First(&f, fleetID).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *FleetRepository) Add(f *fleet.Fleet) (*fleet.Fleet, error) {
+	if err := r.DB.Omit("CharacterFleetAssociations").Create(f).Error; err != nil {
+		global.Logger.Errorf("Failed to add fleet, error: %v", err)
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *FleetRepository) Update(f *fleet.Fleet) (*fleet.Fleet, error) {
+	if err := r.DB.Omit("CharacterFleetAssociations").Save(f).Error; err != nil {
+		global.Logger.Errorf("Failed to update fleet, fleetID: %v, error: %v", f.ID, err)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete 删除舰队及其参与记录
+func (r *FleetRepository) Delete(fleetID uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("fleet_id = ?", fleetID).Delete(&fleet.CharacterFleetAssociation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&fleet.Fleet{}, fleetID).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to delete fleet, fleetID: %v, error: %v", fleetID, err)
+	}
+	return err
+}
+
+// List 按指挥、类型和开始时间分页查询舰队
+func (r *FleetRepository) List(filter FleetFilter) ([]fleet.Fleet, int64, error) {
+	db := r.DB.Model(&fleet.Fleet{})
+	if filter.CommanderID > 0 {
+		db = db.Where("fleet_commander_id = ?", filter.CommanderID)
+	}
+	if filter.FleetType > 0 {
+		db = db.Where("fleet_type = ?", filter.FleetType)
+	}
+	if !filter.StartTime.IsZero() {
+		db = db.Where("start_time >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		db = db.Where("start_time < ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		global.Logger.Errorf("Failed to count fleets, error: %v", err)
+		return nil, 0, err
+	}
+
+	var fleets []fleet.Fleet
+	offset := (filter.Page - 1) * filter.Limit
+	err := db.Order("start_time DESC, id DESC").Offset(offset).Limit(filter.Limit).Find(&fleets).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list fleets, error: %v", err)
+		return nil, 0, err
+	}
+	return fleets, total, nil
+}
+
+// Members 获取舰队的参与记录，按加入时间排序
+func (r *FleetRepository) Members(fleetID uint) ([]fleet.CharacterFleetAssociation, error) {
+	var members []fleet.CharacterFleetAssociation
+	if err := r.DB.Where("fleet_id = ?", fleetID).Order("id ASC").Find(&members).Error; err != nil {
+		global.Logger.Errorf("Failed to list fleet members, fleetID: %v, error: %v", fleetID, err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMembers 添加舰队参与角色，已存在的角色忽略，返回新增数量
+func (r *FleetRepository) AddMembers(fleetID uint, characterIDs []uint) (int, error) {
+	added := 0
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		err := tx.Model(&fleet.CharacterFleetAssociation{}).
+			Where("fleet_id = ? AND character_id IN ?", fleetID, characterIDs).
+			Pluck("character_id", &existing).Error
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			seen[id] = true
+		}
+		var members []fleet.CharacterFleetAssociation
+		for _, id := range characterIDs {
+			if id == 0 || seen[id] {
+				continue
+			}
+			seen[id] = true
+			members = append(members, fleet.CharacterFleetAssociation{FleetID: fleetID, CharacterID: id})
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		added = len(members)
+		return tx.Omit("Fleet").Create(&members).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to add fleet members, fleetID: %v, error: %v", fleetID, err)
+		return 0, err
+	}
+	return added, nil
+}
+
+// RemoveMember 移除舰队参与角色
+func (r *FleetRepository) RemoveMember(fleetID, characterID uint) error {
+	err := r.DB.Where("fleet_id = ? AND character_id = ?", fleetID, characterID).Delete(&fleet.CharacterFleetAssociation{}).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to remove fleet member, fleetID: %v, characterID: %v, error: %v", fleetID, characterID, err)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package srp
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
+	"eve-corp-manager/models/service/fleet"
+	"eve-corp-manager/models/service/srp"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// GetByKillMail 获取击毁邮件对应的补损申请
+func (r *SrpRepository) GetByKillMail(killMailID int) (*srp.SrpRequest, error) {
+	var request srp.SrpRequest
+	if err := r.DB.Where("kill_mail_id = ?", killMailID).First(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// PendingLosses 获取since之后已绑定角色的损失中尚未匹配或未匹配到舰队的记录，按ID分批读取，不包含物品
+func (r *SrpRepository) PendingLosses(since time.Time, afterID uint, limit int) ([]character.KillmailList, error) {
+	var killmails []character.KillmailList
+	err := r.DB.Model(&character.KillmailList{}).
+		Where("id > ? AND kill_mail_time >= ?", afterID, since).
+		Where("character_id IN (?)", r.DB.Model(&character.UserCharacter{}).Select("character_id")).
+		Where("kill_mail_id NOT IN (?)", r.DB.Model(&srp.SrpLossMatch{}).Select("kill_mail_id").Where("status <> ?", srp.SrpMatchUnmatched)).
+		Order("id ASC").
+		Limit(limit).
+		Find(&killmails).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list pending losses, error: %v", err)
+		return nil, err
+	}
+	return killmails, nil
+}
+
+// CharacterFleets 获取角色参加过的、开始时间不晚于at的舰队，按开始时间从新到旧排序
+func (r *SrpRepository) CharacterFleets(characterID uint, at time.Time) ([]fleet.Fleet, error) {
+	var fleets []fleet.Fleet
+	err := r.DB.Model(&fleet.Fleet{}).
+		Where("id IN (?)", r.DB.Model(&fleet.CharacterFleetAssociation{}).Select("fleet_id").Where("character_id = ?", characterID)).
+		Where("start_time <= ?", at).
+		Order("start_time DESC").
+		Find(&fleets).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list character fleets, characterID: %v, error: %v", characterID, err)
+		return nil, err
+	}
+	return fleets, nil
+}
+
+func (r *SrpRepository) GetMatch(id uint) (*srp.SrpLossMatch, error) {
+	var match srp.SrpLossMatch
+	if err := r.DB.First(&match, id).Error; err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// SaveMatch 保存击毁邮件的匹配结果，已存在时覆盖
+func (r *SrpRepository) SaveMatch(match *srp.SrpLossMatch) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "kill_mail_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"user_id", "fleet_id", "request_id", "status", "reason", "operator_id", "operator_name", "updated_at",
+		}),
+	}).Create(match).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to save srp loss match, killMailID: %v, error: %v", match.KillMailID, err)
+	}
+	return err
+}
+
+// ListMatches 按匹配结果分页查询，status为0时不限制
+func (r *SrpRepository) ListMatches(status, page, limit int) ([]srp.SrpLossMatch, int64, error) {
+	db := r.DB.Model(&srp.SrpLossMatch{})
+	if status > 0 {
+		db = db.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		global.Logger.Errorf("Failed to count srp loss matches, error: %v", err)
+		return nil, 0, err
+	}
+
+	var matches []srp.SrpLossMatch
+	err := db.Order("kill_mail_time DESC").Offset((page - 1) * limit).Limit(limit).Find(&matches).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list srp loss matches, error: %v", err)
+		return nil, 0, err
+	}
+	return matches, total, nil
+}
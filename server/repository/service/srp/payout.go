@@ -0,0 +1,189 @@
+package srp
+
+import (
+	"errors"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/srp"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// errBatchConflict 部分申请已加入其他批次或状态已变化，用于回滚事务
+var errBatchConflict = errors.New("srp requests changed")
+
+// ApprovedUnbatched 获取已批准且未加入发放批次的申请，requestIDs为空时返回全部
+func (r *SrpRepository) ApprovedUnbatched(requestIDs []uint) ([]srp.SrpRequest, error) {
+	db := r.DB.Where("status = ? AND payout_item_id = 0", srp.SrpStatusApproved)
+	if len(requestIDs) > 0 {
+		db = db.Where("id IN ?", requestIDs)
+	}
+
+	var requests []srp.SrpRequest
+	if err := db.Order("id ASC").Find(&requests).Error; err != nil {
+		global.Logger.Errorf("Failed to list approved srp requests, error: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// CreateBatch 保存发放批次和明细，requestIDs[i]为Items[i]包含的申请，生成转账备注并关联申请
+// 部分申请已加入其他批次或状态已变化时不做修改并返回false
+func (r *SrpRepository) CreateBatch(batch *srp.SrpPayoutBatch, requestIDs [][]uint) (bool, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		for i := range batch.Items {
+			item := &batch.Items[i]
+			item.Reference = fmt.Sprintf("SRP%d-%d", batch.ID, item.ID)
+			if err := tx.Model(item).Update("reference", item.Reference).Error; err != nil {
+				return err
+			}
+
+			result := tx.Model(&srp.SrpRequest{}).
+				Where("id IN ? AND status = ? AND payout_item_id = 0", requestIDs[i], srp.SrpStatusApproved).
+				Update("payout_item_id", item.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(requestIDs[i])) {
+				return errBatchConflict
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchConflict) {
+		return false, nil
+	}
+	if err != nil {
+		global.Logger.Errorf("Failed to create srp payout batch, error: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetBatch 获取发放批次及明细
+func (r *SrpRepository) GetBatch(id uint) (*srp.SrpPayoutBatch, error) {
+	var batch srp.SrpPayoutBatch
+	err := r.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&batch, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches 分页查询发放批次，status为0时不限制，不包含明细
+func (r *SrpRepository) ListBatches(status, page, limit int) ([]srp.SrpPayoutBatch, int64, error) {
+	db := r.DB.Model(&srp.SrpPayoutBatch{})
+	if status > 0 {
+		db = db.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		global.Logger.Errorf("Failed to count srp payout batches, error: %v", err)
+		return nil, 0, err
+	}
+
+	var batches []srp.SrpPayoutBatch
+	err := db.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&batches).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list srp payout batches, error: %v", err)
+		return nil, 0, err
+	}
+	return batches, total, nil
+}
+
+func (r *SrpRepository) GetItem(id uint) (*srp.SrpPayoutItem, error) {
+	var item srp.SrpPayoutItem
+	if err := r.DB.First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UnpaidItems 获取待发放和金额不符的发放明细
+func (r *SrpRepository) UnpaidItems() ([]srp.SrpPayoutItem, error) {
+	var items []srp.SrpPayoutItem
+	err := r.DB.Where("status IN ?", []int{srp.SrpPayoutPending, srp.SrpPayoutMismatch}).Order("id ASC").Find(&items).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list unpaid srp payout items, error: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// UsedJournalIDs 返回已匹配到发放明细的钱包流水ID
+func (r *SrpRepository) UsedJournalIDs(journalIDs []int64) (map[int64]bool, error) {
+	used := make(map[int64]bool)
+	if len(journalIDs) == 0 {
+		return used, nil
+	}
+
+	var ids []int64
+	err := r.DB.Model(&srp.SrpPayoutItem{}).Where("journal_id IN ?", journalIDs).Pluck("journal_id", &ids).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to query used journal ids, error: %v", err)
+		return nil, err
+	}
+	for _, id := range ids {
+		used[id] = true
+	}
+	return used, nil
+}
+
+// ItemRequests 获取发放明细包含的申请
+func (r *SrpRepository) ItemRequests(itemID uint) ([]srp.SrpRequest, error) {
+	var requests []srp.SrpRequest
+	if err := r.DB.Where("payout_item_id = ?", itemID).Order("id ASC").Find(&requests).Error; err != nil {
+		global.Logger.Errorf("Failed to list srp requests of payout item, itemID: %v, error: %v", itemID, err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// UpdateItem 更新发放明细的对账结果，并重新汇总批次的已发放金额和状态
+func (r *SrpRepository) UpdateItem(item *srp.SrpPayoutItem) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(item).Updates(map[string]interface{}{
+			"status":      item.Status,
+			"journal_id":  item.JournalID,
+			"paid_amount": item.PaidAmount,
+			"paid_time":   item.PaidTime,
+			"note":        item.Note,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var summary struct {
+			PaidTotal float64
+			Unpaid    int64
+		}
+		err = tx.Model(&srp.SrpPayoutItem{}).
+			Select("COALESCE(SUM(CASE WHEN status = ? THEN paid_amount ELSE 0 END), 0) AS paid_total, "+
+				"COALESCE(SUM(CASE WHEN status <> ? THEN 1 ELSE 0 END), 0) AS unpaid", srp.SrpPayoutPaid, srp.SrpPayoutPaid).
+			Where("batch_id = ?", item.BatchID).
+			Scan(&summary).Error
+		if err != nil {
+			return err
+		}
+
+		status := srp.SrpBatchOpen
+		if summary.Unpaid == 0 {
+			status = srp.SrpBatchCompleted
+		}
+		return tx.Model(&srp.SrpPayoutBatch{}).Where("id = ?", item.BatchID).Updates(map[string]interface{}{
+			"paid_total": summary.PaidTotal,
+			"status":     status,
+		}).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to update srp payout item, id: %v, error: %v", item.ID, err)
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package srp
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/srp"
+
+	"gorm.io/gorm/clause"
+)
+
+// ListRules 获取补损规则，按类型和优先级排序
+func (r *SrpRepository) ListRules(enabledOnly bool) ([]srp.SrpRule, error) {
+	var rules []srp.SrpRule
+	db := r.DB.Order("rule_type ASC, priority DESC, id ASC")
+	if enabledOnly {
+		db = db.Where("enabled = ?", true)
+	}
+	if err := db.Find(&rules).Error; err != nil {
+		global.Logger.Errorf("Failed to list srp rules, error: %v", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *SrpRepository) GetRule(id uint) (*srp.SrpRule, error) {
+	var rule srp.SrpRule
+	if err := r.DB.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *SrpRepository) AddRule(rule *srp.SrpRule) (*srp.SrpRule, error) {
+	if err := r.DB.Create(rule).Error; err != nil {
+		global.Logger.Errorf("Failed to add srp rule, error: %v", err)
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *SrpRepository) UpdateRule(rule *srp.SrpRule) (*srp.SrpRule, error) {
+	if err := r.DB.Save(rule).Error; err != nil {
+		global.Logger.Errorf("Failed to update srp rule, id: %v, error: %v", rule.ID, err)
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *SrpRepository) DeleteRule(id uint) error {
+	err := r.DB.Delete(&srp.SrpRule{}, id).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to delete srp rule, id: %v, error: %v", id, err)
+	}
+	return err
+}
+
+// ListMultipliers 获取全部舰队类型倍率
+func (r *SrpRepository) ListMultipliers() ([]srp.SrpFleetMultiplier, error) {
+	var multipliers []srp.SrpFleetMultiplier
+	if err := r.DB.Order("fleet_type ASC").Find(&multipliers).Error; err != nil {
+		global.Logger.Errorf("Failed to list srp fleet multipliers, error: %v", err)
+		return nil, err
+	}
+	return multipliers, nil
+}
+
+// GetMultiplier 获取舰队类型的倍率，未配置时返回gorm.ErrRecordNotFound
+func (r *SrpRepository) GetMultiplier(fleetType int) (*srp.SrpFleetMultiplier, error) {
+	var multiplier srp.SrpFleetMultiplier
+	if err := r.DB.Where("fleet_type = ?", fleetType).First(&multiplier).Error; err != nil {
+		return nil, err
+	}
+	return &multiplier, nil
+}
+
+// SaveMultiplier 新增或修改舰队类型的倍率
+func (r *SrpRepository) SaveMultiplier(multiplier *srp.SrpFleetMultiplier) error {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fleet_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"multiplier", "remark", "updated_at"}),
+	}).Create(multiplier).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to save srp fleet multiplier, fleetType: %v, error: %v", multiplier.FleetType, err)
+	}
+	return err
+}
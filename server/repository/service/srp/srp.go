@@ -0,0 +1,126 @@
+package srp
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/srp"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SrpRepository struct {
+	DB *gorm.DB
+}
+
+// SrpFilter 补损申请查询条件，零值表示不限制
+type SrpFilter struct {
+	UserID      uint
+	CharacterID int
+	FleetID     uint
+	Status      int
+	StartTime   time.Time
+	EndTime     time.Time
+	Page        int
+	Limit       int
+}
+
+// Get 获取补损申请及审计日志
+func (r *SrpRepository) Get(id uint) (*srp.SrpRequest, error) {
+	var request srp.SrpRequest
+	err := r.DB.Preload("Logs", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&request, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// Create 保存补损申请和提交日志，同一击毁邮件已申请时返回false
+func (r *SrpRepository) Create(request *srp.SrpRequest, log *srp.SrpRequestLog) (bool, error) {
+	created := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit("Logs").
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "kill_mail_id"}}, DoNothing: true}).
+			Create(request)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		created = true
+
+		log.RequestID = request.ID
+		return tx.Create(log).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to create srp request, killMailID: %v, error: %v", request.KillMailID, err)
+		return false, err
+	}
+	return created, nil
+}
+
+// Transition 在状态为fromStatuses之一且所在发放明细仍为payoutItemID时更新补损申请并写入日志，已被他人修改时返回false
+func (r *SrpRepository) Transition(id uint, fromStatuses []int, payoutItemID uint, updates map[string]interface{}, log *srp.SrpRequestLog) (bool, error) {
+	changed := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&srp.SrpRequest{}).
+			Where("id = ? AND status IN ? AND payout_item_id = ?", id, fromStatuses, payoutItemID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		changed = true
+
+		log.RequestID = id
+		return tx.Create(log).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to update srp request, id: %v, action: %v, error: %v", id, log.Action, err)
+		return false, err
+	}
+	return changed, nil
+}
+
+// List 按用户、角色、舰队、状态和损失时间分页查询补损申请，不包含日志
+func (r *SrpRepository) List(filter SrpFilter) ([]srp.SrpRequest, int64, error) {
+	db := r.DB.Model(&srp.SrpRequest{})
+	if filter.UserID > 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CharacterID > 0 {
+		db = db.Where("character_id = ?", filter.CharacterID)
+	}
+	if filter.FleetID > 0 {
+		db = db.Where("fleet_id = ?", filter.FleetID)
+	}
+	if filter.Status > 0 {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if !filter.StartTime.IsZero() {
+		db = db.Where("kill_mail_time >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		db = db.Where("kill_mail_time < ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		global.Logger.Errorf("Failed to count srp requests, error: %v", err)
+		return nil, 0, err
+	}
+
+	var requests []srp.SrpRequest
+	offset := (filter.Page - 1) * filter.Limit
+	err := db.Order("id DESC").Offset(offset).Limit(filter.Limit).Find(&requests).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list srp requests, error: %v", err)
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
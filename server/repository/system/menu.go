@@ -0,0 +1,135 @@
+package system
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/system"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type MenuRepository struct {
+	DB *gorm.DB
+}
+
+func (r *MenuRepository) List() ([]system.Menu, error) {
+	var menus []system.Menu
+	err := r.DB.Order("id ASC").Find(&menus).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list menus, error: %v", err)
+		return nil, err
+	}
+	return menus, nil
+}
+
+func (r *MenuRepository) ListByIds(menuIds []uint) ([]system.Menu, error) {
+	var menus []system.Menu
+	if len(menuIds) == 0 {
+		return menus, nil
+	}
+	err := r.DB.Where("id IN ?", menuIds).Find(&menus).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list menus by ids, error: %v", err)
+		return nil, err
+	}
+	return menus, nil
+}
+
+func (r *MenuRepository) Get(menuID uint) (*system.Menu, error) {
+	var menu system.Menu
+	err := r.DB.First(&menu, menuID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &menu, nil
+}
+
+func (r *MenuRepository) Add(menu *system.Menu) (*system.Menu, error) {
+	err := r.DB.Create(menu).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to add menu, error: %v", err)
+		return nil, err
+	}
+	return menu, nil
+}
+
+func (r *MenuRepository) Update(menu *system.Menu) (*system.Menu, error) {
+	err := r.DB.Save(menu).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to update menu, menuID: %v, error: %v", menu.ID, err)
+		return nil, err
+	}
+	return menu, nil
+}
+
+// IsDescendant 沿父菜单向上查找，判断menuID是否为ancestorID本身或其子孙菜单
+func (r *MenuRepository) IsDescendant(menuID, ancestorID uint) (bool, error) {
+	visited := make(map[uint]bool)
+	for menuID != 0 && !visited[menuID] {
+		if menuID == ancestorID {
+			return true, nil
+		}
+		visited[menuID] = true
+		menu, err := r.Get(menuID)
+		if err != nil {
+			return false, err
+		}
+		menuID = menu.Pid
+	}
+	return false, nil
+}
+
+func (r *MenuRepository) CountChildren(menuID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&system.Menu{}).Where("pid = ?", menuID).Count(&count).Error
+	return count, err
+}
+
+// Delete 删除菜单及其角色关联
+func (r *MenuRepository) Delete(menuID uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("menu_id = ?", menuID).Delete(&system.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&system.Menu{}, menuID).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to delete menu, menuID: %v, error: %v", menuID, err)
+	}
+	return err
+}
+
+// BuildMenuTree 将菜单列表组装成树，父菜单不在列表中的节点作为根节点
+func BuildMenuTree(menus []system.Menu) []*system.Menu {
+	nodes := make(map[uint]*system.Menu, len(menus))
+	for i := range menus {
+		menu := menus[i]
+		menu.Children = nil
+		nodes[menu.ID] = &menu
+	}
+
+	roots := make([]*system.Menu, 0)
+	for i := range menus {
+		node := nodes[menus[i].ID]
+		if parent, ok := nodes[node.Pid]; ok && node.Pid != node.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortMenuTree(roots)
+	return roots
+}
+
+func sortMenuTree(menus []*system.Menu) {
+	sort.SliceStable(menus, func(i, j int) bool {
+		if menus[i].Meta.Order != menus[j].Meta.Order {
+			return menus[i].Meta.Order < menus[j].Meta.Order
+		}
+		return menus[i].ID < menus[j].ID
+	})
+	for _, menu := range menus {
+		sortMenuTree(menu.Children)
+	}
+}
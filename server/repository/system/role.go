@@ -0,0 +1,158 @@
+package system
+
+import (
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/system"
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	DB *gorm.DB
+}
+
+func (r *RoleRepository) List() ([]system.SysRole, error) {
+	var roles []system.SysRole
+	err := r.DB.Order("id ASC").Find(&roles).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list roles, error: %v", err)
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *RoleRepository) Get(roleID uint) (*system.SysRole, error) {
+	var role system.SysRole
+	err := r.DB.First(&role, roleID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) GetByCode(code string) (*system.SysRole, error) {
+	var role system.SysRole
+	err := r.DB.Where("code = ?", code).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) Add(role *system.SysRole) (*system.SysRole, error) {
+	err := r.DB.Create(role).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to add role, error: %v", err)
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *RoleRepository) Update(role *system.SysRole) (*system.SysRole, error) {
+	err := r.DB.Save(role).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to update role, roleID: %v, error: %v", role.ID, err)
+		return nil, err
+	}
+	return role, nil
+}
+
+// Delete 删除角色及其菜单和用户关联
+func (r *RoleRepository) Delete(roleID uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("role_id = ?", roleID).Delete(&system.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("role_id = ?", roleID).Delete(&system.Role{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&system.SysRole{}, roleID).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to delete role, roleID: %v, error: %v", roleID, err)
+	}
+	return err
+}
+
+func (r *RoleRepository) GetMenuIds(roleID uint) ([]uint, error) {
+	var menuIds []uint
+	err := r.DB.Model(&system.RoleMenu{}).Where("role_id = ?", roleID).Pluck("menu_id", &menuIds).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to get role menus, roleID: %v, error: %v", roleID, err)
+		return nil, err
+	}
+	return menuIds, nil
+}
+
+// SetMenus 覆盖角色的菜单和按钮权限
+func (r *RoleRepository) SetMenus(roleID uint, menus []system.Menu) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("role_id = ?", roleID).Delete(&system.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		if len(menus) == 0 {
+			return nil
+		}
+
+		roleMenus := make([]system.RoleMenu, 0, len(menus))
+		for _, menu := range menus {
+			roleMenus = append(roleMenus, system.RoleMenu{
+				RoleId:   roleID,
+				MenuId:   menu.ID,
+				IsButton: menu.Type == system.MenuTypeButton,
+			})
+		}
+		return tx.Create(&roleMenus).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to set role menus, roleID: %v, error: %v", roleID, err)
+	}
+	return err
+}
+
+func (r *RoleRepository) GetUserRoleIds(userID uint) ([]uint, error) {
+	var roleIds []uint
+	err := r.DB.Model(&system.Role{}).Where("user_id = ?", userID).Pluck("role_id", &roleIds).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to get user roles, userID: %v, error: %v", userID, err)
+		return nil, err
+	}
+	return roleIds, nil
+}
+
+// SetUserRoles 覆盖用户的角色
+func (r *RoleRepository) SetUserRoles(userID uint, roleIds []uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&system.Role{}).Error; err != nil {
+			return err
+		}
+		if len(roleIds) == 0 {
+			return nil
+		}
+
+		userRoles := make([]system.Role, 0, len(roleIds))
+		for _, roleID := range roleIds {
+			userRoles = append(userRoles, system.Role{UserId: userID, RoleId: roleID})
+		}
+		return tx.Create(&userRoles).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to set user roles, userID: %v, error: %v", userID, err)
+	}
+	return err
+}
+
+// AddUserRole 为用户追加角色，已存在时忽略
+func (r *RoleRepository) AddUserRole(userID, roleID uint) error {
+	var count int64
+	if err := r.DB.Model(&system.Role{}).Where("user_id = ? AND role_id = ?", userID, roleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	err := r.DB.Create(&system.Role{UserId: userID, RoleId: roleID}).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to add user role, userID: %v, roleID: %v, error: %v", userID, roleID, err)
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package system
 
 import (
+	"errors"
 	"eve-corp-manager/global"
+	"eve-corp-manager/models/service/character"
 	"eve-corp-manager/models/system"
 	"gorm.io/gorm"
 )
@@ -37,6 +39,19 @@ func (r *UserRepository) Update(user *system.User) (*system.User, error) {
 	return user, nil
 }
 
+func (r *UserRepository) ListByIds(userIDs []uint) ([]system.User, error) {
+	var users []system.User
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	err := r.DB.Where("user_id IN ?", userIDs).Find(&users).Error
+	if err != nil {
+		global.Logger.Errorf("Failed to list users by ids, got error: %v", err)
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) GetCharacterList(userID uint) (*system.User, error) {
 	var user system.User
 	err := r.DB.Preload("Characters").First(&user, userID).Error
@@ -46,3 +61,76 @@ func (r *UserRepository) GetCharacterList(userID uint) (*system.User, error) {
 	}
 	return &user, nil
 }
+
+// BindCharacter 将SSO登录的角色关联到用户
+// userID为0时按角色查找已有用户，角色不存在或已转手则创建新用户，新用户的第一个角色作为主角色
+func (r *UserRepository) BindCharacter(userID uint, userCharacter *character.UserCharacter) (*system.User, error) {
+	var user system.User
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing character.UserCharacter
+		err := tx.Where("character_id = ?", userCharacter.CharacterID).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		found := err == nil
+		// 角色转手后owner hash会变化，不能沿用原用户
+		sameOwner := found && existing.OwnerHash == userCharacter.OwnerHash
+
+		targetUserID := userID
+		if targetUserID == 0 && sameOwner {
+			targetUserID = existing.UserID
+		}
+
+		if targetUserID == 0 {
+			user = system.User{
+				MainCharacterId: int(userCharacter.CharacterID),
+				Name:            userCharacter.CharacterName,
+				Status:          system.UserStatusNormal,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Where("user_id = ?", targetUserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		if found {
+			userCharacter.CreatedAt = existing.CreatedAt
+		}
+		userCharacter.UserID = user.UserId
+		if err := tx.Save(userCharacter).Error; err != nil {
+			return err
+		}
+
+		if user.MainCharacterId == 0 {
+			user.MainCharacterId = int(userCharacter.CharacterID)
+			if err := tx.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		// 角色从其他用户转移过来时，修正原用户的主角色
+		if found && existing.UserID != 0 && existing.UserID != user.UserId {
+			var prevUser system.User
+			if err := tx.Where("user_id = ?", existing.UserID).First(&prevUser).Error; err == nil &&
+				prevUser.MainCharacterId == int(userCharacter.CharacterID) {
+				var other character.UserCharacter
+				prevUser.MainCharacterId = 0
+				if tx.Where("user_id = ?", prevUser.UserId).First(&other).Error == nil {
+					prevUser.MainCharacterId = int(other.CharacterID)
+				}
+				if err := tx.Save(&prevUser).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Preload("Characters").Where("user_id = ?", user.UserId).First(&user).Error
+	})
+	if err != nil {
+		global.Logger.Errorf("Failed to bind character, characterID: %v, error: %v", userCharacter.CharacterID, err)
+		return nil, err
+	}
+	return &user, nil
+}
@@ -2,6 +2,8 @@ package corp_pap
 
 import (
 	"eve-corp-manager/api/v1/service"
+	"eve-corp-manager/core/system"
+	"eve-corp-manager/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,16 +11,16 @@ import (
 // Init 初始化路由
 func Init(routerGroup *gin.RouterGroup) {
 	// 创建corpPap路由组
-	corpPapRouter := routerGroup.Group("corp_pap")
+	corpPapRouter := routerGroup.Group("corp_pap", middleware.JWTAuth())
 	{
 		// 获取用户PAP记录列表
 		corpPapRouter.GET("/list", service.GetUserPapList)
 		// 获取用户PAP余额
 		corpPapRouter.GET("/balance", service.GetUserPapBalance)
 		// 增加用户PAP
-		corpPapRouter.POST("/add", service.AddUserPap)
+		corpPapRouter.POST("/add", middleware.Permission(system.PermCorpPapAdd), service.AddUserPap)
 		// 消费用户PAP
-		corpPapRouter.POST("/consume", service.ConsumeUserPap)
+		corpPapRouter.POST("/consume", middleware.Permission(system.PermCorpPapConsume), service.ConsumeUserPap)
 		// 获取PAP操作日志
 		corpPapRouter.GET("/logs", service.GetPapLogs)
 	}
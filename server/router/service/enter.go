@@ -2,6 +2,9 @@ package service
 
 import (
 	"eve-corp-manager/router/service/corp_pap"
+	"eve-corp-manager/router/service/fleet"
+	"eve-corp-manager/router/service/killmail"
+	"eve-corp-manager/router/service/srp"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,5 +16,8 @@ func Init(routerGroup *gin.RouterGroup) {
 
 	// 初始化各个服务模块的路由
 	corp_pap.Init(serviceRouter)
+	killmail.Init(serviceRouter)
+	srp.Init(serviceRouter)
+	fleet.Init(serviceRouter)
 	// 这里可以添加其他服务模块的路由初始化
 }
@@ -0,0 +1,28 @@
+package fleet
+
+import (
+	"eve-corp-manager/api/v1/service"
+	"eve-corp-manager/core/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建舰队路由组
+	fleetRouter := routerGroup.Group("fleet", middleware.JWTAuth())
+	{
+		// 查询舰队列表
+		fleetRouter.GET("/list", service.GetFleetList)
+		// 获取舰队详情及参与角色
+		fleetRouter.GET("/:id", service.GetFleet)
+		// 新建、修改、删除舰队
+		fleetRouter.POST("", middleware.Permission(system.PermFleetManage), service.CreateFleet)
+		fleetRouter.PUT("/:id", middleware.Permission(system.PermFleetManage), service.UpdateFleet)
+		fleetRouter.DELETE("/:id", middleware.Permission(system.PermFleetManage), service.DeleteFleet)
+		// 舰队参与角色
+		fleetRouter.POST("/:id/members", middleware.Permission(system.PermFleetManage), service.AddFleetMembers)
+		fleetRouter.DELETE("/:id/members/:characterId", middleware.Permission(system.PermFleetManage), service.RemoveFleetMember)
+	}
+}
@@ -0,0 +1,24 @@
+package killmail
+
+import (
+	"eve-corp-manager/api/v1/service"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建killmail路由组
+	killmailRouter := routerGroup.Group("killmail", middleware.JWTAuth())
+	{
+		// 提交击毁邮件链接
+		killmailRouter.POST("/submit", service.SubmitKillmail)
+		// 击毁邮件列表
+		killmailRouter.GET("/list", service.GetKillmailList)
+		// 获取击毁邮件详情
+		killmailRouter.GET("/:id", service.GetKillmail)
+		// 获取击毁邮件UI数据
+		killmailRouter.GET("/:id/ui", service.GetKillmailUIJSON)
+	}
+}
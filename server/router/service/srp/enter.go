@@ -0,0 +1,53 @@
+package srp
+
+import (
+	"eve-corp-manager/api/v1/service"
+	"eve-corp-manager/core/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建补损路由组
+	srpRouter := routerGroup.Group("srp", middleware.JWTAuth())
+	{
+		// 提交补损申请
+		srpRouter.POST("/submit", service.SubmitSrp)
+		// 获取当前用户的补损申请
+		srpRouter.GET("/my", service.GetMySrpList)
+		// 按补损规则试算
+		srpRouter.POST("/dry_run", service.DryRunSrp)
+		// 补损规则
+		srpRouter.GET("/rules", middleware.Permission(system.PermSrpRule), service.GetSrpRules)
+		srpRouter.POST("/rules", middleware.Permission(system.PermSrpRule), service.CreateSrpRule)
+		srpRouter.PUT("/rules/:id", middleware.Permission(system.PermSrpRule), service.UpdateSrpRule)
+		srpRouter.DELETE("/rules/:id", middleware.Permission(system.PermSrpRule), service.DeleteSrpRule)
+		// 舰队类型补损倍率
+		srpRouter.GET("/multipliers", middleware.Permission(system.PermSrpRule), service.GetSrpMultipliers)
+		srpRouter.POST("/multipliers", middleware.Permission(system.PermSrpRule), service.SaveSrpMultiplier)
+		// 查询全部补损申请
+		srpRouter.GET("/list", middleware.Permission(system.PermSrpReview), service.GetSrpList)
+		// 自动补损匹配记录，默认为未匹配到舰队的损失
+		srpRouter.GET("/losses", middleware.Permission(system.PermSrpReview), service.GetSrpLosses)
+		srpRouter.POST("/losses/:id/assign", middleware.Permission(system.PermSrpReview), service.AssignSrpLoss)
+		srpRouter.POST("/losses/:id/ignore", middleware.Permission(system.PermSrpReview), service.IgnoreSrpLoss)
+		// 发放批次
+		srpRouter.POST("/batches", middleware.Permission(system.PermSrpPay), service.CreateSrpBatch)
+		srpRouter.GET("/batches", middleware.Permission(system.PermSrpPay), service.GetSrpBatchList)
+		srpRouter.GET("/batches/:id", middleware.Permission(system.PermSrpPay), service.GetSrpBatch)
+		srpRouter.GET("/batches/:id/export", middleware.Permission(system.PermSrpPay), service.ExportSrpBatch)
+		srpRouter.POST("/batches/items/:id/confirm", middleware.Permission(system.PermSrpPay), service.ConfirmSrpPayoutItem)
+		// 立即执行钱包对账
+		srpRouter.POST("/batches/reconcile", middleware.Permission(system.PermSrpPay), service.ReconcileSrp)
+		// 获取补损申请详情
+		srpRouter.GET("/:id", service.GetSrp)
+		// 批准补损申请
+		srpRouter.POST("/:id/approve", middleware.Permission(system.PermSrpReview), service.ApproveSrp)
+		// 拒绝补损申请
+		srpRouter.POST("/:id/reject", middleware.Permission(system.PermSrpReview), service.RejectSrp)
+		// 发放补损
+		srpRouter.POST("/:id/pay", middleware.Permission(system.PermSrpPay), service.PaySrp)
+	}
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"eve-corp-manager/api/v1/system"
+	coreSystem "eve-corp-manager/core/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建system管理路由组
+	adminRouter := routerGroup.Group("system", middleware.JWTAuth())
+
+	roleRouter := adminRouter.Group("role", middleware.Permission(coreSystem.PermRoleManage))
+	{
+		// 角色列表
+		roleRouter.GET("/list", system.GetRoleList)
+		// 新增角色
+		roleRouter.POST("", system.CreateRole)
+		// 修改角色
+		roleRouter.PUT("/:id", system.UpdateRole)
+		// 删除角色
+		roleRouter.DELETE("/:id", system.DeleteRole)
+		// 获取角色菜单
+		roleRouter.GET("/:id/menus", system.GetRoleMenus)
+		// 设置角色菜单
+		roleRouter.PUT("/:id/menus", system.SetRoleMenus)
+	}
+
+	menuRouter := adminRouter.Group("menu", middleware.Permission(coreSystem.PermMenuManage))
+	{
+		// 菜单列表
+		menuRouter.GET("/list", system.GetMenuList)
+		// 新增菜单
+		menuRouter.POST("", system.CreateMenu)
+		// 修改菜单
+		menuRouter.PUT("/:id", system.UpdateMenu)
+		// 删除菜单
+		menuRouter.DELETE("/:id", system.DeleteMenu)
+	}
+
+	userRouter := adminRouter.Group("user", middleware.Permission(coreSystem.PermUserRole))
+	{
+		// 获取用户角色
+		userRouter.GET("/:id/roles", system.GetUserRoles)
+		// 设置用户角色
+		userRouter.PUT("/:id/roles", system.SetUserRoles)
+	}
+
+	esiRouter := adminRouter.Group("esi", middleware.Permission(coreSystem.PermEsiStatus))
+	{
+		// ESI错误额度状态
+		esiRouter.GET("/error_limit", system.GetEsiErrorLimit)
+	}
+}
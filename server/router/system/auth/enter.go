@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"eve-corp-manager/api/v1/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建auth路由组
+	authRouter := routerGroup.Group("auth")
+	{
+		// 跳转EVE SSO登录
+		authRouter.GET("/eve/login", system.EveLogin)
+		// EVE SSO登录回调
+		authRouter.GET("/eve/callback", system.EveCallback)
+		// 使用SSO登录票据换取令牌
+		authRouter.POST("/login", system.Login)
+		// 刷新访问令牌
+		authRouter.POST("/refresh", system.RefreshToken)
+		// 退出登录
+		authRouter.POST("/logout", system.Logout)
+	}
+
+	// 需要登录的接口
+	authRequiredRouter := routerGroup.Group("auth", middleware.JWTAuth())
+	{
+		// 获取用户权限码
+		authRequiredRouter.GET("/codes", system.GetAccessCodes)
+		// 绑定新角色
+		authRequiredRouter.POST("/eve/bind", system.EveBind)
+	}
+}
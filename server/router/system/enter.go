@@ -1,5 +1,18 @@
 package system
 
-import "github.com/gin-gonic/gin"
+import (
+	"eve-corp-manager/router/system/admin"
+	"eve-corp-manager/router/system/auth"
+	"eve-corp-manager/router/system/menu"
+	"eve-corp-manager/router/system/user"
 
-func Init(routerGroup *gin.RouterGroup) {}
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化系统模块路由
+func Init(routerGroup *gin.RouterGroup) {
+	auth.Init(routerGroup)
+	user.Init(routerGroup)
+	menu.Init(routerGroup)
+	admin.Init(routerGroup)
+}
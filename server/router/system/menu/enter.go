@@ -0,0 +1,18 @@
+package menu
+
+import (
+	"eve-corp-manager/api/v1/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建menu路由组
+	menuRouter := routerGroup.Group("menu", middleware.JWTAuth())
+	{
+		// 获取当前用户菜单树
+		menuRouter.GET("/all", system.GetAllMenus)
+	}
+}
@@ -0,0 +1,18 @@
+package user
+
+import (
+	"eve-corp-manager/api/v1/system"
+	"eve-corp-manager/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Init 初始化路由
+func Init(routerGroup *gin.RouterGroup) {
+	// 创建user路由组
+	userRouter := routerGroup.Group("user", middleware.JWTAuth())
+	{
+		// 获取当前用户信息
+		userRouter.GET("/info", system.GetUserInfo)
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"strconv"
 	"strings"
 	"time"
@@ -60,3 +61,15 @@ func StringToDate(dateStr string) time.Time {
 	}
 	return t
 }
+
+// SleepContext 等待d，ctx取消时返回false
+func SleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
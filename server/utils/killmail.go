@@ -1,51 +1,45 @@
 package utils
 
 import (
+	"context"
 	"eve-corp-manager/core/esi"
+	"eve-corp-manager/models/sde"
 	"fmt"
+	"slices"
 	"strconv"
+	"time"
 )
 
-// PersonalLocationFlag 槽位类型枚举
-const (
-	HighSlot      = 1 // 高能量槽
-	MediumSlot    = 2 // 中能量槽
-	LowSlot       = 3 // 低能量槽
-	RigSlot       = 4 // 船插
-	DroneSlot     = 5 // 无人机仓
-	CargoSlot     = 6 // 货柜仓
-	SubsystemSlot = 7 // 子系统仓
-)
-
-// GetSlotNameByFlag 根据槽位标志获取槽位名称
-func GetSlotNameByFlag(flag int) int {
-	switch flag {
-	case 27, 28, 29, 30, 31, 32, 33, 34: // 高槽
-		return HighSlot
-	case 19, 20, 21, 22, 23, 24, 25, 26: // 中槽
-		return MediumSlot
-	case 11, 12, 13, 14, 15, 16, 17, 18: // 低槽
-		return LowSlot
-	case 92, 93, 94: // 船插
-		return RigSlot
-	case 87: // 无人机仓
-		return DroneSlot
-	case 89, 5, 133, 134, 136, 137, 138: // 货柜仓
-		return CargoSlot
-	case 125, 126, 127, 128: // 子系统
-		return SubsystemSlot
-	default:
-		return CargoSlot
-	}
-}
-
 // KillmailItem 击毁邮件物品信息
 type KillmailItem struct {
-	ItemID   int    `json:"item_id"`
-	ItemName string `json:"item_name"`
-	ItemNum  int    `json:"item_num"`
-	DropType bool   `json:"drop_type"`
-	SlotType int    `json:"slot_type"`
+	ItemID       int            `json:"item_id"`
+	ItemName     string         `json:"item_name"`
+	ItemNum      int            `json:"item_num"`
+	DropType     bool           `json:"drop_type"`
+	SlotType     int            `json:"slot_type"`
+	GroupID      int            `json:"group_id"`
+	GroupName    string         `json:"group_name"`
+	CategoryID   int            `json:"category_id"`
+	CategoryName string         `json:"category_name"`
+	Items        []KillmailItem `json:"items,omitempty"` // 容器或舰船内的物品
+}
+
+// KillmailAttacker 击毁邮件攻击者信息
+type KillmailAttacker struct {
+	CharacterID     int     `json:"character_id"`
+	CharacterName   string  `json:"character_name"`
+	CorporationID   int     `json:"corporation_id"`
+	CorporationName string  `json:"corporation_name"`
+	AllianceID      int     `json:"alliance_id"`
+	AllianceName    string  `json:"alliance_name"`
+	FactionID       int     `json:"faction_id"`
+	ShipTypeID      int     `json:"ship_type_id"`
+	ShipTypeName    string  `json:"ship_type_name"`
+	WeaponTypeID    int     `json:"weapon_type_id"`
+	WeaponTypeName  string  `json:"weapon_type_name"`
+	DamageDone      int     `json:"damage_done"`
+	FinalBlow       bool    `json:"final_blow"`
+	SecurityStatus  float64 `json:"security_status"`
 }
 
 // KillmailDetails 击毁邮件详情
@@ -63,9 +57,22 @@ type KillmailDetails struct {
 	CorporationName string                 `json:"corporation_name"`
 	CharacterName   string                 `json:"character_name"`
 	ShipTypeName    string                 `json:"ship_type_name"`
+	ShipGroupID     int                    `json:"ship_group_id"`
+	ShipGroupName   string                 `json:"ship_group_name"`
+	ShipCategoryID  int                    `json:"ship_category_id"`
+	RegionID        int                    `json:"region_id"`
+	RegionName      string                 `json:"region_name"`
+	DamageTaken     int                    `json:"damage_taken"`
+	WarID           int                    `json:"war_id"`
+	Position        *esi.KillmailPosition  `json:"position"`
 	Items           []KillmailItem         `json:"items"`
+	Attackers       []KillmailAttacker     `json:"attackers"`
+	FinalBlow       *KillmailAttacker      `json:"final_blow"`
 	JaniceAmount    float64                `json:"janice_amount"`
 	UIJSON          map[string]interface{} `json:"ui_json"`
+
+	types map[int]sde.TypeInfo // 已解析的物品信息
+	lang  string               // 物品名称的语言
 }
 
 // NewKillmailDetails 创建一个新的击毁邮件详情对象
@@ -74,59 +81,62 @@ func NewKillmailDetails(killmailID int, killmailHash string) *KillmailDetails {
 		KillmailID:   killmailID,
 		KillmailHash: killmailHash,
 		Items:        make([]KillmailItem, 0),
+		Attackers:    make([]KillmailAttacker, 0),
 		UIJSON:       make(map[string]interface{}),
 	}
 }
 
 // Init 初始化击毁邮件详情
-func (k *KillmailDetails) Init() error {
+func (k *KillmailDetails) Init(ctx context.Context) error {
 	// 获取击毁邮件数据
-	killmailData, err := esi.GetKillmail(k.KillmailID, k.KillmailHash)
+	killmail, err := esi.GetKillmail(ctx, k.KillmailID, k.KillmailHash)
 	if err != nil {
 		return err
 	}
 
 	// 设置基本信息
-	k.Time = killmailData["killmail_time"].(string)
-
-	victim, ok := killmailData["victim"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("无法解析victim数据")
-	}
-
-	k.SolarSystemID = int(killmailData["solar_system_id"].(float64))
-
-	if allianceID, ok := victim["alliance_id"].(float64); ok {
-		k.AllianceID = int(allianceID)
-	}
-
-	if corporationID, ok := victim["corporation_id"].(float64); ok {
-		k.CorporationID = int(corporationID)
-	}
-
-	if characterID, ok := victim["character_id"].(float64); ok {
-		k.CharacterID = int(characterID)
-	}
-
-	if shipTypeID, ok := victim["ship_type_id"].(float64); ok {
-		k.ShipTypeID = int(shipTypeID)
+	k.Time = killmail.KillmailTime.UTC().Format(time.RFC3339)
+	k.SolarSystemID = killmail.SolarSystemID
+	k.WarID = killmail.WarID
+
+	victim := killmail.Victim
+	k.AllianceID = victim.AllianceID
+	k.CorporationID = victim.CorporationID
+	k.CharacterID = victim.CharacterID
+	k.ShipTypeID = victim.ShipTypeID
+	k.DamageTaken = victim.DamageTaken
+	k.Position = victim.Position
+
+	// 处理攻击者
+	k.Attackers = make([]KillmailAttacker, 0, len(killmail.Attackers))
+	for _, attacker := range killmail.Attackers {
+		k.Attackers = append(k.Attackers, KillmailAttacker{
+			CharacterID:    attacker.CharacterID,
+			CorporationID:  attacker.CorporationID,
+			AllianceID:     attacker.AllianceID,
+			FactionID:      attacker.FactionID,
+			ShipTypeID:     attacker.ShipTypeID,
+			WeaponTypeID:   attacker.WeaponTypeID,
+			DamageDone:     attacker.DamageDone,
+			FinalBlow:      attacker.FinalBlow,
+			SecurityStatus: attacker.SecurityStatus,
+		})
 	}
 
 	// 处理物品
-	items, ok := victim["items"].([]interface{})
-	if ok && len(items) > 0 {
-		if err := k.handleItems(items); err != nil {
+	if len(victim.Items) > 0 {
+		if err := k.handleItems(ctx, victim.Items); err != nil {
 			return err
 		}
 	}
 
 	// 获取角色信息
-	if err := k.getCharInfo(); err != nil {
+	if err := k.getCharInfo(ctx); err != nil {
 		return err
 	}
 
 	// 获取Janice估价
-	if err := k.getJaniceAmount(); err != nil {
+	if err := k.getJaniceAmount(ctx); err != nil {
 		return err
 	}
 
@@ -136,8 +146,8 @@ func (k *KillmailDetails) Init() error {
 	return nil
 }
 
-// handleItems 处理物品信息
-func (k *KillmailDetails) handleItems(items []interface{}) error {
+// handleItems 处理物品信息，递归处理容器内的物品
+func (k *KillmailDetails) handleItems(ctx context.Context, items []esi.KillmailItem) error {
 	if len(items) == 0 {
 		k.Items = []KillmailItem{}
 		return nil
@@ -145,83 +155,87 @@ func (k *KillmailDetails) handleItems(items []interface{}) error {
 
 	// 提取所有物品ID
 	ids := make([]int, 0, len(items))
-	for _, item := range items {
-		itemMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		if itemTypeID, ok := itemMap["item_type_id"].(float64); ok {
-			ids = append(ids, int(itemTypeID))
+	var collectIDs func(items []esi.KillmailItem)
+	collectIDs = func(items []esi.KillmailItem) {
+		for _, item := range items {
+			ids = append(ids, item.ItemTypeID)
+			collectIDs(item.Items)
 		}
 	}
+	collectIDs(items)
 
 	// 获取物品名称
-	names, err := esi.PostIdsToNames(ids)
+	types, err := k.resolveTypes(ctx, ids)
 	if err != nil {
 		return err
 	}
 
-	// 处理物品数据
-	itemDict := make(map[string]*KillmailItem)
-	for _, item := range items {
-		itemMap, ok := item.(map[string]interface{})
-		if !ok {
-			continue
-		}
+	k.Items = buildItems(items, types)
+	return nil
+}
 
-		itemID := int(itemMap["item_type_id"].(float64))
-		dropType := false
-		if _, ok := itemMap["quantity_dropped"]; ok {
-			dropType = true
-		}
+// buildItems 合并同一层级中相同物品、掉落状态和槽位的数量，带有内容物的容器单独保留
+func buildItems(items []esi.KillmailItem, types map[int]sde.TypeInfo) []KillmailItem {
+	result := make([]KillmailItem, 0, len(items))
+	itemIndex := make(map[string]int)
 
-		flag := int(itemMap["flag"].(float64))
-		slotType := GetSlotNameByFlag(flag)
+	for i, item := range items {
+		itemID := item.ItemTypeID
+		dropType := item.Dropped()
+		slotType := GetSlotNameByFlag(item.Flag)
 
 		// 创建唯一键
 		key := fmt.Sprintf("%d-%v-%d", itemID, dropType, slotType)
+		if len(item.Items) > 0 {
+			key = fmt.Sprintf("container-%d", i)
+		}
 
-		if _, exists := itemDict[key]; !exists {
-			itemDict[key] = &KillmailItem{
-				ItemID:   itemID,
-				ItemName: names[strconv.Itoa(itemID)],
-				ItemNum:  0,
-				DropType: dropType,
-				SlotType: slotType,
-			}
+		index, exists := itemIndex[key]
+		if !exists {
+			index = len(result)
+			itemIndex[key] = index
+			info := types[itemID]
+			result = append(result, KillmailItem{
+				ItemID:       itemID,
+				ItemName:     info.TypeName,
+				ItemNum:      0,
+				DropType:     dropType,
+				SlotType:     slotType,
+				GroupID:      info.GroupID,
+				GroupName:    info.GroupName,
+				CategoryID:   info.CategoryID,
+				CategoryName: info.CategoryName,
+			})
 		}
 
 		// 计算数量
-		var quantityDropped, quantityDestroyed float64
-		if qd, ok := itemMap["quantity_dropped"].(float64); ok {
-			quantityDropped = qd
-		}
-		if qd, ok := itemMap["quantity_destroyed"].(float64); ok {
-			quantityDestroyed = qd
-		}
+		result[index].ItemNum += int(item.Quantity())
 
-		itemDict[key].ItemNum += int(quantityDropped + quantityDestroyed)
+		if len(item.Items) > 0 {
+			result[index].Items = buildItems(item.Items, types)
+		}
 	}
 
-	// 转换为切片
-	k.Items = make([]KillmailItem, 0, len(itemDict))
-	for _, item := range itemDict {
-		k.Items = append(k.Items, *item)
-	}
+	return result
+}
 
-	return nil
+// walkItems 深度优先遍历物品及其内容物
+func walkItems(items []KillmailItem, fn func(item *KillmailItem)) {
+	for i := range items {
+		fn(&items[i])
+		walkItems(items[i].Items, fn)
+	}
 }
 
 // getJaniceAmount 获取Janice估价
-func (k *KillmailDetails) getJaniceAmount() error {
+func (k *KillmailDetails) getJaniceAmount(ctx context.Context) error {
 	queryStr := ""
-	for _, item := range k.Items {
+	walkItems(k.Items, func(item *KillmailItem) {
 		queryStr += fmt.Sprintf("%s\t%d\n", item.ItemName, item.ItemNum)
-	}
+	})
 	queryStr += k.ShipTypeName
 
-	amount, err := esi.GetAppraisal(queryStr)
+	amount, err := esi.GetAppraisal(ctx, queryStr)
 	if err != nil {
 		return err
 	}
@@ -230,49 +244,130 @@ func (k *KillmailDetails) getJaniceAmount() error {
 	return nil
 }
 
-// getCharInfo 获取角色信息
-func (k *KillmailDetails) getCharInfo() error {
+// getCharInfo 获取受害者和攻击者信息，舰船、武器和星系从SDE获取，角色、公司和联盟从ESI获取
+func (k *KillmailDetails) getCharInfo(ctx context.Context) error {
+	typeIDs := []int{k.ShipTypeID}
 	ids := []int{
 		k.CharacterID,
 		k.CorporationID,
 		k.AllianceID,
-		k.ShipTypeID,
-		k.SolarSystemID,
+	}
+	for _, attacker := range k.Attackers {
+		typeIDs = append(typeIDs, attacker.ShipTypeID, attacker.WeaponTypeID)
+		ids = append(ids,
+			attacker.CharacterID,
+			attacker.CorporationID,
+			attacker.AllianceID,
+		)
 	}
 
-	names, err := esi.PostIdsToNames(ids)
+	types, err := k.resolveTypes(ctx, typeIDs)
 	if err != nil {
 		return err
 	}
 
-	k.CharacterName = names[strconv.Itoa(k.CharacterID)]
-	if k.CharacterName == "" {
-		k.CharacterName = "未知"
+	// 星系从SDE获取失败时交给ESI
+	system, err := sde.GetSolarSystemByID(k.SolarSystemID)
+	if err != nil {
+		ids = append(ids, k.SolarSystemID)
 	}
 
-	k.CorporationName = names[strconv.Itoa(k.CorporationID)]
-	if k.CorporationName == "" {
-		k.CorporationName = "未知"
+	names, err := esi.PostIdsToNames(ctx, uniqueIDs(ids))
+	if err != nil {
+		return err
 	}
 
-	k.AllianceName = names[strconv.Itoa(k.AllianceID)]
-	if k.AllianceName == "" {
-		k.AllianceName = "未知"
-	}
+	k.CharacterName = nameOrUnknown(names, k.CharacterID)
+	k.CorporationName = nameOrUnknown(names, k.CorporationID)
+	k.AllianceName = nameOrUnknown(names, k.AllianceID)
 
-	k.ShipTypeName = names[strconv.Itoa(k.ShipTypeID)]
+	ship := types[k.ShipTypeID]
+	k.ShipTypeName = ship.TypeName
 	if k.ShipTypeName == "" {
 		k.ShipTypeName = "未知"
 	}
+	k.ShipGroupID = ship.GroupID
+	k.ShipGroupName = ship.GroupName
+	k.ShipCategoryID = ship.CategoryID
+
+	if system.SolarSystemID != 0 {
+		k.SolarSystemName = system.SolarSystemName
+		k.RegionID = system.RegionID
+		k.RegionName = system.RegionName
+	} else {
+		k.SolarSystemName = nameOrUnknown(names, k.SolarSystemID)
+	}
 
-	k.SolarSystemName = names[strconv.Itoa(k.SolarSystemID)]
-	if k.SolarSystemName == "" {
-		k.SolarSystemName = "未知"
+	k.FinalBlow = nil
+	for i := range k.Attackers {
+		attacker := &k.Attackers[i]
+		attacker.CharacterName = names[strconv.Itoa(attacker.CharacterID)]
+		attacker.CorporationName = names[strconv.Itoa(attacker.CorporationID)]
+		attacker.AllianceName = names[strconv.Itoa(attacker.AllianceID)]
+		attacker.ShipTypeName = types[attacker.ShipTypeID].TypeName
+		attacker.WeaponTypeName = types[attacker.WeaponTypeID].TypeName
+		if attacker.FinalBlow {
+			k.FinalBlow = attacker
+		}
 	}
 
 	return nil
 }
 
+// resolveTypes 从SDE获取物品信息，SDE中不存在的物品回退到ESI获取名称
+func (k *KillmailDetails) resolveTypes(ctx context.Context, typeIDs []int) (map[int]sde.TypeInfo, error) {
+	if k.types == nil {
+		k.types = make(map[int]sde.TypeInfo)
+	}
+
+	missing := make([]int, 0)
+	for _, typeID := range uniqueIDs(typeIDs) {
+		if _, ok := k.types[typeID]; ok {
+			continue
+		}
+		info, err := sde.GetTypeInfoByID(typeID, "")
+		if err != nil {
+			missing = append(missing, typeID)
+			continue
+		}
+		k.types[typeID] = info
+	}
+
+	if len(missing) > 0 {
+		names, err := esi.PostIdsToNames(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, typeID := range missing {
+			k.types[typeID] = sde.TypeInfo{TypeID: typeID, TypeName: names[strconv.Itoa(typeID)]}
+		}
+	}
+
+	return k.types, nil
+}
+
+// nameOrUnknown 获取名称，不存在时返回"未知"
+func nameOrUnknown(names map[string]string, id int) string {
+	if name := names[strconv.Itoa(id)]; name != "" {
+		return name
+	}
+	return "未知"
+}
+
+// uniqueIDs 去除重复和为0的ID
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
 // handleUIJSON 处理UI JSON数据
 func (k *KillmailDetails) handleUIJSON() {
 	k.UIJSON = map[string]interface{}{
@@ -285,48 +380,45 @@ func (k *KillmailDetails) handleUIJSON() {
 		"time":     k.Time,
 	}
 
-	// 槽位映射
-	slotMap := map[int]string{
-		1: "高能量槽",
-		2: "中能量槽",
-		3: "低能量槽",
-		4: "船插",
-		5: "无人机仓",
-		6: "货柜仓",
-		7: "子系统仓",
-	}
-
 	// 按槽位分组物品
-	slotData := make(map[string][]map[string]interface{})
-	for _, slotName := range slotMap {
-		slotData[slotName] = []map[string]interface{}{}
-	}
-
+	slotData := make(map[int][]map[string]interface{})
 	for _, item := range k.Items {
-		slotName := slotMap[item.SlotType]
-		if slotName == "" {
-			slotName = "货柜仓"
+		slotType := item.SlotType
+		if !slices.Contains(slotOrder, slotType) {
+			slotType = CargoSlot
 		}
-
-		slotData[slotName] = append(slotData[slotName], map[string]interface{}{
-			"id":      item.ItemID,
-			"name":    item.ItemName,
-			"dropped": fmt.Sprintf("%v", item.DropType),
-			"num":     item.ItemNum,
-		})
+		slotData[slotType] = append(slotData[slotType], uiItem(item))
 	}
 
 	// 按顺序添加槽位数据
 	var data []map[string]interface{}
-	for i := 1; i <= 7; i++ {
-		slotName := slotMap[i]
-		if len(slotData[slotName]) > 0 {
+	for _, slotType := range slotOrder {
+		if len(slotData[slotType]) > 0 {
 			data = append(data, map[string]interface{}{
-				"slotName": slotName,
-				"data":     slotData[slotName],
+				"slotType": slotType,
+				"slotName": SlotName(slotType),
+				"data":     slotData[slotType],
 			})
 		}
 	}
 
 	k.UIJSON["data"] = data
 }
+
+// uiItem 转换为UI使用的物品数据，内容物放在items中
+func uiItem(item KillmailItem) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":      item.ItemID,
+		"name":    item.ItemName,
+		"dropped": fmt.Sprintf("%v", item.DropType),
+		"num":     item.ItemNum,
+	}
+	if len(item.Items) > 0 {
+		children := make([]map[string]interface{}, 0, len(item.Items))
+		for _, child := range item.Items {
+			children = append(children, uiItem(child))
+		}
+		data["items"] = children
+	}
+	return data
+}
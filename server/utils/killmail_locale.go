@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"eve-corp-manager/models/sde"
+	"strings"
+)
+
+// DefaultLang 未指定语言时物品、舰船和分组名称使用的语言
+const DefaultLang = "zh"
+
+// NormalizeLang 将zh-CN、en_US等语言代码转换为SDE使用的语言代码，空字符串表示不翻译
+func NormalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// Localize 使用SDE的trnTranslations翻译物品、舰船、武器和分组名称，并按该语言重新生成UI JSON
+func (k *KillmailDetails) Localize(lang string) {
+	k.lang = NormalizeLang(lang)
+	if k.lang == "" {
+		k.handleUIJSON()
+		return
+	}
+
+	translated := make(map[int]sde.TypeInfo)
+	translate := func(typeID int) (sde.TypeInfo, bool) {
+		if typeID == 0 {
+			return sde.TypeInfo{}, false
+		}
+		if info, ok := translated[typeID]; ok {
+			return info, true
+		}
+		info, err := sde.GetTypeInfoByID(typeID, k.lang)
+		if err != nil {
+			return info, false
+		}
+		translated[typeID] = info
+		return info, true
+	}
+
+	walkItems(k.Items, func(item *KillmailItem) {
+		if info, ok := translate(item.ItemID); ok {
+			item.ItemName = info.TypeName
+			item.GroupID, item.GroupName = info.GroupID, info.GroupName
+			item.CategoryID, item.CategoryName = info.CategoryID, info.CategoryName
+		}
+	})
+
+	if info, ok := translate(k.ShipTypeID); ok {
+		k.ShipTypeName = info.TypeName
+		k.ShipGroupID, k.ShipGroupName = info.GroupID, info.GroupName
+		k.ShipCategoryID = info.CategoryID
+	}
+
+	for i := range k.Attackers {
+		attacker := &k.Attackers[i]
+		if info, ok := translate(attacker.ShipTypeID); ok {
+			attacker.ShipTypeName = info.TypeName
+		}
+		if info, ok := translate(attacker.WeaponTypeID); ok {
+			attacker.WeaponTypeName = info.TypeName
+		}
+	}
+
+	k.handleUIJSON()
+}
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"cmp"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PersonalLocationFlag 槽位类型枚举，1-7与旧数据保持一致
+const (
+	HighSlot         = 1  // 高能量槽
+	MediumSlot       = 2  // 中能量槽
+	LowSlot          = 3  // 低能量槽
+	RigSlot          = 4  // 船插
+	DroneSlot        = 5  // 无人机仓
+	CargoSlot        = 6  // 货柜仓
+	SubsystemSlot    = 7  // 子系统仓
+	ServiceSlot      = 8  // 建筑服务槽
+	FighterTubeSlot  = 9  // 铁骑舰载机发射管
+	FighterBaySlot   = 10 // 铁骑舰载机机库
+	FuelBaySlot      = 11 // 燃料舱
+	OreHoldSlot      = 12 // 矿石、冰矿、气云等采矿专用舱
+	ShipHangarSlot   = 13 // 舰船维护阵列
+	FleetHangarSlot  = 14 // 舰队机库
+	SpecializedHold  = 15 // 其他专用舱
+	FrigateEscapeBay = 16 // 护卫舰逃生舱
+	ImplantSlot      = 17 // 植入体
+	BoosterSlot      = 18 // 增效剂
+)
+
+// slotOrder UI中槽位的显示顺序
+var slotOrder = []int{
+	HighSlot, MediumSlot, LowSlot, RigSlot, SubsystemSlot, ServiceSlot,
+	FighterTubeSlot, FighterBaySlot, DroneSlot, FrigateEscapeBay,
+	FuelBaySlot, OreHoldSlot, ShipHangarSlot, FleetHangarSlot, SpecializedHold,
+	CargoSlot, ImplantSlot, BoosterSlot,
+}
+
+// flagSlotPrefixes 按invFlags.flagName前缀映射槽位，前缀较长的写在前面
+var flagSlotPrefixes = []struct {
+	prefix   string
+	slotType int
+}{
+	{"HiSlot", HighSlot},
+	{"MedSlot", MediumSlot},
+	{"LoSlot", LowSlot},
+	{"RigSlot", RigSlot},
+	{"SubSystem", SubsystemSlot}, // SDE中为SubSystem0-7，SubSystemBay由完整匹配先处理
+	{"StructureServiceSlot", ServiceSlot},
+	{"FighterTube", FighterTubeSlot},
+	{"SpecializedSmallShipHold", ShipHangarSlot},
+	{"SpecializedMediumShipHold", ShipHangarSlot},
+	{"SpecializedLargeShipHold", ShipHangarSlot},
+	{"SpecializedIndustrialShipHold", ShipHangarSlot},
+	{"SpecializedShipHold", ShipHangarSlot},
+	{"SpecializedFuelBay", FuelBaySlot},
+	{"SpecializedOreHold", OreHoldSlot},
+	{"SpecializedGasHold", OreHoldSlot},
+	{"SpecializedMineralHold", OreHoldSlot},
+	{"SpecializedIceHold", OreHoldSlot},
+	{"SpecializedAsteroidHold", OreHoldSlot},
+	{"Specialized", SpecializedHold},
+}
+
+// flagSlotNames 按invFlags.flagName完整匹配的槽位
+var flagSlotNames = map[string]int{
+	"DroneBay":         DroneSlot,
+	"FighterBay":       FighterBaySlot,
+	"StructureFuel":    FuelBaySlot,
+	"ShipHangar":       ShipHangarSlot,
+	"FleetHangar":      FleetHangarSlot,
+	"FrigateEscapeBay": FrigateEscapeBay,
+	"Implant":          ImplantSlot,
+	"Booster":          BoosterSlot,
+	"BoosterBay":       SpecializedHold,
+	"SubSystemBay":     SpecializedHold,
+	"QuafeBay":         SpecializedHold,
+	"StructureDeedBay": SpecializedHold,
+	"MobileDepot":      SpecializedHold,
+	"Cargo":            CargoSlot,
+}
+
+// defaultFlagNames SDE不可用时使用的invFlags名称
+var defaultFlagNames = map[int]string{
+	5: "Cargo", 87: "DroneBay", 88: "Booster", 89: "Implant", 90: "ShipHangar",
+	133: "SpecializedFuelBay", 134: "SpecializedOreHold", 135: "SpecializedGasHold",
+	136: "SpecializedMineralHold", 137: "SpecializedSalvageHold", 138: "SpecializedShipHold",
+	139: "SpecializedSmallShipHold", 140: "SpecializedMediumShipHold", 141: "SpecializedLargeShipHold",
+	142: "SpecializedIndustrialShipHold", 143: "SpecializedAmmoHold",
+	148: "SpecializedCommandCenterHold", 149: "SpecializedPlanetaryCommoditiesHold",
+	151: "SpecializedMaterialBay", 154: "QuafeBay", 155: "FleetHangar", 158: "FighterBay",
+	172: "StructureFuel", 176: "BoosterBay", 177: "SubSystemBay", 179: "FrigateEscapeBay",
+	180: "StructureDeedBay", 181: "SpecializedIceHold", 182: "SpecializedAsteroidHold",
+}
+
+func init() {
+	for i := 0; i < 8; i++ {
+		defaultFlagNames[11+i] = fmt.Sprintf("LoSlot%d", i)
+		defaultFlagNames[19+i] = fmt.Sprintf("MedSlot%d", i)
+		defaultFlagNames[27+i] = fmt.Sprintf("HiSlot%d", i)
+		defaultFlagNames[92+i] = fmt.Sprintf("RigSlot%d", i)
+		defaultFlagNames[125+i] = fmt.Sprintf("SubSystem%d", i)
+		defaultFlagNames[164+i] = fmt.Sprintf("StructureServiceSlot%d", i)
+	}
+	for i := 0; i < 5; i++ {
+		defaultFlagNames[159+i] = fmt.Sprintf("FighterTube%d", i)
+	}
+}
+
+// flagSlots invFlags标志到槽位类型的映射和槽位名称，SDE可用后只生成一次
+var flagSlots struct {
+	sync.RWMutex
+	slots  map[int]int
+	names  map[int]string // 槽位类型 -> 槽位名称
+	loaded bool
+}
+
+// GetSlotNameByFlag 根据invFlags标志获取槽位类型，未知标志视为货柜仓
+func GetSlotNameByFlag(flag int) int {
+	slots, _ := loadFlagSlots()
+	if slotType, ok := slots[flag]; ok {
+		return slotType
+	}
+	return CargoSlot
+}
+
+// SlotName 获取槽位名称，取该槽位ID最小的invFlags标志的flagText并去掉编号，如 High power slot 1 -> High power slot
+// SDE没有invFlags的翻译，槽位名称不随语言变化；SDE不可用时使用flagName
+func SlotName(slotType int) string {
+	_, names := loadFlagSlots()
+	if name, ok := names[slotType]; ok {
+		return name
+	}
+	return names[CargoSlot]
+}
+
+// SlotTypeByFlagName 根据invFlags.flagName获取槽位类型
+func SlotTypeByFlagName(flagName string) (int, bool) {
+	if slotType, ok := flagSlotNames[flagName]; ok {
+		return slotType, true
+	}
+	for _, rule := range flagSlotPrefixes {
+		if strings.HasPrefix(flagName, rule.prefix) {
+			return rule.slotType, true
+		}
+	}
+	return 0, false
+}
+
+// loadFlagSlots 从SDE的invFlags生成映射和槽位名称，SDE未初始化时使用内置名称，初始化后重新生成
+func loadFlagSlots() (map[int]int, map[int]string) {
+	flagSlots.RLock()
+	slots, names, loaded := flagSlots.slots, flagSlots.names, flagSlots.loaded
+	flagSlots.RUnlock()
+	if loaded || (slots != nil && global.SdeDb == nil) {
+		return slots, names
+	}
+
+	var flags []sde.InvFlag
+	if global.SdeDb != nil {
+		var err error
+		if flags, err = sde.GetInvFlags(); err != nil || len(flags) == 0 {
+			flags = nil
+			if global.Logger != nil {
+				global.Logger.Warnf("读取SDE物品标志失败，使用内置槽位映射: %v", err)
+			}
+		}
+	}
+	if flags == nil {
+		for flagID, flagName := range defaultFlagNames {
+			flags = append(flags, sde.InvFlag{FlagID: flagID, FlagName: flagName, FlagText: flagName})
+		}
+		slices.SortFunc(flags, func(a, b sde.InvFlag) int { return cmp.Compare(a.FlagID, b.FlagID) })
+	}
+
+	slots = make(map[int]int, len(flags))
+	names = make(map[int]string, len(slotOrder))
+	for _, flag := range flags {
+		slotType, ok := SlotTypeByFlagName(flag.FlagName)
+		if !ok {
+			continue
+		}
+		slots[flag.FlagID] = slotType
+		if _, ok := names[slotType]; !ok {
+			names[slotType] = strings.TrimRight(flag.FlagText, "0123456789 ")
+		}
+	}
+
+	flagSlots.Lock()
+	flagSlots.slots, flagSlots.names, flagSlots.loaded = slots, names, global.SdeDb != nil
+	flagSlots.Unlock()
+	return slots, names
+}
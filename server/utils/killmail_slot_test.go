@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/global"
+	"eve-corp-manager/models/sde"
+	"testing"
+)
+
+// sdeInvFlags SDE中invFlags的部分真实数据
+var sdeInvFlags = []sde.InvFlag{
+	{FlagID: 4, FlagName: "Hangar"},
+	{FlagID: 5, FlagName: "Cargo", FlagText: "Cargo"},
+	{FlagID: 11, FlagName: "LoSlot0", FlagText: "Low power slot 1"},
+	{FlagID: 18, FlagName: "LoSlot7", FlagText: "Low power slot 8"},
+	{FlagID: 19, FlagName: "MedSlot0", FlagText: "Medium power slot 1"},
+	{FlagID: 27, FlagName: "HiSlot0", FlagText: "High power slot 1"},
+	{FlagID: 34, FlagName: "HiSlot7", FlagText: "High power slot 8"},
+	{FlagID: 87, FlagName: "DroneBay", FlagText: "Drone Bay"},
+	{FlagID: 88, FlagName: "Booster"},
+	{FlagID: 89, FlagName: "Implant"},
+	{FlagID: 90, FlagName: "ShipHangar"},
+	{FlagID: 92, FlagName: "RigSlot0", FlagText: "Rig power slot 1"},
+	{FlagID: 125, FlagName: "SubSystem0", FlagText: "Sub system slot 0"},
+	{FlagID: 129, FlagName: "SubSystem4", FlagText: "Sub system slot 4"},
+	{FlagID: 132, FlagName: "SubSystem7"},
+	{FlagID: 133, FlagName: "SpecializedFuelBay"},
+	{FlagID: 134, FlagName: "SpecializedOreHold"},
+	{FlagID: 139, FlagName: "SpecializedSmallShipHold"},
+	{FlagID: 143, FlagName: "SpecializedAmmoHold"},
+	{FlagID: 155, FlagName: "FleetHangar"},
+	{FlagID: 156, FlagName: "HiddenModifiers"},
+	{FlagID: 158, FlagName: "FighterBay"},
+	{FlagID: 159, FlagName: "FighterTube0"},
+	{FlagID: 164, FlagName: "StructureServiceSlot0"},
+	{FlagID: 172, FlagName: "StructureFuel"},
+	{FlagID: 176, FlagName: "BoosterBay"},
+	{FlagID: 177, FlagName: "SubSystemBay"},
+	{FlagID: 179, FlagName: "FrigateEscapeBay"},
+	{FlagID: 181, FlagName: "SpecializedIceHold"},
+}
+
+// wantFlagSlots sdeInvFlags中各标志应归入的槽位
+var wantFlagSlots = map[int]int{
+	4:   CargoSlot,
+	5:   CargoSlot,
+	11:  LowSlot,
+	18:  LowSlot,
+	19:  MediumSlot,
+	27:  HighSlot,
+	34:  HighSlot,
+	87:  DroneSlot,
+	88:  BoosterSlot,
+	89:  ImplantSlot,
+	90:  ShipHangarSlot,
+	92:  RigSlot,
+	125: SubsystemSlot,
+	129: SubsystemSlot,
+	132: SubsystemSlot,
+	133: FuelBaySlot,
+	134: OreHoldSlot,
+	139: ShipHangarSlot,
+	143: SpecializedHold,
+	155: FleetHangarSlot,
+	156: CargoSlot,
+	158: FighterBaySlot,
+	159: FighterTubeSlot,
+	164: ServiceSlot,
+	172: FuelBaySlot,
+	176: SpecializedHold,
+	177: SpecializedHold,
+	179: FrigateEscapeBay,
+	181: OreHoldSlot,
+}
+
+func resetFlagSlots() {
+	flagSlots.Lock()
+	flagSlots.slots, flagSlots.names, flagSlots.loaded = nil, nil, false
+	flagSlots.Unlock()
+}
+
+func TestGetSlotNameByFlagWithSde(t *testing.T) {
+	db := esitest.UseSdeDB(t, &sde.InvFlag{})
+	if err := db.Create(&sdeInvFlags).Error; err != nil {
+		t.Fatal(err)
+	}
+	resetFlagSlots()
+	t.Cleanup(resetFlagSlots)
+
+	for _, flag := range sdeInvFlags {
+		if got, want := GetSlotNameByFlag(flag.FlagID), wantFlagSlots[flag.FlagID]; got != want {
+			t.Errorf("flag %d %s: got slot %d, want %d", flag.FlagID, flag.FlagName, got, want)
+		}
+	}
+}
+
+func TestGetSlotNameByFlagWithoutSde(t *testing.T) {
+	sdeDb := global.SdeDb
+	global.SdeDb = nil
+	resetFlagSlots()
+	t.Cleanup(func() {
+		global.SdeDb = sdeDb
+		resetFlagSlots()
+	})
+
+	// 内置名称应与SDE一致
+	for _, flag := range sdeInvFlags {
+		if got, want := GetSlotNameByFlag(flag.FlagID), wantFlagSlots[flag.FlagID]; got != want {
+			t.Errorf("flag %d %s: got slot %d, want %d", flag.FlagID, flag.FlagName, got, want)
+		}
+	}
+}
+
+func TestSlotName(t *testing.T) {
+	db := esitest.UseSdeDB(t, &sde.InvFlag{})
+	if err := db.Create(&sdeInvFlags).Error; err != nil {
+		t.Fatal(err)
+	}
+	resetFlagSlots()
+	t.Cleanup(resetFlagSlots)
+
+	tests := []struct {
+		slotType int
+		want     string
+	}{
+		{HighSlot, "High power slot"},
+		{MediumSlot, "Medium power slot"},
+		{LowSlot, "Low power slot"},
+		{RigSlot, "Rig power slot"},
+		{SubsystemSlot, "Sub system slot"},
+		{DroneSlot, "Drone Bay"},
+		{CargoSlot, "Cargo"},
+		{99, "Cargo"}, // 未知槽位视为货柜仓
+	}
+	for _, tt := range tests {
+		if got := SlotName(tt.slotType); got != tt.want {
+			t.Errorf("SlotName(%d) = %q, want %q", tt.slotType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"eve-corp-manager/core/esi/esitest"
+	"eve-corp-manager/global"
+	"testing"
+)
+
+func TestKillmailDetailsInit(t *testing.T) {
+	srv := esitest.NewServerWithFixtures()
+	defer srv.Close()
+	defer srv.Install()()
+
+	// 不使用SDE，物品名称回退到ESI
+	sdeDb := global.SdeDb
+	global.SdeDb = nil
+	resetFlagSlots()
+	t.Cleanup(func() {
+		global.SdeDb = sdeDb
+		resetFlagSlots()
+	})
+
+	details := NewKillmailDetails(esitest.FixtureKillmailID, esitest.FixtureKillmailHash)
+	if err := details.Init(context.Background()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	if details.Time != "2025-05-01T12:34:56Z" || details.SolarSystemName != "Amarr" {
+		t.Errorf("time = %q, system = %q", details.Time, details.SolarSystemName)
+	}
+	if details.CharacterName != "Esitest Victim" || details.CorporationName != "Esitest Victim Corp" ||
+		details.AllianceName != "Esitest Victim Alliance" || details.ShipTypeName != "Rifter" {
+		t.Errorf("victim = %q %q %q %q", details.CharacterName, details.CorporationName, details.AllianceName, details.ShipTypeName)
+	}
+	if details.DamageTaken != 1500 || details.Position == nil {
+		t.Errorf("damage = %d, position = %v", details.DamageTaken, details.Position)
+	}
+
+	if len(details.Attackers) != 2 {
+		t.Fatalf("attackers = %d, want 2", len(details.Attackers))
+	}
+	fb := details.FinalBlow
+	if fb == nil || fb.CharacterName != "Esitest Hunter" || fb.ShipTypeName != "Machariel" || fb.WeaponTypeName != "800mm Repeating Cannon II" {
+		t.Errorf("final blow = %+v", fb)
+	}
+	if tackler := details.Attackers[1]; tackler.AllianceName != "" || tackler.CorporationName != "Esitest Attackers Corp" {
+		t.Errorf("attacker = %+v", tackler)
+	}
+
+	// 同一槽位相同掉落状态的物品合并，容器保留内容物
+	type wantItem struct {
+		id      int
+		name    string
+		num     int
+		dropped bool
+		slot    int
+		items   int
+	}
+	want := []wantItem{
+		{2873, "200mm AutoCannon I", 2, false, HighSlot, 0},
+		{2873, "200mm AutoCannon I", 1, true, HighSlot, 0},
+		{3831, "Medium Shield Extender I", 1, true, MediumSlot, 0},
+		{2046, "Damage Control I", 1, false, LowSlot, 0},
+		{519, "Gyrostabilizer I", 1, false, LowSlot, 0},
+		{31788, "Small Projectile Burst Aerator I", 1, false, RigSlot, 0},
+		{3293, "Small Standard Container", 1, true, CargoSlot, 2},
+	}
+	if len(details.Items) != len(want) {
+		t.Fatalf("items = %+v, want %d items", details.Items, len(want))
+	}
+	for i, w := range want {
+		item := details.Items[i]
+		got := wantItem{item.ItemID, item.ItemName, item.ItemNum, item.DropType, item.SlotType, len(item.Items)}
+		if got != w {
+			t.Errorf("items[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	contents := details.Items[6].Items
+	if len(contents) == 2 {
+		if c := contents[0]; c.ItemName != "Tritanium" || c.ItemNum != 1000 || !c.DropType {
+			t.Errorf("contents[0] = %+v", c)
+		}
+		if c := contents[1]; c.ItemName != "Pyerite" || c.ItemNum != 500 || c.DropType {
+			t.Errorf("contents[1] = %+v", c)
+		}
+	}
+
+	// 估价包括容器内容物: 12000*3 + 8000 + 25000 + 15000 + 60000 + 3000 + 4.5*1000 + 9*500 + 450000
+	if details.JaniceAmount != 606000 {
+		t.Errorf("JaniceAmount = %v, want 606000", details.JaniceAmount)
+	}
+
+	var container map[string]interface{}
+	for _, slot := range details.UIJSON["data"].([]map[string]interface{}) {
+		if slot["slotType"] == CargoSlot {
+			container = slot["data"].([]map[string]interface{})[0]
+		}
+	}
+	if container == nil || len(container["items"].([]map[string]interface{})) != 2 {
+		t.Errorf("UI JSON container = %v", container)
+	}
+}